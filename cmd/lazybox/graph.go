@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"lazybox/internal/glpg"
+	"math"
+	"sort"
+	"strings"
+)
+
+// graphAlgos maps the algorithms of the graph command to their help text.
+var graphAlgos = map[string]string{
+	"bfs":        "nodes reachable from --from (or every root), breadth-first, with Order and Depth",
+	"dfs":        "nodes reachable from --from (or every root), depth-first, with Order and Depth",
+	"topo":       "every node with its TopoOrder; fails on a cycle",
+	"cycles":     "the nodes on cycles, with the Component they cycle in",
+	"scc":        "every node with its strongly connected Component and ComponentSize",
+	"path":       "a shortest path from --from to --to, with each node's Step",
+	"subgraph":   "the part of the graph within --hops of --from",
+	"centrality": "every node with InDegree, OutDegree, DegreeCentrality, Betweenness and Closeness",
+}
+
+// graphOptions are the flags of the graph command.
+type graphOptions struct {
+	From string
+	To   string
+	Hops int
+}
+
+func graphAlgoNames() []string {
+	names := make([]string, 0, len(graphAlgos))
+	for name := range graphAlgos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// graphAlgoHelp lists the algorithms for the command's long help.
+func graphAlgoHelp() string {
+	var b strings.Builder
+	for _, name := range graphAlgoNames() {
+		fmt.Fprintf(&b, "  %-10s  %s\n", name, graphAlgos[name])
+	}
+	return b.String()
+}
+
+// runGraphAlgo runs an algorithm on g and returns its result as a graph, so
+// it can be printed in any mode.
+func runGraphAlgo(g *glpg.GLPG, algo string, opts graphOptions) (*glpg.GLPG, error) {
+	switch algo {
+	case "bfs", "dfs":
+		starts := g.Roots()
+		if opts.From != "" {
+			from, err := resolveNode(g, opts.From)
+			if err != nil {
+				return nil, err
+			}
+			starts = []string{from}
+		}
+		walk := g.BFS
+		if algo == "dfs" {
+			walk = g.DFS
+		}
+		var order []string
+		depths := make(map[string]int)
+		for _, start := range starts {
+			walk(start, func(node *glpg.GLPGNode, depth int) bool {
+				if _, seen := depths[node.ID]; !seen {
+					depths[node.ID] = depth
+					order = append(order, node.ID)
+				}
+				return true
+			})
+		}
+		result, err := g.Induced(order)
+		if err != nil {
+			return nil, err
+		}
+		for i, id := range order {
+			result.Nodes[id].Properties["Order"] = i
+			result.Nodes[id].Properties["Depth"] = depths[id]
+		}
+		return result, nil
+
+	case "topo":
+		order, err := g.TopologicalSort()
+		if errors.Is(err, glpg.ErrCycle) {
+			return nil, fmt.Errorf("no topological order: cycle %s", strings.Join(g.FindCycle(), " -> "))
+		}
+		if err != nil {
+			return nil, err
+		}
+		result, err := g.Induced(order)
+		if err != nil {
+			return nil, err
+		}
+		for i, id := range order {
+			result.Nodes[id].Properties["TopoOrder"] = i
+		}
+		return result, nil
+
+	case "cycles", "scc":
+		var ids []string
+		component := make(map[string]int)
+		size := make(map[string]int)
+		for i, c := range g.StronglyConnectedComponents() {
+			if algo == "cycles" && len(c) == 1 && !hasSelfLoop(g, c[0]) {
+				continue
+			}
+			for _, id := range c {
+				ids = append(ids, id)
+				component[id] = i
+				size[id] = len(c)
+			}
+		}
+		result, err := g.Induced(ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			result.Nodes[id].Properties["Component"] = component[id]
+			if algo == "scc" {
+				result.Nodes[id].Properties["ComponentSize"] = size[id]
+			}
+		}
+		return result, nil
+
+	case "path":
+		if opts.From == "" || opts.To == "" {
+			return nil, fmt.Errorf("path needs --from and --to")
+		}
+		from, err := resolveNode(g, opts.From)
+		if err != nil {
+			return nil, err
+		}
+		to, err := resolveNode(g, opts.To)
+		if err != nil {
+			return nil, err
+		}
+		path := g.ShortestPath(from, to)
+		if path == nil {
+			return nil, fmt.Errorf("no path from %s to %s", from, to)
+		}
+		result, err := g.Induced(path)
+		if err != nil {
+			return nil, err
+		}
+		for i, id := range path {
+			result.Nodes[id].Properties["Step"] = i
+		}
+		return result, nil
+
+	case "subgraph":
+		from := ""
+		if opts.From != "" {
+			var err error
+			if from, err = resolveNode(g, opts.From); err != nil {
+				return nil, err
+			}
+		} else if roots := g.Roots(); len(roots) > 0 {
+			from = roots[0]
+		}
+		return g.Subgraph(from, opts.Hops)
+
+	case "centrality":
+		result := g.Clone()
+		result.OriginalFileInfo = nil
+		for id, m := range g.Metrics() {
+			props := result.Nodes[id].Properties
+			props["InDegree"] = m.InDegree
+			props["OutDegree"] = m.OutDegree
+			props["DegreeCentrality"] = round4(m.Degree)
+			props["Betweenness"] = round4(m.Betweenness)
+			props["Closeness"] = round4(m.Closeness)
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("unknown algorithm %q (expected one of %s)", algo, strings.Join(graphAlgoNames(), ", "))
+}
+
+// resolveNode finds a node by ID, or else by its Path or Name property;
+// every ingest path names properties by Go field, so these two cover all
+// targets.
+func resolveNode(g *glpg.GLPG, ref string) (string, error) {
+	if g.GetNode(ref) != nil {
+		return ref, nil
+	}
+	var matches []string
+	for id, node := range g.Nodes {
+		if node.Properties["Path"] == ref || node.Properties["Name"] == ref {
+			matches = append(matches, id)
+		}
+	}
+	sort.Strings(matches)
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no node with ID, Path or Name %q", ref)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("%q matches %d nodes (%s); use a node ID", ref, len(matches), strings.Join(matches, ", "))
+}
+
+func hasSelfLoop(g *glpg.GLPG, id string) bool {
+	for _, edge := range g.GetOutgoingEdges(id) {
+		if edge.TargetID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func round4(f float64) float64 {
+	return math.Round(f*1e4) / 1e4
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"lazybox/internal/glpg"
+)
+
+// algoGraph is a small package tree with a cross-link and a cycle:
+//
+//	pkg -> src -> main.go -> util.go, src -> util.go, x <-> y
+func algoGraph(t *testing.T) *glpg.GLPG {
+	t.Helper()
+	g := glpg.NewGLPG()
+	for id, name := range map[string]string{"pkg": "demo", "src": "src", "main": "main.go", "util": "util.go", "x": "loop", "y": "loop"} {
+		g.AddNode(&glpg.GLPGNode{ID: id, Labels: []string{"Node"}, Properties: glpg.GLPGProperty{"Name": name}})
+	}
+	g.GetNode("main").Properties["Path"] = "src/main.go"
+	for _, e := range [][3]string{
+		{"pkg", "CONTAINS", "src"},
+		{"src", "CONTAINS", "main"},
+		{"src", "CONTAINS", "util"},
+		{"main", "CALLS", "util"},
+		{"x", "LINK", "y"},
+		{"y", "LINK", "x"},
+	} {
+		if g.Connect(e[0], e[1], e[2]) == nil {
+			t.Fatalf("connecting %v", e)
+		}
+	}
+	return g
+}
+
+// nodeProperty lists id=value for one property of every node that has it,
+// sorted by ID.
+func nodeProperty(g *glpg.GLPG, key string) string {
+	var values []string
+	for id, node := range g.Nodes {
+		if v, ok := node.Properties[key]; ok {
+			values = append(values, fmt.Sprintf("%s=%v", id, v))
+		}
+	}
+	sort.Strings(values)
+	return strings.Join(values, " ")
+}
+
+func TestRunGraphAlgo(t *testing.T) {
+	tests := []struct {
+		algo string
+		opts graphOptions
+		key  string // property to compare
+		want string
+	}{
+		{"bfs", graphOptions{}, "Order", "main=2 pkg=0 src=1 util=3"},
+		{"bfs", graphOptions{From: "src/main.go"}, "Depth", "main=0 util=1"},
+		{"dfs", graphOptions{From: "src"}, "Order", "main=1 src=0 util=2"},
+		{"scc", graphOptions{}, "ComponentSize", "main=1 pkg=1 src=1 util=1 x=2 y=2"},
+		{"cycles", graphOptions{}, "Component", "x=0 y=0"},
+		{"path", graphOptions{From: "demo", To: "util.go"}, "Step", "pkg=0 src=1 util=2"},
+		{"subgraph", graphOptions{From: "src", Hops: 1}, "Name", "main=main.go src=src util=util.go"},
+		{"subgraph", graphOptions{Hops: 1}, "Name", "pkg=demo src=src"},
+		{"centrality", graphOptions{}, "Betweenness", "main=0 pkg=0 src=0.1 util=0 x=0 y=0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algo, func(t *testing.T) {
+			g := algoGraph(t)
+			result, err := runGraphAlgo(g, tt.algo, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := nodeProperty(result, tt.key); got != tt.want {
+				t.Errorf("%s %s = %q, want %q", tt.algo, tt.key, got, tt.want)
+			}
+			if _, ok := g.GetNode("pkg").Properties[tt.key]; ok && tt.key != "Name" {
+				t.Errorf("%s wrote %s into the input graph", tt.algo, tt.key)
+			}
+		})
+	}
+}
+
+func TestRunGraphAlgoTopo(t *testing.T) {
+	g := algoGraph(t)
+	g.RemoveNode("x")
+	result, err := runGraphAlgo(g, "topo", graphOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := nodeProperty(result, "TopoOrder"), "main=2 pkg=0 src=1 util=3 y=4"; got != want {
+		t.Errorf("TopoOrder = %q, want %q", got, want)
+	}
+}
+
+func TestRunGraphAlgoErrors(t *testing.T) {
+	tests := []struct {
+		algo string
+		opts graphOptions
+		want string
+	}{
+		{"topo", graphOptions{}, "no topological order: cycle x -> y -> x"},
+		{"path", graphOptions{From: "src"}, "path needs --from and --to"},
+		{"path", graphOptions{From: "util", To: "pkg"}, "no path from util to pkg"},
+		{"bfs", graphOptions{From: "nothing"}, `no node with ID, Path or Name "nothing"`},
+		{"bfs", graphOptions{From: "loop"}, `"loop" matches 2 nodes (x, y); use a node ID`},
+		{"pagerank", graphOptions{}, `unknown algorithm "pagerank" (expected one of bfs, centrality, cycles, dfs, path, scc, subgraph, topo)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algo, func(t *testing.T) {
+			_, err := runGraphAlgo(algoGraph(t), tt.algo, tt.opts)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("%s error = %v, want %q", tt.algo, err, tt.want)
+			}
+		})
+	}
+}
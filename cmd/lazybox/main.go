@@ -2,21 +2,20 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"lazybox/internal/code"
-	"lazybox/internal/enuminfo"
-	"lazybox/internal/env"
-	"lazybox/internal/file"
-	"lazybox/internal/fn"
+	"lazybox/internal/budget"
 	"lazybox/internal/fs"
 	"lazybox/internal/glpg" // Added GLPG import
-	"lazybox/internal/listinfo"
+	"lazybox/internal/ir"
 	"lazybox/internal/output"
-	"lazybox/internal/structinfo"
-	"lazybox/internal/text"
+	"lazybox/internal/query"
+	"lazybox/internal/rewrite"
+	"lazybox/internal/schema"
 	"lazybox/internal/theme" // Import the theme package
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
@@ -36,12 +35,47 @@ var modeAliases = map[string]string{
 	"commafy":    "commafy",
 	"fastfetch":  "fastfetch",
 	"commentify": "commentify",
+	"flow":       "flowify",
+	"chart":      "graphify",
+	"http":       "httpify",
+	"httpify":    "httpify",
+	"graphify":   "graphify",
 	"flowify":    "flowify",
+	"xml":        "xmlify",
+	"xmlify":     "xmlify",
+	"yaml":       "yamlify",
+	"yml":        "yamlify",
+	"yamlify":    "yamlify",
+	"toml":       "tomlify",
+	"tomlify":    "tomlify",
 	// Add more as needed
 }
 
 var commentifyLang string // Language for commentify mode
 
+var flowFormat string // Diagram format for flowify mode
+
+var chartFormat string // Chart format for graphify mode
+
+var httpVerb string     // Request method for httpify mode; responses when empty
+var httpURL string      // Request URL for httpify mode
+var httpStatus int      // Response status for httpify mode
+var httpBodyMode string // Mode whose output httpify wraps
+
+var irFormat string // Graph format for the --ir flag
+
+var rulesFile string   // YAML file of rewrite rules
+var ruleNames []string // Built-in rewrite rules to apply
+
+var schemaFile string // Schema the graph must satisfy before it is printed
+
+var queryExpr string // Query selecting the part of the graph to print
+
+var budgetTokens int     // Token budget for --budget (0 for unlimited)
+var tokenizerName string // Tokenizer used to estimate --budget
+
+var scanOpts fs.Options // Ignore and glob options shared by the fs and pkg targets
+
 func main() {
 	// Only print banner if no arguments or help flag is present
 	if len(os.Args) == 1 || hasHelpFlag(os.Args) {
@@ -59,6 +93,7 @@ func main() {
 	var flagIR bool
 	var flagSilent bool
 	var flagTokenize bool
+	var flagDryRun bool
 
 	var outputMode string // Variable to hold the output mode from the flag
 
@@ -77,7 +112,21 @@ func main() {
 	rootCmd.PersistentFlags().BoolVarP(&flagSilent, "silent", "s", false, "Create an intermediate representation of the data, but do not print it to stdout.")
 	rootCmd.PersistentFlags().BoolVarP(&flagTokenize, "tokenize", "t", false, "Remove articles or other prose grammar and use simple key:value pairs.")
 	rootCmd.PersistentFlags().StringVarP(&outputMode, "output", "o", "jsonify", "Output mode (e.g., jsonify, prettify, mdify, tableify, commafy, fastfetch)")
+	rootCmd.PersistentFlags().StringVar(&irFormat, "ir-format", glpg.FormatJGF, "Graph format for --ir ("+strings.Join(glpg.IRFormats, ", ")+")")
+	rootCmd.PersistentFlags().StringVar(&schemaFile, "schema", "", "Schema file the graph must satisfy; violations are reported and nothing is printed")
+	rootCmd.PersistentFlags().StringVar(&rulesFile, "rules", "", "YAML file of graph rewrite rules to apply before output")
+	rootCmd.PersistentFlags().StringSliceVar(&ruleNames, "rule", nil, "Built-in rewrite rules to apply (collapse-dirs, hoist-content, drop-content, drop-git)")
+	rootCmd.PersistentFlags().BoolVar(&flagDryRun, "dry-run", false, "Report which rewrite rules would fire, and how often, instead of printing the output")
+	rootCmd.PersistentFlags().StringVarP(&queryExpr, "query", "q", "", "Only print the nodes matched by this query (e.g. 'FileInfo[Extension=\".go\", Size>2KB]')")
+	rootCmd.PersistentFlags().IntVar(&budgetTokens, "budget", 0, "Prune the output to fit roughly this many LLM tokens (0 for unlimited)")
+	rootCmd.PersistentFlags().StringVar(&tokenizerName, "tokenizer", budget.DefaultTokenizer, "Tokenizer used to estimate --budget (approx, chars, words)")
 	rootCmd.PersistentFlags().StringVar(&commentifyLang, "lang", "bash", "Language for commentify mode (e.g., bash, python, go, c, lua, sql)")
+	rootCmd.PersistentFlags().StringVar(&chartFormat, "chart-format", output.ChartText, "Chart format for graphify mode ("+strings.Join(output.ChartFormats, ", ")+")")
+	rootCmd.PersistentFlags().StringVar(&httpVerb, "http-verb", "", "HTTP verb for httpify mode: print a request (e.g. POST) instead of a response")
+	rootCmd.PersistentFlags().StringVar(&httpURL, "http-url", "/", "Request URL for httpify mode with --http-verb")
+	rootCmd.PersistentFlags().IntVar(&httpStatus, "http-status", 200, "Response status code for httpify mode")
+	rootCmd.PersistentFlags().StringVar(&httpBodyMode, "http-body", "jsonify", "Output mode httpify wraps in the HTTP message")
+	rootCmd.PersistentFlags().StringVar(&flowFormat, "flow-format", output.FlowASCII, "Diagram format for flowify mode ("+strings.Join(output.FlowFormats, ", ")+")")
 
 	var fsCmd = &cobra.Command{
 		Use:   "fs [path] [mode]",
@@ -106,18 +155,7 @@ func main() {
 					mode = args[1]
 				}
 			}
-
-			fileInfoIR, err := fs.Scan(path)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error scanning %s: %v\n", path, err)
-				os.Exit(1)
-			}
-			glpgData, err := glpg.ToGLPG(fileInfoIR)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error converting to GLPG: %v\n", err)
-				os.Exit(1)
-			}
-			handleOutput(glpgData, mode, collectFlags(cmd))
+			runTarget(cmd, "fs", path, mode)
 		},
 	}
 
@@ -131,67 +169,35 @@ func main() {
 			if len(args) > 1 && !cmd.Flags().Changed("output") {
 				mode = args[1] // Fallback to positional if --output not explicitly set
 			}
-			fileDataIR, err := file.Read(path)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error reading file %s: %v\n", path, err)
-				os.Exit(1)
-			}
-			glpgData, err := glpg.ToGLPG(fileDataIR)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error converting to GLPG: %v\n", err)
-				os.Exit(1)
-			}
-			handleOutput(glpgData, mode, collectFlags(cmd))
+			runTarget(cmd, "file", path, mode)
 		},
 	}
 
 	var apiCmd = &cobra.Command{
 		Use:   "api [path] [mode]",
-		Short: "Extract an API from a source file",
+		Short: "Extract the exported API of a Go package directory",
 		Args:  cobra.RangeArgs(1, 2),
 		Run: func(cmd *cobra.Command, args []string) {
-			// path := args[0]
-			// mode := outputMode // Use the --output flag
-			// if len(args) > 1 && !cmd.Flags().Changed("output") {
-			// 	mode = args[1] // Fallback to positional
-			// }
-			// apiInfoIR, err := api.Extract(path) // Placeholder for actual API extraction
-			// if err != nil {
-			// 	fmt.Fprintf(os.Stderr, "Error extracting API from %s: %v\n", path, err)
-			// 	os.Exit(1)
-			// }
-			// glpgData, err := glpg.ToGLPG(apiInfoIR)
-			// if err != nil {
-			// 	fmt.Fprintf(os.Stderr, "Error converting API info to GLPG: %v\n", err)
-			// 	os.Exit(1)
-			// }
-			// handleOutput(glpgData, mode, collectFlags(cmd))
-			fmt.Println("TODO: API command output handling to be implemented with GLPG")
+			path := args[0]
+			mode := outputMode // Use the --output flag
+			if len(args) > 1 && !cmd.Flags().Changed("output") {
+				mode = args[1] // Fallback to positional
+			}
+			runTarget(cmd, "api", path, mode)
 		},
 	}
 
 	var pkgCmd = &cobra.Command{
 		Use:   "pkg [path] [mode]",
-		Short: "Crawl a package directory and emit a representation of its structure",
+		Short: "Crawl a package directory and emit its structure with the contents of text files",
 		Args:  cobra.RangeArgs(1, 2),
 		Run: func(cmd *cobra.Command, args []string) {
-			// path := args[0]
-			// mode := outputMode // Use the --output flag
-			// if len(args) > 1 && !cmd.Flags().Changed("output") {
-			// 	mode = args[1] // Fallback to positional
-			// }
-			// pkgInfoIR, err := pkg.Crawl(path) // Placeholder for actual package crawling
-			// if err != nil {
-			// 	fmt.Fprintf(os.Stderr, "Error crawling package %s: %v\n", path, err)
-			// 	os.Exit(1)
-			// }
-			// glpgData, err := glpg.ToGLPG(pkgInfoIR)
-			// if err != nil {
-			// 	fmt.Fprintf(os.Stderr, "Error converting package info to GLPG: %v\n", err)
-			// 	os.Exit(1)
-			// }
-			// handleOutput(glpgData, mode, collectFlags(cmd))
-			fmt.Println("TODO: PKG command output handling to be implemented with GLPG")
+			path := args[0]
+			mode := outputMode // Use the --output flag
+			if len(args) > 1 && !cmd.Flags().Changed("output") {
+				mode = args[1] // Fallback to positional
+			}
+			runTarget(cmd, "pkg", path, mode)
 		},
 	}
 
@@ -205,28 +211,7 @@ func main() {
 			if len(args) > 1 && !cmd.Flags().Changed("output") {
 				mode = args[1] // Fallback to positional
 			}
-
-			fileData, err := file.Read(path)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error reading file %s: %v\n", path, err)
-				os.Exit(1)
-			}
-			if fileData.Error != "" {
-				fmt.Fprintf(os.Stderr, "Error reading file content %s: %v\n", path, fileData.Error)
-				os.Exit(1)
-			}
-
-			textInfoIR, err := text.Analyze(*fileData.Content, path) // Dereference fileData.Content
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error analyzing text from %s: %v\n", path, err)
-				os.Exit(1)
-			}
-			glpgData, err := glpg.ToGLPG(textInfoIR)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error converting to GLPG: %v\n", err)
-				os.Exit(1)
-			}
-			handleOutput(glpgData, mode, collectFlags(cmd))
+			runTarget(cmd, "text", path, mode)
 		},
 	}
 
@@ -240,17 +225,7 @@ func main() {
 			if len(args) > 1 && !cmd.Flags().Changed("output") {
 				mode = args[1] // Fallback to positional
 			}
-			codeInfoIR, err := code.ExtractPlaceholder(path)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
-			}
-			glpgData, err := glpg.ToGLPG(codeInfoIR)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error converting to GLPG: %v\n", err)
-				os.Exit(1)
-			}
-			handleOutput(glpgData, mode, collectFlags(cmd))
+			runTarget(cmd, "code", path, mode)
 		},
 	}
 
@@ -264,17 +239,7 @@ func main() {
 			if len(args) > 1 && !cmd.Flags().Changed("output") {
 				mode = args[1] // Fallback to positional
 			}
-			funcInfoIR, err := fn.ExtractPlaceholder(path)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
-			}
-			glpgData, err := glpg.ToGLPG(funcInfoIR)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error converting to GLPG: %v\n", err)
-				os.Exit(1)
-			}
-			handleOutput(glpgData, mode, collectFlags(cmd))
+			runTarget(cmd, "func", path, mode)
 		},
 	}
 
@@ -287,17 +252,7 @@ func main() {
 			if len(args) > 0 && !cmd.Flags().Changed("output") {
 				mode = args[0] // Fallback to positional
 			}
-			envInfoIR, err := env.ExtractPlaceholder()
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
-			}
-			glpgData, err := glpg.ToGLPG(envInfoIR)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error converting to GLPG: %v\n", err)
-				os.Exit(1)
-			}
-			handleOutput(glpgData, mode, collectFlags(cmd))
+			runTarget(cmd, "env", "", mode)
 		},
 	}
 
@@ -311,17 +266,7 @@ func main() {
 			if len(args) > 1 && !cmd.Flags().Changed("output") {
 				mode = args[1] // Fallback to positional
 			}
-			structInfoIR, err := structinfo.ExtractPlaceholder(path)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
-			}
-			glpgData, err := glpg.ToGLPG(structInfoIR)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error converting to GLPG: %v\n", err)
-				os.Exit(1)
-			}
-			handleOutput(glpgData, mode, collectFlags(cmd))
+			runTarget(cmd, "struct", path, mode)
 		},
 	}
 
@@ -335,17 +280,7 @@ func main() {
 			if len(args) > 1 && !cmd.Flags().Changed("output") {
 				mode = args[1] // Fallback to positional
 			}
-			enumInfoIR, err := enuminfo.ExtractPlaceholder(path)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
-			}
-			glpgData, err := glpg.ToGLPG(enumInfoIR)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error converting to GLPG: %v\n", err)
-				os.Exit(1)
-			}
-			handleOutput(glpgData, mode, collectFlags(cmd))
+			runTarget(cmd, "enum", path, mode)
 		},
 	}
 
@@ -359,20 +294,183 @@ func main() {
 			if len(args) > 1 && !cmd.Flags().Changed("output") {
 				mode = args[1] // Fallback to positional
 			}
-			listInfoIR, err := listinfo.ExtractPlaceholder(path)
+			runTarget(cmd, "list", path, mode)
+		},
+	}
+
+	var irCmd = &cobra.Command{
+		Use:   "ir [path|-] [mode]",
+		Short: "Load a previously dumped GLPG (JSON Graph Format or lazybox IR JSON) from a file or stdin",
+		Args:  cobra.RangeArgs(0, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			path := "-"
+			if len(args) > 0 {
+				path = args[0]
+			}
+			mode := outputMode // Use the --output flag
+			if len(args) > 1 && !cmd.Flags().Changed("output") {
+				mode = args[1] // Fallback to positional
+			}
+			glpgData, err := glpg.Load(path)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				fmt.Fprintf(os.Stderr, "Error loading graph from %s: %v\n", path, err)
 				os.Exit(1)
 			}
-			glpgData, err := glpg.ToGLPG(listInfoIR)
+			handleOutput(glpgData, mode, collectFlags(cmd))
+		},
+	}
+
+	var validateCmd = &cobra.Command{
+		Use:   "validate [path|-]",
+		Short: "Check the integrity of a previously dumped GLPG (JSON Graph Format or lazybox IR JSON) and validate it against --schema",
+		Args:  cobra.RangeArgs(0, 1),
+		Run: func(cmd *cobra.Command, args []string) {
+			path := "-"
+			if len(args) > 0 {
+				path = args[0]
+			}
+			var s *schema.Schema
+			if schemaFile != "" {
+				var err error
+				if s, err = schema.Load(schemaFile); err != nil {
+					styledError(fmt.Sprintf("Error loading schema: %v", err))
+					os.Exit(1)
+				}
+			}
+			// Load unchecked so dangling edges and bad adjacency lists are
+			// reported below rather than failing the load
+			glpgData, err := glpg.LoadUnchecked(path)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error converting to GLPG: %v\n", err)
+				fmt.Fprintf(os.Stderr, "Error loading graph from %s: %v\n", path, err)
 				os.Exit(1)
 			}
-			handleOutput(glpgData, mode, collectFlags(cmd))
+			problems := glpgData.CheckIntegrity()
+			if len(problems) > 0 {
+				styledError(fmt.Sprintf("Integrity check failed with %d problems:", len(problems)))
+				for _, problem := range problems {
+					fmt.Fprintln(os.Stderr, "  "+problem.Error())
+				}
+			}
+			var violations []schema.Violation
+			if s != nil {
+				if violations = s.Validate(glpgData); len(violations) > 0 {
+					printViolations(violations)
+				}
+			}
+			if len(problems) > 0 || len(violations) > 0 {
+				os.Exit(1)
+			}
+			if s == nil {
+				fmt.Printf("%s: %d nodes and %d edges are consistent\n", path, len(glpgData.Nodes), len(glpgData.Edges))
+				return
+			}
+			fmt.Printf("%s: %d nodes and %d edges match %s\n", path, len(glpgData.Nodes), len(glpgData.Edges), schemaFile)
 		},
 	}
 
+	var mergePolicy string
+	var mergeCmd = &cobra.Command{
+		Use:   "merge <kind:path>...",
+		Short: "Merge several targets (e.g. fs:./cmd code:main.go text:README.md ir:dump.json) into one graph",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			parts := make([]glpg.MergePart, 0, len(args))
+			for _, spec := range args {
+				g, err := loadSpec(spec)
+				if err != nil {
+					styledError(fmt.Sprintf("Error loading %v", err))
+					os.Exit(1)
+				}
+				parts = append(parts, glpg.MergePart{Source: spec, Graph: g})
+			}
+			glpgData, err := glpg.Merge(parts, glpg.ConflictPolicy(mergePolicy))
+			if err != nil {
+				styledError(fmt.Sprintf("Error merging: %v", err))
+				os.Exit(1)
+			}
+			handleOutput(glpgData, outputMode, collectFlags(cmd))
+		},
+	}
+	policies := make([]string, len(glpg.ConflictPolicies))
+	for i, p := range glpg.ConflictPolicies {
+		policies[i] = string(p)
+	}
+	mergeCmd.Flags().StringVar(&mergePolicy, "conflict", string(glpg.ConflictFirst), "How to resolve a property two targets set differently on the same node ("+strings.Join(policies, ", ")+")")
+
+	var diffIgnore []string
+	var diffCmd = &cobra.Command{
+		Use:   "diff <old> <new> [mode]",
+		Short: "Show the nodes, edges and properties added, removed or changed between two targets or saved IR dumps",
+		Args:  cobra.RangeArgs(2, 3),
+		Run: func(cmd *cobra.Command, args []string) {
+			mode := outputMode // Use the --output flag
+			if len(args) > 2 && !cmd.Flags().Changed("output") {
+				mode = args[2] // Fallback to positional
+			}
+			graphs := make([]*glpg.GLPG, 2)
+			for i, arg := range args[:2] {
+				g, err := loadGraph(arg)
+				if err != nil {
+					styledError(fmt.Sprintf("Error loading %s: %v", arg, err))
+					os.Exit(1)
+				}
+				graphs[i] = g
+			}
+			diff, err := glpg.Diff(graphs[0], graphs[1], diffIgnore).Graph(args[0], args[1])
+			if err != nil {
+				styledError(fmt.Sprintf("Error building the diff graph: %v", err))
+				os.Exit(1)
+			}
+			handleOutput(diff, mode, collectFlags(cmd))
+		},
+	}
+	diffCmd.Flags().StringSliceVar(&diffIgnore, "ignore", nil, "Properties to leave out of the comparison (e.g. ModTime)")
+
+	var graphOpts graphOptions
+	var graphCmd = &cobra.Command{
+		Use:   "graph <algo> <kind:path|dump> [mode]",
+		Short: "Run a graph algorithm (bfs, dfs, topo, cycles, scc, path, subgraph, centrality) on a target",
+		Long: "Run a graph algorithm on a target given as kind:path (e.g. pkg:., api:./internal/glpg) or a saved IR dump,\n" +
+			"and print its result as a graph. --from and --to take a node ID, Path or Name.\n\nAlgorithms:\n" + graphAlgoHelp(),
+		Args: cobra.RangeArgs(2, 3),
+		Run: func(cmd *cobra.Command, args []string) {
+			mode := outputMode // Use the --output flag
+			if len(args) > 2 && !cmd.Flags().Changed("output") {
+				mode = args[2] // Fallback to positional
+			}
+			g, err := loadGraph(args[1])
+			if err != nil {
+				styledError(fmt.Sprintf("Error loading %s: %v", args[1], err))
+				os.Exit(1)
+			}
+			result, err := runGraphAlgo(g, args[0], graphOpts)
+			if err != nil {
+				styledError(fmt.Sprintf("Error: %v", err))
+				os.Exit(1)
+			}
+			// The empty graph still goes to stdout, so structured output stays parseable
+			if args[0] == "cycles" && len(result.Nodes) == 0 {
+				fmt.Fprintln(os.Stderr, "No cycles.")
+			}
+			handleOutput(result, mode, collectFlags(cmd))
+		},
+	}
+	graphCmd.Flags().StringVar(&graphOpts.From, "from", "", "Start node for bfs, dfs, path and subgraph (ID, Path or Name)")
+	graphCmd.Flags().StringVar(&graphOpts.To, "to", "", "End node for path (ID, Path or Name)")
+	graphCmd.Flags().IntVar(&graphOpts.Hops, "hops", -1, "Maximum distance from --from for subgraph (-1 for unlimited)")
+
+	for _, c := range []*cobra.Command{fsCmd, pkgCmd, mergeCmd, diffCmd, graphCmd} {
+		c.Flags().StringSliceVar(&scanOpts.Include, "include", nil, "Only include files matching these globs (gitignore syntax, repeatable)")
+		c.Flags().StringSliceVar(&scanOpts.Exclude, "exclude", nil, "Exclude entries matching these globs (gitignore syntax, repeatable)")
+		c.Flags().BoolVar(&scanOpts.NoIgnore, "no-ignore", false, "Don't honor .gitignore/.lazyboxignore files and don't skip .git")
+		c.Flags().IntVar(&scanOpts.MaxDepth, "depth", 0, "Maximum directory depth to descend into (0 for unlimited)")
+		c.Flags().IntVar(&scanOpts.MaxFiles, "max-files", 0, "Maximum number of entries to collect (0 for unlimited)")
+		c.Flags().IntVar(&scanOpts.Workers, "workers", 0, "Number of concurrent scan workers (0 for one per CPU)")
+	}
+	for _, c := range []*cobra.Command{pkgCmd, fileCmd, textCmd, mergeCmd, diffCmd, graphCmd} {
+		c.Flags().Int64Var(&scanOpts.MaxFileSize, "max-file-size", 0, "Maximum number of bytes of file content to read (0 for unlimited)")
+	}
+
 	rootCmd.AddCommand(fsCmd)
 	rootCmd.AddCommand(fileCmd)
 	rootCmd.AddCommand(apiCmd)
@@ -384,6 +482,11 @@ func main() {
 	rootCmd.AddCommand(structCmd)
 	rootCmd.AddCommand(enumCmd)
 	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(irCmd)
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(mergeCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(graphCmd)
 	// rootCmd.AddCommand(fetchCmd) // Commented out as fetchCmd is not defined in the provided code
 	rootCmd.Execute()
 }
@@ -409,18 +512,198 @@ func collectFlags(cmd *cobra.Command) map[string]bool {
 	if cmd.Flags().Changed("all") {
 		flags["all"] = true
 	}
+	if cmd.Flags().Changed("incremental") {
+		flags["incremental"] = true
+	}
+	if cmd.Flags().Changed("ir") {
+		flags["ir"] = true
+	}
+	if cmd.Flags().Changed("dry-run") {
+		flags["dry-run"] = true
+	}
 	return flags
 }
 
+// runTarget loads the kind target at path and prints it in mode. The fs and
+// pkg targets stream their entries as they are scanned when --incremental
+// allows it.
+func runTarget(cmd *cobra.Command, kind, path, mode string) {
+	flags := collectFlags(cmd)
+	if kind == "fs" || kind == "pkg" {
+		if streamer, streaming := startStream(mode, flags); streaming {
+			scanOpts.OnEntry = streamEntry(streamer)
+			_, err := readTarget(kind, path)
+			finishStream(streamer)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading %s target %s: %v\n", kind, path, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	glpgData, err := loadTarget(kind, path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading %s target %s: %v\n", kind, path, err)
+		os.Exit(1)
+	}
+	handleOutput(glpgData, mode, flags)
+}
+
+// startStream returns a node streamer when --incremental is set and the
+// output mode can render nodes one at a time.
+func startStream(mode string, flags map[string]bool) (output.NodeStreamer, bool) {
+	// Rewrites, queries, budgets and schemas need the whole graph
+	if !flags["incremental"] || flags["silent"] || flags["ir"] || flags["dry-run"] ||
+		rulesFile != "" || len(ruleNames) > 0 || queryExpr != "" || budgetTokens > 0 || schemaFile != "" {
+		return nil, false
+	}
+	canonicalMode, ok := modeAliases[strings.ToLower(mode)]
+	if !ok {
+		return nil, false
+	}
+	return output.NewNodeStreamer(canonicalMode, flags)
+}
+
+// streamEntry returns an fs.Options.OnEntry callback that renders each
+// scanned entry as soon as it is found. Nodes and edges are built by
+// glpg.FileInfoNode and glpg.NewEdge, like the full graph's, with ID
+// collisions resolved by glpg.NodeIDs as the full graph resolves them, and
+// file contents are split into their own FileContent nodes, as in the full
+// pkg graph. Streamers that render
+// edges also get the CONTAINS edge from the parent directory and the
+// HAS_CONTENT edge to the content node; the pkg node is only known once the
+// crawl ends, so its edge to the scan root is left out.
+func streamEntry(streamer output.NodeStreamer) func(fi, parent *ir.FileInfo) {
+	edges, _ := streamer.(output.EdgeStreamer)
+	var ids sync.Map // Node ID of each directory streamed so far, for its children's edges
+	var nodeIDs glpg.NodeIDs
+	writeEdge := func(sourceID, label, targetID string) error {
+		return edges.WriteEdge(glpg.NewEdge(sourceID, label, targetID))
+	}
+	return func(fi, parent *ir.FileInfo) {
+		node := glpg.FileInfoNode(fi)
+		nodeIDs.Claim(node) // Before Content is dropped, as it is hashed in the full graph
+		if fi.Content != nil {
+			delete(node.Properties, "Content")
+		}
+		if err := streamer.WriteNode(node); err != nil {
+			styledError(fmt.Sprintf("Error streaming %s: %v", fi.Path, err))
+			return
+		}
+		if edges != nil {
+			if fi.IsDir {
+				ids.Store(fi, node.ID)
+			}
+			if parentID, ok := ids.Load(parent); ok {
+				if err := writeEdge(parentID.(string), "CONTAINS", node.ID); err != nil {
+					styledError(fmt.Sprintf("Error streaming %s: %v", fi.Path, err))
+				}
+			}
+		}
+		if fi.Content == nil {
+			return
+		}
+		content := glpg.FileContentNode(fi)
+		content.ID = "FileContent_" + strings.TrimPrefix(node.ID, "FileInfo_")
+		nodeIDs.Claim(content)
+		if err := streamer.WriteNode(content); err != nil {
+			styledError(fmt.Sprintf("Error streaming content of %s: %v", fi.Path, err))
+			return
+		}
+		if edges != nil {
+			if err := writeEdge(node.ID, "HAS_CONTENT", content.ID); err != nil {
+				styledError(fmt.Sprintf("Error streaming content of %s: %v", fi.Path, err))
+			}
+		}
+	}
+}
+
+// finishStream closes a streamer started by startStream.
+func finishStream(streamer output.NodeStreamer) {
+	if err := streamer.Close(); err != nil {
+		styledError(fmt.Sprintf("Error during incremental output: %v", err))
+	}
+}
+
 func handleOutput(data *glpg.GLPG, mode string, flags map[string]bool) {
 	if data == nil {
 		styledError("Error: No data to output.")
 		os.Exit(1)
 	}
-	if silentFlag, ok := flags["silent"]; ok && silentFlag {
+	// With --schema, --silent still validates the graph
+	if silentFlag, ok := flags["silent"]; ok && silentFlag && schemaFile == "" {
 		return // Do not print anything
 	}
 
+	if rulesFile != "" || len(ruleNames) > 0 || flags["dry-run"] {
+		pipeline, err := loadRules()
+		if err != nil {
+			styledError(fmt.Sprintf("Error loading rewrite rules: %v", err))
+			os.Exit(1)
+		}
+		if flags["dry-run"] {
+			fmt.Println("Dry run: " + pipeline.Apply(data.Clone()).String())
+			return
+		}
+		// The fs tree view is built from the original scan, not the graph
+		if report := pipeline.Apply(data); report.Total() > 0 {
+			data.OriginalFileInfo = nil
+		}
+	}
+
+	if queryExpr != "" {
+		q, err := query.Parse(queryExpr)
+		if err != nil {
+			styledError(err.Error())
+			os.Exit(1)
+		}
+		if data, err = q.Eval(data); err != nil {
+			styledError(fmt.Sprintf("Error running query: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	// The schema checks the graph the query selected, before --budget cuts
+	// it down: pruning only drops contents, properties and nodes and marks
+	// where it did (ContentDropped, Truncated), which a schema need not
+	// declare
+	if schemaFile != "" {
+		s, err := schema.Load(schemaFile)
+		if err != nil {
+			styledError(fmt.Sprintf("Error loading schema: %v", err))
+			os.Exit(1)
+		}
+		if violations := s.Validate(data); len(violations) > 0 {
+			printViolations(violations)
+			os.Exit(1)
+		}
+	}
+
+	var budgetReport *budget.Report
+	if budgetTokens > 0 {
+		var err error
+		if budgetReport, err = applyBudget(data); err != nil {
+			styledError(fmt.Sprintf("Error applying budget: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	if flags["silent"] {
+		return
+	}
+	if budgetReport != nil {
+		defer printBudgetReport(budgetReport, mode, flags)
+	}
+
+	// --ir prints the graph itself in place of the mode output
+	if flags["ir"] {
+		if err := output.PrintGLPGAsIR(data, irFormat, flags); err != nil {
+			styledError(fmt.Sprintf("Error serializing IR: %v", err))
+			os.Exit(1)
+		}
+		return
+	}
+
 	mode = strings.ToLower(mode)
 	canonicalMode, ok := modeAliases[mode]
 	if !ok {
@@ -428,31 +711,148 @@ func handleOutput(data *glpg.GLPG, mode string, flags map[string]bool) {
 		return
 	}
 
-	var err error
+	// Targets that can't stream during discovery still print node by node
+	if flags["incremental"] {
+		if streamer, ok := output.NewNodeStreamer(canonicalMode, flags); ok {
+			if err := output.StreamGLPG(data, streamer); err != nil {
+				styledError(fmt.Sprintf("Error during incremental output for mode '%s': %v", canonicalMode, err))
+			}
+			return
+		}
+	}
+
+	if err := printMode(data, canonicalMode, flags); errors.Is(err, errUnknownMode) {
+		styledErrorWithModes(mode)
+	} else if err != nil {
+		styledError(fmt.Sprintf("Error during output generation for mode '%s': %v", canonicalMode, err))
+	}
+}
+
+var errUnknownMode = errors.New("unknown output mode")
+
+// printMode prints data in a canonical output mode.
+func printMode(data *glpg.GLPG, canonicalMode string, flags map[string]bool) error {
 	switch canonicalMode {
 	case "jsonify":
-		err = output.PrintGLPGAsJSON(data, flags)
+		return output.PrintGLPGAsJSON(data, flags)
 	case "prettify":
-		err = output.PrintGLPGAsPretty(data, flags)
+		return output.PrintGLPGAsPretty(data, flags)
 	case "mdify":
-		err = output.PrintGLPGAsMarkdown(data, flags)
+		return output.PrintGLPGAsMarkdown(data, flags)
 	case "tabelify":
-		err = output.PrintGLPGAsTable(data, flags)
+		return output.PrintGLPGAsTable(data, flags)
 	case "commafy":
-		err = output.PrintGLPGAsCSV(data, flags)
+		return output.PrintGLPGAsCSV(data, flags)
 	case "fastfetch":
-		err = output.PrintGLPGAsFastfetch(data, flags)
+		return output.PrintGLPGAsFastfetch(data, flags)
 	case "commentify":
-		err = output.PrintGLPGAsComment(data, flags, commentifyLang)
+		return output.PrintGLPGAsComment(data, flags, commentifyLang)
 	case "flowify":
-		err = output.PrintGLPGAsFlow(data, flags)
-	default:
-		styledErrorWithModes(mode)
+		return output.PrintGLPGAsFlow(data, flags, flowFormat)
+	case "graphify":
+		return output.PrintGLPGAsCharts(data, flags, chartFormat)
+	case "xmlify":
+		return output.PrintGLPGAsXML(data, flags)
+	case "yamlify":
+		return output.PrintGLPGAsYAML(data, flags)
+	case "tomlify":
+		return output.PrintGLPGAsTOML(data, flags)
+	case "httpify":
+		return printHTTP(data, flags)
+	}
+	return errUnknownMode
+}
+
+// printHTTP prints the output of the --http-body mode wrapped in an HTTP
+// request (with --http-verb) or response.
+func printHTTP(data *glpg.GLPG, flags map[string]bool) error {
+	bodyMode, ok := modeAliases[strings.ToLower(httpBodyMode)]
+	if !ok || bodyMode == "httpify" {
+		return fmt.Errorf("invalid body mode %q for httpify", httpBodyMode)
+	}
+	format := ""
+	switch bodyMode {
+	case "flowify":
+		format = flowFormat
+	case "graphify":
+		format = chartFormat
+	}
+	body, err := output.CaptureStdout(func() error {
+		return printMode(data, bodyMode, flags)
+	})
+	if err != nil {
+		return err
+	}
+	return output.PrintHTTP(output.HTTPMessage{
+		Method:      httpVerb,
+		URL:         httpURL,
+		Status:      httpStatus,
+		ContentType: output.ContentType(bodyMode, format),
+	}, body)
+}
+
+// structuredModes are the output modes meant to be parsed, which the
+// budget summary must not follow on stdout.
+var structuredModes = map[string]bool{
+	"jsonify": true,
+	"xmlify":  true,
+	"yamlify": true,
+	"tomlify": true,
+	"commafy": true,
+	"httpify": true, // Anything after the body would break Content-Length
+}
+
+// printBudgetReport prints the --budget summary after the output, or on
+// stderr when the output is a structured document or the IR.
+func printBudgetReport(report *budget.Report, mode string, flags map[string]bool) {
+	if flags["ir"] || structuredModes[modeAliases[strings.ToLower(mode)]] {
+		fmt.Fprintln(os.Stderr, report)
 		return
 	}
+	fmt.Println(report)
+}
 
+// loadRules builds the rewrite pipeline from --rule and --rules. Built-in
+// rules run first, in the order given, followed by the rules file.
+func loadRules() (rewrite.Pipeline, error) {
+	var pipeline rewrite.Pipeline
+	for _, name := range ruleNames {
+		rule, err := rewrite.Lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, rule)
+	}
+	if rulesFile != "" {
+		fileRules, err := rewrite.LoadFile(rulesFile)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, fileRules...)
+	}
+	return pipeline, nil
+}
+
+// applyBudget prunes the graph to fit --budget tokens. The fs tree view is
+// built from the original scan rather than the graph, so it is dropped once
+// anything has been cut and the pruned graph is rendered instead.
+func applyBudget(data *glpg.GLPG) (*budget.Report, error) {
+	tok, err := budget.GetTokenizer(tokenizerName)
 	if err != nil {
-		styledError(fmt.Sprintf("Error during output generation for mode '%s': %v", canonicalMode, err))
+		return nil, err
+	}
+	report := budget.Prune(data, budgetTokens, tok)
+	if report.Pruned() {
+		data.OriginalFileInfo = nil
+	}
+	return report, nil
+}
+
+// printViolations reports schema violations on stderr.
+func printViolations(violations []schema.Violation) {
+	styledError(fmt.Sprintf("Schema validation failed with %d violations:", len(violations)))
+	for _, v := range violations {
+		fmt.Fprintln(os.Stderr, "  "+v.String())
 	}
 }
 
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"lazybox/internal/api"
+	"lazybox/internal/code"
+	"lazybox/internal/enuminfo"
+	"lazybox/internal/env"
+	"lazybox/internal/file"
+	"lazybox/internal/fn"
+	"lazybox/internal/fs"
+	"lazybox/internal/glpg"
+	"lazybox/internal/listinfo"
+	"lazybox/internal/pkg"
+	"lazybox/internal/structinfo"
+	"lazybox/internal/text"
+	"sort"
+	"strings"
+)
+
+// targetLoaders read the IR of each target from a path. The target
+// subcommands and the commands that combine several targets (merge, diff),
+// which refer to them with kind:path specs, all load targets through them.
+var targetLoaders = map[string]func(path string) (interface{}, error){
+	"fs": func(path string) (interface{}, error) {
+		return fs.ScanWithOptions(path, scanOpts)
+	},
+	"file": func(path string) (interface{}, error) {
+		return file.ReadWithLimit(path, scanOpts.MaxFileSize)
+	},
+	"api": func(path string) (interface{}, error) {
+		return api.Extract(path)
+	},
+	"pkg": func(path string) (interface{}, error) {
+		return pkg.Crawl(path, scanOpts)
+	},
+	"text": func(path string) (interface{}, error) {
+		fileData, err := file.ReadWithLimit(path, scanOpts.MaxFileSize)
+		if err != nil {
+			return nil, err
+		}
+		if fileData.Error != "" {
+			return nil, fmt.Errorf("reading file content: %s", fileData.Error)
+		}
+		return text.Analyze(*fileData.Content, path)
+	},
+	"code": func(path string) (interface{}, error) {
+		if code.IsSupported(path) {
+			return code.Extract(path)
+		}
+		return code.ExtractPlaceholder(path)
+	},
+	"func": func(path string) (interface{}, error) {
+		return fn.ExtractPlaceholder(path)
+	},
+	"env": func(string) (interface{}, error) {
+		return env.ExtractPlaceholder()
+	},
+	"struct": func(path string) (interface{}, error) {
+		return structinfo.ExtractPlaceholder(path)
+	},
+	"enum": func(path string) (interface{}, error) {
+		return enuminfo.ExtractPlaceholder(path)
+	},
+	"list": func(path string) (interface{}, error) {
+		return listinfo.ExtractPlaceholder(path)
+	},
+}
+
+// targetKinds lists the kinds a target spec may use.
+func targetKinds() []string {
+	kinds := []string{"ir"}
+	for kind := range targetLoaders {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// readTarget reads the IR of the kind target at path.
+func readTarget(kind, path string) (interface{}, error) {
+	loader, ok := targetLoaders[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown target %q (expected one of %s)", kind, strings.Join(targetKinds(), ", "))
+	}
+	return loader(path)
+}
+
+// loadTarget builds the graph of the kind target at path.
+func loadTarget(kind, path string) (*glpg.GLPG, error) {
+	data, err := readTarget(kind, path)
+	if err != nil {
+		return nil, err
+	}
+	g, err := glpg.ToGLPG(data)
+	if err != nil {
+		return nil, fmt.Errorf("converting to GLPG: %w", err)
+	}
+	return g, nil
+}
+
+// loadSpec builds the graph for a kind:path target spec, such as fs:./cmd
+// or code:main.go. ir:FILE loads a saved dump (ir:- reads stdin), and env
+// needs no path.
+func loadSpec(spec string) (*glpg.GLPG, error) {
+	kind, path, _ := strings.Cut(spec, ":")
+	if kind == "ir" {
+		if path == "" {
+			path = "-"
+		}
+		return glpg.Load(path)
+	}
+	if _, ok := targetLoaders[kind]; !ok {
+		return nil, fmt.Errorf("unknown target %q in %q (expected kind:path with kind one of %s)", kind, spec, strings.Join(targetKinds(), ", "))
+	}
+	if path == "" && kind != "env" {
+		return nil, fmt.Errorf("target %q needs a path (%s:PATH)", spec, kind)
+	}
+	g, err := loadTarget(kind, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", spec, err)
+	}
+	return g, nil
+}
+
+// loadGraph loads a target spec, or a saved IR dump if arg does not start
+// with a known kind (so both fs:./cmd and snapshot.json work).
+func loadGraph(arg string) (*glpg.GLPG, error) {
+	kind, _, found := strings.Cut(arg, ":")
+	if _, known := targetLoaders[kind]; (found && known) || kind == "ir" || arg == "env" {
+		return loadSpec(arg)
+	}
+	return glpg.Load(arg)
+}
@@ -1,41 +1,84 @@
 package api
 
 import (
-	"io/ioutil"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"lazybox/internal/code"
 	"lazybox/internal/ir"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 )
 
-// Extract is a placeholder for API extraction. For now, just reads file content and metadata.
-func Extract(path string) (*ir.FileInfo, error) {
+// Extract loads the Go package in the directory at path (or containing the
+// file at path) and returns its exported API surface. Test files and files
+// excluded by build constraints are ignored.
+func Extract(path string) (*ir.APIInfo, error) {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
 		return nil, err
 	}
-	fi, err := os.Lstat(absPath)
+	info, err := os.Stat(absPath)
 	if err != nil {
-		return &ir.FileInfo{
-			Name:         filepath.Base(absPath),
-			Path:         absPath,
-			AbsolutePath: absPath,
-			Error:        err.Error(),
-		}, nil
+		return nil, err
+	}
+	dir := absPath
+	if !info.IsDir() {
+		dir = filepath.Dir(absPath)
+	}
+
+	bpkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load package in %s: %w", dir, err)
 	}
-	contentBytes, err := ioutil.ReadFile(absPath)
-	content := ""
-	if err == nil {
-		content = string(contentBytes)
+
+	api := &ir.APIInfo{
+		Name:     bpkg.Name,
+		Path:     dir,
+		Language: "go",
+	}
+	files := append([]string{}, bpkg.GoFiles...)
+	files = append(files, bpkg.CgoFiles...)
+	sort.Strings(files)
+
+	fset := token.NewFileSet()
+	for _, name := range files {
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		if api.Doc == "" && file.Doc != nil {
+			api.Doc = strings.TrimSpace(file.Doc.Text())
+		}
+		// Drop unexported declarations, fields and interface methods in place.
+		ast.FileExports(file)
+
+		ci := code.ExtractFile(fset, file)
+		api.Files = append(api.Files, name)
+		for _, fi := range ci.Funcs {
+			// FileExports keeps exported methods on unexported types.
+			if fi.Receiver != "" && !ast.IsExported(fi.Receiver) {
+				continue
+			}
+			fi.File = name
+			api.Funcs = append(api.Funcs, fi)
+		}
+		for _, ti := range ci.Types {
+			ti.File = name
+			api.Types = append(api.Types, ti)
+		}
+		for _, vi := range ci.Consts {
+			vi.File = name
+			api.Consts = append(api.Consts, vi)
+		}
+		for _, vi := range ci.Vars {
+			vi.File = name
+			api.Vars = append(api.Vars, vi)
+		}
 	}
-	return &ir.FileInfo{
-		Name:         filepath.Base(absPath),
-		Path:         absPath,
-		AbsolutePath: absPath,
-		Type:         ir.FileTypeFile,
-		Size:         fi.Size(),
-		Mode:         fi.Mode().String(),
-		ModTime:      fi.ModTime(),
-		Extension:    filepath.Ext(absPath),
-		Content:      content,
-	}, nil
+	return api, nil
 }
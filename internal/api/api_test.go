@@ -0,0 +1,51 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	api, err := Extract("../code/testdata/demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api.Name != "demo" || api.Doc != "Package demo is a fixture for the code and api extractors." {
+		t.Errorf("package = %s %q", api.Name, api.Doc)
+	}
+	// platform_a.go is excluded by its build constraint
+	if got, want := strings.Join(api.Files, " "), "demo.go platform_b.go"; got != want {
+		t.Errorf("files = %q, want %q", got, want)
+	}
+
+	var decls []string
+	for _, ti := range api.Types {
+		decls = append(decls, fmt.Sprintf("type %s (%s)", ti.Name, ti.File))
+	}
+	for _, fi := range api.Funcs {
+		decls = append(decls, fmt.Sprintf("func %s.%s", fi.Receiver, fi.Name))
+	}
+	for _, vi := range append(api.Consts, api.Vars...) {
+		decls = append(decls, vi.Kind+" "+vi.Name)
+	}
+	want := "type Base (demo.go), type Named (demo.go), type Widget (demo.go), type Platform (platform_b.go), " +
+		"func Widget.Size, const Version"
+	if got := strings.Join(decls, ", "); got != want {
+		t.Errorf("exported declarations =\n%s\nwant\n%s", got, want)
+	}
+
+	// Unexported fields are dropped; embedded types are kept
+	for _, ti := range api.Types {
+		if ti.Name != "Widget" {
+			continue
+		}
+		var fields []string
+		for _, f := range ti.Fields {
+			fields = append(fields, f.Name+":"+f.Type)
+		}
+		if got, want := strings.Join(fields, " "), ":Base :*Named Name:string"; got != want {
+			t.Errorf("Widget fields = %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+// Package budget estimates the token cost of a GLPG and prunes it to fit a
+// token budget before it is serialized.
+package budget
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"lazybox/internal/glpg"
+)
+
+// Per-item overhead for the punctuation and layout every mode adds around
+// nodes, edges and properties.
+const (
+	nodeOverhead     = 4
+	edgeOverhead     = 4
+	propertyOverhead = 2
+)
+
+// contentProperties hold file contents and are dropped first.
+var contentProperties = []string{"Content", "ContentSummary"}
+
+// contentLabel marks nodes that only carry file contents.
+const contentLabel = "FileContent"
+
+// lowPriorityProperties are dropped, in order, after file contents. Empty
+// values of any property are dropped before all of these.
+var lowPriorityProperties = []string{
+	"CreateTime", "ModTime", "Owner", "Group", "Mode",
+	"AbsolutePath", "SymlinkTarget", "GitCurrentBranch", "GitRemoteURL",
+	"Column", "EndLine", "EndColumn", "Line", "File",
+	"SkippedEntries", "Error", "MimeType", "Definition", "Signature", "Doc",
+}
+
+// Report describes what Prune removed to fit the budget.
+type Report struct {
+	Budget          int
+	Before          int            // estimated tokens before pruning
+	After           int            // estimated tokens after pruning
+	ContentsDropped int            // file contents removed
+	EmptyDropped    int            // empty-valued properties removed
+	PropsDropped    map[string]int // low-priority properties removed, by key
+	NodesDropped    int            // nodes removed with deep subtrees
+	CutDepth        int            // shallowest depth nodes were removed from, or -1
+}
+
+// Pruned reports whether anything was removed.
+func (r *Report) Pruned() bool {
+	return r.ContentsDropped > 0 || r.EmptyDropped > 0 || len(r.PropsDropped) > 0 || r.NodesDropped > 0
+}
+
+// String summarizes the report in one line.
+func (r *Report) String() string {
+	if !r.Pruned() {
+		return fmt.Sprintf("[budget] ~%d of %d tokens, nothing cut", r.After, r.Budget)
+	}
+	var cuts []string
+	if r.ContentsDropped > 0 {
+		cuts = append(cuts, fmt.Sprintf("%d file contents", r.ContentsDropped))
+	}
+	if r.EmptyDropped > 0 {
+		cuts = append(cuts, fmt.Sprintf("%d empty properties", r.EmptyDropped))
+	}
+	if len(r.PropsDropped) > 0 {
+		keys := make([]string, 0, len(r.PropsDropped))
+		total := 0
+		for k, n := range r.PropsDropped {
+			keys = append(keys, k)
+			total += n
+		}
+		sort.Strings(keys)
+		cuts = append(cuts, fmt.Sprintf("%d properties (%s)", total, strings.Join(keys, ", ")))
+	}
+	if r.NodesDropped > 0 {
+		cuts = append(cuts, fmt.Sprintf("%d nodes at depth %d or deeper", r.NodesDropped, r.CutDepth))
+	}
+	status := "fits"
+	if r.After > r.Budget {
+		status = "still over budget"
+	}
+	return fmt.Sprintf("[budget] ~%d -> ~%d of %d tokens (%s); cut %s", r.Before, r.After, r.Budget, status, strings.Join(cuts, ", "))
+}
+
+// pruner tracks the estimated size of a graph as it is cut down.
+type pruner struct {
+	g     *glpg.GLPG
+	tok   Tokenizer
+	total int
+}
+
+// Estimate returns the estimated token cost of serializing the graph.
+func Estimate(g *glpg.GLPG, tok Tokenizer) int {
+	p := &pruner{g: g, tok: tok}
+	return p.estimate()
+}
+
+func (p *pruner) estimate() int {
+	total := 0
+	for _, node := range p.g.Nodes {
+		total += p.nodeCost(node)
+	}
+	for _, edge := range p.g.Edges {
+		total += p.edgeCost(edge)
+	}
+	return total
+}
+
+func (p *pruner) nodeCost(node *glpg.GLPGNode) int {
+	cost := nodeOverhead + p.tok.Count(node.ID) + p.tok.Count(strings.Join(node.Labels, " "))
+	for k, v := range node.Properties {
+		cost += p.propertyCost(k, v)
+	}
+	return cost
+}
+
+func (p *pruner) edgeCost(edge *glpg.GLPGEdge) int {
+	cost := edgeOverhead + p.tok.Count(edge.Label)
+	for k, v := range edge.Properties {
+		cost += p.propertyCost(k, v)
+	}
+	return cost
+}
+
+func (p *pruner) propertyCost(key string, value interface{}) int {
+	return propertyOverhead + p.tok.Count(key) + p.tok.Count(fmt.Sprint(value))
+}
+
+func (p *pruner) fits(budget int) bool {
+	return p.total <= budget
+}
+
+// Prune removes data from g until its estimated cost fits within budget
+// tokens: file contents first (largest first), then empty and low-priority
+// properties, then nodes from the deepest level of the graph upwards.
+// Parents that lose children are marked Truncated.
+func Prune(g *glpg.GLPG, budget int, tok Tokenizer) *Report {
+	p := &pruner{g: g, tok: tok}
+	p.total = p.estimate()
+	report := &Report{Budget: budget, Before: p.total, PropsDropped: make(map[string]int), CutDepth: -1}
+	if !p.fits(budget) {
+		p.dropContents(budget, report)
+	}
+	if !p.fits(budget) {
+		p.dropProperties(budget, report)
+	}
+	if !p.fits(budget) {
+		p.dropDeepNodes(budget, report)
+	}
+	report.After = p.total
+	return report
+}
+
+// contentItem is a single file content to drop: a FileContent node, or a
+// content property on another node.
+type contentItem struct {
+	node *glpg.GLPGNode
+	key  string // empty for a whole FileContent node
+	cost int
+}
+
+func (p *pruner) dropContents(budget int, report *Report) {
+	var items []contentItem
+	for _, node := range p.g.Nodes {
+		if hasLabel(node, contentLabel) {
+			cost := p.nodeCost(node)
+			for _, edge := range p.g.GetIncomingEdges(node.ID) {
+				cost += p.edgeCost(edge)
+			}
+			items = append(items, contentItem{node: node, cost: cost})
+			continue
+		}
+		for _, key := range contentProperties {
+			if v, ok := node.Properties[key]; ok {
+				items = append(items, contentItem{node: node, key: key, cost: p.propertyCost(key, v)})
+			}
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].cost != items[j].cost {
+			return items[i].cost > items[j].cost
+		}
+		return items[i].node.ID+items[i].key < items[j].node.ID+items[j].key
+	})
+	for _, item := range items {
+		if p.fits(budget) {
+			return
+		}
+		if item.key == "" {
+			for _, edge := range p.g.GetIncomingEdges(item.node.ID) {
+				if parent := p.g.GetNode(edge.SourceID); parent != nil {
+					p.setProperty(parent, "ContentDropped", true)
+				}
+			}
+			p.g.RemoveNode(item.node.ID)
+		} else {
+			p.g.UpdateProperties(item.node.ID, glpg.GLPGProperty{item.key: nil})
+			p.setProperty(item.node, "ContentDropped", true)
+		}
+		p.total -= item.cost
+		report.ContentsDropped++
+	}
+}
+
+func (p *pruner) dropProperties(budget int, report *Report) {
+	nodes := p.sortedNodes()
+	// Empty values carry no information, so they go first
+	for _, node := range nodes {
+		for _, key := range sortedKeys(node.Properties) {
+			if p.fits(budget) {
+				return
+			}
+			if v := node.Properties[key]; isEmpty(v) {
+				p.total -= p.propertyCost(key, v)
+				p.g.UpdateProperties(node.ID, glpg.GLPGProperty{key: nil})
+				report.EmptyDropped++
+			}
+		}
+	}
+	for _, key := range lowPriorityProperties {
+		for _, node := range nodes {
+			if p.fits(budget) {
+				return
+			}
+			if v, ok := node.Properties[key]; ok {
+				p.total -= p.propertyCost(key, v)
+				p.g.UpdateProperties(node.ID, glpg.GLPGProperty{key: nil})
+				report.PropsDropped[key]++
+			}
+		}
+	}
+}
+
+func (p *pruner) dropDeepNodes(budget int, report *Report) {
+	depths := p.depths()
+	byDepth := make(map[int][]string)
+	maxDepth := 0
+	for id, d := range depths {
+		byDepth[d] = append(byDepth[d], id)
+		if d > maxDepth {
+			maxDepth = d
+		}
+	}
+	// Always keep the roots
+	for depth := maxDepth; depth > 0 && !p.fits(budget); depth-- {
+		ids := byDepth[depth]
+		sort.Strings(ids)
+		for _, id := range ids {
+			node := p.g.GetNode(id)
+			if node == nil || p.fits(budget) {
+				continue
+			}
+			for _, edge := range p.g.GetIncomingEdges(id) {
+				if parent := p.g.GetNode(edge.SourceID); parent != nil && depths[parent.ID] < depth {
+					p.setProperty(parent, "Truncated", true)
+				}
+			}
+			p.total -= p.nodeCost(node)
+			for _, edge := range append(p.g.GetIncomingEdges(id), p.g.GetOutgoingEdges(id)...) {
+				p.total -= p.edgeCost(edge)
+			}
+			p.g.RemoveNode(id)
+			report.NodesDropped++
+			report.CutDepth = depth
+		}
+	}
+}
+
+// depths returns each node's distance from the nearest root (a node without
+// incoming edges). Nodes only reachable through cycles count as roots.
+func (p *pruner) depths() map[string]int {
+	depths := make(map[string]int, len(p.g.Nodes))
+	var queue []string
+	for _, node := range p.sortedNodes() {
+		if len(p.g.GetIncomingEdges(node.ID)) == 0 {
+			depths[node.ID] = 0
+			queue = append(queue, node.ID)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, edge := range p.g.GetOutgoingEdges(id) {
+			if _, seen := depths[edge.TargetID]; !seen {
+				depths[edge.TargetID] = depths[id] + 1
+				queue = append(queue, edge.TargetID)
+			}
+		}
+	}
+	for id := range p.g.Nodes {
+		if _, seen := depths[id]; !seen {
+			depths[id] = 0
+		}
+	}
+	return depths
+}
+
+// setProperty sets a marker property, keeping the running total in step.
+func (p *pruner) setProperty(node *glpg.GLPGNode, key string, value interface{}) {
+	if old, ok := node.Properties[key]; ok {
+		p.total -= p.propertyCost(key, old)
+	}
+	p.g.UpdateProperties(node.ID, glpg.GLPGProperty{key: value})
+	p.total += p.propertyCost(key, value)
+}
+
+func (p *pruner) sortedNodes() []*glpg.GLPGNode {
+	nodes := make([]*glpg.GLPGNode, 0, len(p.g.Nodes))
+	for _, node := range p.g.Nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+func sortedKeys(props glpg.GLPGProperty) []string {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func hasLabel(node *glpg.GLPGNode, label string) bool {
+	for _, l := range node.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmpty reports whether a property value carries nothing: nil, an empty
+// string or an empty collection. false and 0 are kept, since they say
+// something.
+func isEmpty(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case map[string]interface{}:
+		return len(val) == 0
+	case map[string]string:
+		return len(val) == 0
+	case []string:
+		return len(val) == 0
+	case []interface{}:
+		return len(val) == 0
+	}
+	return false
+}
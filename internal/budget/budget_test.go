@@ -0,0 +1,128 @@
+package budget
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"lazybox/internal/glpg"
+)
+
+var words = TokenizerFunc(func(s string) int { return len(strings.Fields(s)) })
+
+// budgetGraph builds pkg -> src -> main.go -> content, with two file
+// contents (the content node and src's summary), two empty properties and
+// three low-priority ones.
+func budgetGraph(t *testing.T) *glpg.GLPG {
+	t.Helper()
+	g := glpg.NewGLPG()
+	for _, node := range []*glpg.GLPGNode{
+		{ID: "pkg", Labels: []string{"PackageInfo"}, Properties: glpg.GLPGProperty{"Name": "demo"}},
+		{ID: "src", Labels: []string{"FileInfo", "directory"}, Properties: glpg.GLPGProperty{
+			"Name": "src", "ContentSummary": "two files", "Owner": "", "ModTime": "2024-01-01"}},
+		{ID: "main", Labels: []string{"FileInfo", "file"}, Properties: glpg.GLPGProperty{
+			"Name": "main.go", "Mode": "-rw-r--r--", "ModTime": "2024-01-01", "Group": ""}},
+		{ID: "content", Labels: []string{"FileContent"}, Properties: glpg.GLPGProperty{
+			"Content": strings.Repeat("func main ( ) { } ", 5)}},
+	} {
+		g.AddNode(node)
+	}
+	for _, e := range [][3]string{{"pkg", "CONTAINS", "src"}, {"src", "CONTAINS", "main"}, {"main", "HAS_CONTENT", "content"}} {
+		if g.Connect(e[0], e[1], e[2]) == nil {
+			t.Fatalf("connecting %v", e)
+		}
+	}
+	return g
+}
+
+func TestEstimate(t *testing.T) {
+	g := glpg.NewGLPG()
+	g.AddNode(&glpg.GLPGNode{ID: "a", Labels: []string{"A"}, Properties: glpg.GLPGProperty{"Name": "x y"}})
+	g.AddNode(&glpg.GLPGNode{ID: "b", Labels: []string{"B"}, Properties: glpg.GLPGProperty{}})
+	g.Connect("a", "LINK", "b")
+
+	// Node: overhead + ID + labels; property: overhead + key + value;
+	// edge: overhead + label
+	want := (nodeOverhead + 1 + 1 + propertyOverhead + 1 + 2) + (nodeOverhead + 1 + 1) + (edgeOverhead + 1)
+	if got := Estimate(g, words); got != want {
+		t.Errorf("Estimate with a word count = %d, want %d", got, want)
+	}
+	perByte := TokenizerFunc(func(s string) int { return len(s) })
+	want = (nodeOverhead + 1 + 1 + propertyOverhead + 4 + 3) + (nodeOverhead + 1 + 1) + (edgeOverhead + 4)
+	if got := Estimate(g, perByte); got != want {
+		t.Errorf("Estimate with a byte count = %d, want %d", got, want)
+	}
+}
+
+func TestPruneWithinBudget(t *testing.T) {
+	g := budgetGraph(t)
+	before := Estimate(g, words)
+	r := Prune(g, before, words)
+	if r.Pruned() || r.Before != before || r.After != before {
+		t.Errorf("Prune at the graph's own size = %s, want nothing cut", r)
+	}
+}
+
+func TestPruneContentsFirst(t *testing.T) {
+	g := budgetGraph(t)
+	r := Prune(g, Estimate(g, words)-1, words)
+	if r.ContentsDropped != 1 || r.EmptyDropped != 0 || len(r.PropsDropped) != 0 || r.NodesDropped != 0 {
+		t.Fatalf("Prune just under budget = %s, want only the largest content cut", r)
+	}
+	if g.GetNode("content") != nil {
+		t.Errorf("the FileContent node survived")
+	}
+	if g.GetNode("main").Properties["ContentDropped"] != true {
+		t.Errorf("main.go is not marked ContentDropped")
+	}
+	if _, ok := g.GetNode("src").Properties["ContentSummary"]; !ok {
+		t.Errorf("the smaller content was cut too")
+	}
+}
+
+func TestPruneOrder(t *testing.T) {
+	lowPriority := map[string]int{"ModTime": 2, "Mode": 1}
+	for budget := Estimate(budgetGraph(t), words); budget >= 0; budget-- {
+		g := budgetGraph(t)
+		r := Prune(g, budget, words)
+
+		if got := Estimate(g, words); r.After != got {
+			t.Fatalf("budget %d: report says ~%d tokens, graph estimates at %d", budget, r.After, got)
+		}
+		if r.After > budget && len(g.Nodes) != 1 {
+			t.Errorf("budget %d: still over budget with %d nodes left", budget, len(g.Nodes))
+		}
+		if r.EmptyDropped > 0 && r.ContentsDropped != 2 {
+			t.Errorf("budget %d: empty properties cut before all contents (%s)", budget, r)
+		}
+		if len(r.PropsDropped) > 0 && r.EmptyDropped != 2 {
+			t.Errorf("budget %d: low-priority properties cut before empty ones (%s)", budget, r)
+		}
+		if r.PropsDropped["Mode"] > 0 && r.PropsDropped["ModTime"] != 2 {
+			t.Errorf("budget %d: Mode cut before ModTime (%s)", budget, r)
+		}
+		if r.NodesDropped > 0 && !reflect.DeepEqual(r.PropsDropped, lowPriority) {
+			t.Errorf("budget %d: nodes cut before every low-priority property (%s)", budget, r)
+		}
+		if r.NodesDropped == 1 && (g.GetNode("main") != nil || r.CutDepth != 2) {
+			t.Errorf("budget %d: the first node cut is not the deepest (%s)", budget, r)
+		}
+	}
+}
+
+func TestPruneKeepsRoots(t *testing.T) {
+	g := budgetGraph(t)
+	r := Prune(g, 0, words)
+	if len(g.Nodes) != 1 || g.GetNode("pkg") == nil {
+		t.Fatalf("Prune to 0 left %d nodes, want only the root", len(g.Nodes))
+	}
+	if r.NodesDropped != 2 || r.CutDepth != 1 {
+		t.Errorf("Prune to 0 = %s, want src and main.go cut from depth 1", r)
+	}
+	if g.GetNode("pkg").Properties["Truncated"] != true {
+		t.Errorf("the root is not marked Truncated")
+	}
+	if !strings.Contains(r.String(), "still over budget") {
+		t.Errorf("report %q does not say it is over budget", r)
+	}
+}
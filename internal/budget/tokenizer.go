@@ -0,0 +1,131 @@
+package budget
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer estimates how many LLM tokens a string costs.
+type Tokenizer interface {
+	Count(s string) int
+}
+
+// TokenizerFunc adapts a function to the Tokenizer interface.
+type TokenizerFunc func(s string) int
+
+// Count calls f(s).
+func (f TokenizerFunc) Count(s string) int { return f(s) }
+
+// DefaultTokenizer is the name of the tokenizer used when none is given.
+const DefaultTokenizer = "approx"
+
+var (
+	tokenizersMu sync.RWMutex
+	tokenizers   = map[string]Tokenizer{
+		"approx": TokenizerFunc(ApproxTokens),
+		"chars":  TokenizerFunc(func(s string) int { return (utf8.RuneCountInString(s) + 3) / 4 }),
+		"words":  TokenizerFunc(func(s string) int { return len(strings.Fields(s)) }),
+	}
+)
+
+// RegisterTokenizer makes a tokenizer available by name, replacing any
+// tokenizer already registered under that name.
+func RegisterTokenizer(name string, t Tokenizer) {
+	tokenizersMu.Lock()
+	defer tokenizersMu.Unlock()
+	tokenizers[strings.ToLower(name)] = t
+}
+
+// GetTokenizer returns the tokenizer registered under name.
+func GetTokenizer(name string) (Tokenizer, error) {
+	if name == "" {
+		name = DefaultTokenizer
+	}
+	tokenizersMu.RLock()
+	defer tokenizersMu.RUnlock()
+	t, ok := tokenizers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown tokenizer %q (available: %s)", name, strings.Join(tokenizerNames(), ", "))
+	}
+	return t, nil
+}
+
+// tokenizerNames lists the registered tokenizers; the caller holds tokenizersMu.
+func tokenizerNames() []string {
+	names := make([]string, 0, len(tokenizers))
+	for name := range tokenizers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApproxTokens approximates the token count of a BPE tokenizer such as
+// cl100k. Text is pre-split the way BPE tokenizers do (letter runs with an
+// optional leading space, digit runs, punctuation runs and whitespace), and
+// each piece is charged by length: letter runs cost about one token per four
+// characters, digits are grouped in threes, and every punctuation or
+// non-ASCII character costs roughly one token.
+func ApproxTokens(s string) int {
+	tokens := 0
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == ' ' && i+size < len(s) && isWordRune(nextRune(s[i+size:])):
+			// A single leading space merges into the following word
+			i += size
+		case isWordRune(r):
+			n := 0
+			for i < len(s) {
+				r, size = utf8.DecodeRuneInString(s[i:])
+				if !isWordRune(r) {
+					break
+				}
+				if r < utf8.RuneSelf {
+					n++
+				} else {
+					n += 4 // multi-byte letters rarely merge
+				}
+				i += size
+			}
+			tokens += (n + 3) / 4
+		case unicode.IsDigit(r):
+			n := 0
+			for i < len(s) {
+				r, size = utf8.DecodeRuneInString(s[i:])
+				if !unicode.IsDigit(r) {
+					break
+				}
+				n++
+				i += size
+			}
+			tokens += (n + 2) / 3
+		case unicode.IsSpace(r):
+			for i < len(s) {
+				r, size = utf8.DecodeRuneInString(s[i:])
+				if !unicode.IsSpace(r) {
+					break
+				}
+				i += size
+			}
+			tokens++
+		default:
+			tokens++
+			i += size
+		}
+	}
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+func nextRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
@@ -0,0 +1,61 @@
+package budget
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApproxTokens(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"hello", 2},
+		{"hello world", 4}, // the space merges into "world"
+		{"foo  bar", 3},    // a run of spaces is a token of its own
+		{"snake_case", 3},
+		{"12345", 2},
+		{"a, b", 3},
+		{"{}", 2},
+		{"日本", 2},
+	}
+	for _, tt := range tests {
+		if got := ApproxTokens(tt.in); got != tt.want {
+			t.Errorf("ApproxTokens(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGetTokenizer(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want int
+	}{
+		{"", "hello world", 4}, // the default, approx
+		{"approx", "hello world", 4},
+		{"chars", "hello world", 3},
+		{"chars", "日本語", 1},
+		{"WORDS", "a b  c", 3},
+	}
+	for _, tt := range tests {
+		tok, err := GetTokenizer(tt.name)
+		if err != nil {
+			t.Fatalf("GetTokenizer(%q): %v", tt.name, err)
+		}
+		if got := tok.Count(tt.in); got != tt.want {
+			t.Errorf("%q tokenizer counts %q as %d, want %d", tt.name, tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := GetTokenizer("gpt2"); err == nil || !strings.Contains(err.Error(), "available: approx, chars, words") {
+		t.Errorf("GetTokenizer of an unknown name: %v", err)
+	}
+
+	RegisterTokenizer("Bytes", TokenizerFunc(func(s string) int { return len(s) }))
+	tok, err := GetTokenizer("bytes")
+	if err != nil || tok.Count("日本") != 6 {
+		t.Errorf("registered tokenizer not found or wrong: %v", err)
+	}
+}
@@ -1,12 +1,253 @@
 package code
 
 import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
 	"io/ioutil"
 	"lazybox/internal/ir"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+// Extract parses a source file and returns its declarations as an ir.CodeInfo.
+// Only Go sources are supported for now.
+func Extract(path string) (*ir.CodeInfo, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if !IsSupported(absPath) {
+		return nil, fmt.Errorf("unsupported language for %s", path)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, absPath, nil, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	ci := ExtractFile(fset, file)
+	ci.Name = filepath.Base(absPath)
+	ci.Path = absPath
+	return ci, nil
+}
+
+// IsSupported reports whether Extract can parse the file at path.
+func IsSupported(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".go"
+}
+
+// ExtractFile converts a parsed Go file into an ir.CodeInfo.
+// Name and Path are left for the caller to fill in.
+func ExtractFile(fset *token.FileSet, file *ast.File) *ir.CodeInfo {
+	ci := &ir.CodeInfo{
+		Language: "go",
+		Package:  file.Name.Name,
+		Doc:      docText(file.Doc),
+	}
+
+	for _, spec := range file.Imports {
+		imp := &ir.ImportInfo{
+			Path:     strings.Trim(spec.Path.Value, "\"`"),
+			Position: position(fset, spec),
+		}
+		if spec.Name != nil {
+			imp.Alias = spec.Name.Name
+		}
+		ci.Imports = append(ci.Imports, imp)
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			ci.Funcs = append(ci.Funcs, funcInfo(fset, d))
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					ci.Types = append(ci.Types, typeInfo(fset, d, s))
+				case *ast.ValueSpec:
+					values := valueInfos(fset, d, s)
+					if d.Tok == token.CONST {
+						ci.Consts = append(ci.Consts, values...)
+					} else {
+						ci.Vars = append(ci.Vars, values...)
+					}
+				}
+			}
+		}
+	}
+	return ci
+}
+
+func funcInfo(fset *token.FileSet, d *ast.FuncDecl) *ir.FuncInfo {
+	// Print the declaration without its body to get the signature.
+	sig := *d
+	sig.Body = nil
+	sig.Doc = nil
+	fi := &ir.FuncInfo{
+		Name:      d.Name.Name,
+		Signature: nodeString(fset, &sig),
+		Doc:       docText(d.Doc),
+		Exported:  d.Name.IsExported(),
+		Position:  position(fset, d),
+	}
+	if d.Recv != nil && len(d.Recv.List) > 0 {
+		fi.Receiver = ReceiverTypeName(d.Recv.List[0].Type)
+	}
+	return fi
+}
+
+func typeInfo(fset *token.FileSet, d *ast.GenDecl, s *ast.TypeSpec) *ir.TypeInfo {
+	ti := &ir.TypeInfo{
+		Name:       s.Name.Name,
+		Kind:       typeKind(s),
+		Definition: "type " + nodeString(fset, s),
+		Doc:        specDoc(d, s.Doc),
+		Exported:   s.Name.IsExported(),
+		Position:   position(fset, s),
+	}
+	var fields *ast.FieldList
+	switch t := s.Type.(type) {
+	case *ast.StructType:
+		fields = t.Fields
+	case *ast.InterfaceType:
+		fields = t.Methods
+	}
+	if fields == nil {
+		return ti
+	}
+	for _, f := range fields.List {
+		typ := nodeString(fset, f.Type)
+		tag := ""
+		if f.Tag != nil {
+			tag = f.Tag.Value
+		}
+		if len(f.Names) == 0 {
+			ti.Fields = append(ti.Fields, &ir.FieldInfo{Type: typ, Tag: tag, Doc: docText(f.Doc), Embedded: true})
+			ti.Embeds = append(ti.Embeds, typ)
+			continue
+		}
+		for _, name := range f.Names {
+			ti.Fields = append(ti.Fields, &ir.FieldInfo{Name: name.Name, Type: typ, Tag: tag, Doc: docText(f.Doc)})
+		}
+	}
+	return ti
+}
+
+func valueInfos(fset *token.FileSet, d *ast.GenDecl, s *ast.ValueSpec) []*ir.ValueInfo {
+	kind := "var"
+	if d.Tok == token.CONST {
+		kind = "const"
+	}
+	typ := ""
+	if s.Type != nil {
+		typ = nodeString(fset, s.Type)
+	}
+	values := make([]*ir.ValueInfo, 0, len(s.Names))
+	for i, name := range s.Names {
+		vi := &ir.ValueInfo{
+			Name:     name.Name,
+			Kind:     kind,
+			Type:     typ,
+			Doc:      specDoc(d, s.Doc),
+			Exported: name.IsExported(),
+			Position: position(fset, name),
+		}
+		if i < len(s.Values) {
+			vi.Value = nodeString(fset, s.Values[i])
+		}
+		values = append(values, vi)
+	}
+	return values
+}
+
+// ReceiverTypeName returns the base type name of a method receiver,
+// stripping pointers and type parameters (e.g. *List[T] -> List).
+func ReceiverTypeName(expr ast.Expr) string {
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+		case *ast.ParenExpr:
+			expr = t.X
+		case *ast.IndexExpr:
+			expr = t.X
+		case *ast.IndexListExpr:
+			expr = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+func typeKind(s *ast.TypeSpec) string {
+	if s.Assign.IsValid() {
+		return "alias"
+	}
+	switch s.Type.(type) {
+	case *ast.StructType:
+		return "struct"
+	case *ast.InterfaceType:
+		return "interface"
+	case *ast.FuncType:
+		return "func"
+	case *ast.MapType:
+		return "map"
+	case *ast.ArrayType:
+		return "array"
+	case *ast.ChanType:
+		return "chan"
+	case *ast.StarExpr:
+		return "pointer"
+	default:
+		return "named"
+	}
+}
+
+// specDoc prefers the spec's own doc comment, falling back to the
+// declaration's doc for single-spec declarations like `type X struct{}`.
+func specDoc(d *ast.GenDecl, doc *ast.CommentGroup) string {
+	if doc != nil {
+		return docText(doc)
+	}
+	if len(d.Specs) == 1 {
+		return docText(d.Doc)
+	}
+	return ""
+}
+
+func docText(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	return strings.TrimSpace(doc.Text())
+}
+
+func position(fset *token.FileSet, node ast.Node) ir.Position {
+	start := fset.Position(node.Pos())
+	end := fset.Position(node.End())
+	return ir.Position{
+		Line:      start.Line,
+		Column:    start.Column,
+		EndLine:   end.Line,
+		EndColumn: end.Column,
+	}
+}
+
+func nodeString(fset *token.FileSet, node interface{}) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
 func ExtractPlaceholder(path string) (*ir.FileInfo, error) {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
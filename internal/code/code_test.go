@@ -0,0 +1,61 @@
+package code
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	ci, err := Extract("testdata/demo/demo.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ci.Package != "demo" || ci.Name != "demo.go" || ci.Doc != "Package demo is a fixture for the code and api extractors." {
+		t.Errorf("file = %s %s %q", ci.Package, ci.Name, ci.Doc)
+	}
+
+	var imports []string
+	for _, imp := range ci.Imports {
+		imports = append(imports, strings.TrimPrefix(imp.Alias+" ", " ")+imp.Path)
+	}
+	if got, want := strings.Join(imports, ", "), "fmt, str strings"; got != want {
+		t.Errorf("imports = %q, want %q", got, want)
+	}
+
+	var funcs []string
+	for _, fi := range ci.Funcs {
+		funcs = append(funcs, fmt.Sprintf("%s.%s:%d", fi.Receiver, fi.Name, fi.Position.Line))
+	}
+	if got, want := strings.Join(funcs, " "), "Widget.Size:26 Widget.hidden:28 .init:30 .init:32"; got != want {
+		t.Errorf("funcs = %q, want %q", got, want)
+	}
+
+	var types []string
+	for _, ti := range ci.Types {
+		var fields []string
+		for _, f := range ti.Fields {
+			fields = append(fields, f.Name+":"+f.Type)
+		}
+		types = append(types, fmt.Sprintf("%s %s embeds%v fields%v", ti.Name, ti.Kind, ti.Embeds, fields))
+	}
+	want := "Base struct embeds[] fields[ID:int]; " +
+		"Named struct embeds[] fields[Label:string]; " +
+		"Widget struct embeds[Base *Named] fields[:Base :*Named Name:string count:int]"
+	if got := strings.Join(types, "; "); got != want {
+		t.Errorf("types =\n%s\nwant\n%s", got, want)
+	}
+
+	if len(ci.Consts) != 1 || ci.Consts[0].Value != `"1.0"` || !ci.Consts[0].Exported {
+		t.Errorf("consts = %+v, want the exported Version", ci.Consts)
+	}
+	if len(ci.Vars) != 1 || ci.Vars[0].Name != "defaultWidget" || ci.Vars[0].Exported {
+		t.Errorf("vars = %+v, want the unexported defaultWidget", ci.Vars)
+	}
+}
+
+func TestExtractUnsupported(t *testing.T) {
+	if _, err := Extract("testdata/demo"); err == nil || !strings.Contains(err.Error(), "unsupported language") {
+		t.Errorf("Extract of a directory: %v", err)
+	}
+}
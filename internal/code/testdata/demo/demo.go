@@ -0,0 +1,37 @@
+// Package demo is a fixture for the code and api extractors.
+package demo
+
+import (
+	"fmt"
+	str "strings"
+)
+
+// Base is embedded by Widget.
+type Base struct {
+	ID int
+}
+
+// Named is embedded by pointer.
+type Named struct{ Label string }
+
+// Widget embeds Base and Named.
+type Widget struct {
+	Base
+	*Named
+	Name  string `json:"name"`
+	count int
+}
+
+// Size returns the widget's size.
+func (w *Widget) Size() int { return len(w.Name) + w.count }
+
+func (w Widget) hidden() {}
+
+func init() { fmt.Sprint() }
+
+func init() { str.TrimSpace("") }
+
+// Version is the fixture's version.
+const Version = "1.0"
+
+var defaultWidget = Widget{}
@@ -0,0 +1,6 @@
+//go:build !demo_a
+
+package demo
+
+// Platform is declared once per build constraint.
+type Platform struct{ B int }
@@ -1,8 +1,10 @@
 package file
 
 import (
+	"io"
 	"io/ioutil"
 	"lazybox/internal/ir"
+	"lazybox/internal/text"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,6 +13,13 @@ import (
 // Read reads a file and returns its metadata and contents as an ir.FileInfo
 // It will also perform a basic text analysis if the file seems to be text-based.
 func Read(path string) (*ir.FileInfo, error) {
+	return ReadWithLimit(path, 0)
+}
+
+// ReadWithLimit is like Read but reads at most maxSize bytes of content
+// (0 for unlimited). Files cut short are marked Truncated; Size still
+// reports the full size on disk.
+func ReadWithLimit(path string, maxSize int64) (*ir.FileInfo, error) {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
 		return nil, err
@@ -37,8 +46,9 @@ func Read(path string) (*ir.FileInfo, error) {
 	}
 
 	// Attempt to read content
-	contentBytes, readErr := ioutil.ReadFile(absPath)
+	contentBytes, truncated, readErr := ReadContent(absPath, maxSize)
 	if readErr == nil {
+		fileInfo.Truncated = truncated
 		contentStr := string(contentBytes) // Create a string variable
 		fileInfo.Content = &contentStr     // Assign its address
 
@@ -48,7 +58,7 @@ func Read(path string) (*ir.FileInfo, error) {
 		}
 		// Populate TextAnalysis fields
 		if fileInfo.Content != nil { // Check if content is not nil before dereferencing
-			fileInfo.TextAnalysis.LineCount = len(strings.Split(*fileInfo.Content, "\n"))
+			fileInfo.TextAnalysis.LineCount = text.CountLines(*fileInfo.Content)
 			fileInfo.TextAnalysis.WordCount = len(strings.Fields(*fileInfo.Content))
 		} else {
 			fileInfo.TextAnalysis.LineCount = 0
@@ -72,6 +82,29 @@ func Read(path string) (*ir.FileInfo, error) {
 	return fileInfo, nil
 }
 
+// ReadContent reads up to maxSize bytes from the file at path (all of it if
+// maxSize is 0) and reports whether the file was longer than that.
+func ReadContent(path string, maxSize int64) ([]byte, bool, error) {
+	if maxSize <= 0 {
+		data, err := ioutil.ReadFile(path)
+		return data, false, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	// Read one extra byte to detect whether anything was left behind.
+	data, err := ioutil.ReadAll(io.LimitReader(f, maxSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(data)) > maxSize {
+		return data[:maxSize], true, nil
+	}
+	return data, false, nil
+}
+
 // Helper functions for OS-specific info (to be implemented)
 // func getOwner(path string) string { /* ... */ return "" }
 // func getGroup(path string) string { /* ... */ return "" }
@@ -6,18 +6,175 @@ import (
 	"lazybox/internal/ir"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 	// "syscall" // For owner/group/createtime - OS specific, handle later
 )
 
+// Options controls how Scan walks a tree.
+type Options struct {
+	NoIgnore bool     // Don't honor .gitignore/.lazyboxignore files or skip .git
+	Include  []string // If set, only files matching one of these globs are kept
+	Exclude  []string // Entries matching any of these globs are skipped
+
+	MaxDepth    int   // Maximum directory depth below the root to descend into, 0 for unlimited
+	MaxFiles    int   // Maximum number of entries to collect below the root, 0 for unlimited
+	MaxFileSize int64 // Maximum number of content bytes read per file by content readers, 0 for unlimited
+
+	// Workers bounds how many directory entries are scanned concurrently,
+	// 0 for one per CPU. Children keep their directory order and MaxFiles
+	// keeps the first entries level by level, regardless.
+	Workers int
+
+	// OnEntry, if set, is called with each entry and the directory it was
+	// found in (nil for the scan root) as soon as the entry's own metadata is
+	// known, after its parent's call and before any of its children are
+	// scanned. It may be called from several goroutines at once. Truncated
+	// and SkippedEntries are not final when it is called.
+	OnEntry func(entry, parent *ir.FileInfo)
+}
+
+// scanner holds the state shared by a single scan.
+type scanner struct {
+	opts    Options
+	include *Matcher
+	exclude *Matcher
+	globDir string // Scan root relative to the match root, which include/exclude globs are relative to
+	workers int
+	count   int // Entries collected so far, for MaxFiles
+	git     *gitCache
+}
+
 // Scan recursively scans a directory or gets info for a file.
 func Scan(path string) (*ir.FileInfo, error) {
+	return ScanWithOptions(path, Options{})
+}
+
+// ScanWithOptions recursively scans a directory or gets info for a file,
+// applying ignore files and include/exclude globs while walking so that
+// ignored subtrees are never read or stat'ed.
+func ScanWithOptions(path string, opts Options) (*ir.FileInfo, error) {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get absolute path for %s: %w", path, err)
 	}
 
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	s := &scanner{
+		opts:    opts,
+		workers: workers,
+		git:     newGitCache(),
+	}
+	if len(opts.Include) > 0 {
+		if s.include, err = NewMatcher(opts.Include); err != nil {
+			return nil, fmt.Errorf("invalid include pattern: %w", err)
+		}
+	}
+	if s.exclude, err = NewMatcher(opts.Exclude); err != nil {
+		return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+
+	// Ignore files are matched relative to the enclosing repository, so rules
+	// from a parent .gitignore still apply when scanning a subdirectory.
+	// Include and exclude globs stay relative to the scan root.
+	matchRoot := absPath
+	if info, err := os.Stat(absPath); err == nil && !info.IsDir() {
+		matchRoot = filepath.Dir(absPath)
+	}
+	scanDir := matchRoot
+	var matcher *Matcher
+	if !opts.NoIgnore {
+		if isGit, gitDir := s.git.lookup(matchRoot, true); isGit {
+			repoRoot := filepath.Dir(gitDir)
+			var dirs []string
+			for dir := filepath.Dir(matchRoot); strings.HasPrefix(dir, repoRoot); dir = filepath.Dir(dir) {
+				dirs = append([]string{dir}, dirs...)
+				if dir == repoRoot {
+					break
+				}
+			}
+			for _, dir := range dirs {
+				rel, _ := filepath.Rel(repoRoot, dir)
+				matcher = matcher.withDir(dir, relBase(rel))
+			}
+			matchRoot = repoRoot
+		}
+	}
+	if dir, err := filepath.Rel(matchRoot, scanDir); err == nil {
+		s.globDir = relBase(dir)
+	}
+	rel, err := filepath.Rel(matchRoot, absPath)
+	if err != nil {
+		rel = "."
+	}
+	fileIR, err := s.stat(path, absPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if fileIR.Type == ir.FileTypeDir {
+		s.walk(&dirScan{fileIR: fileIR, path: path, absPath: absPath, rel: relBase(rel), matcher: matcher})
+	}
+	return fileIR, nil
+}
+
+// relBase converts a filepath.Rel result into a slash-separated match path.
+func relBase(rel string) string {
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		return ""
+	}
+	return rel
+}
+
+// skip reports whether an entry should be left out of the scan. rel is the
+// entry's slash-separated path relative to the match root; include and
+// exclude globs see it relative to the scan root instead.
+func (s *scanner) skip(name, rel string, isDir bool, matcher *Matcher) bool {
+	if !s.opts.NoIgnore {
+		if name == ".git" {
+			return true
+		}
+		if matcher.Match(rel, isDir) {
+			return true
+		}
+	}
+	if s.globDir != "" {
+		rel = strings.TrimPrefix(rel, s.globDir+"/")
+	}
+	if s.exclude.Match(rel, isDir) {
+		return true
+	}
+	if s.include != nil && !isDir && !s.included(rel) {
+		return true
+	}
+	return false
+}
+
+// included reports whether the file at rel, or one of the directories it is
+// in, matches an include glob, so that including a directory keeps its
+// contents.
+func (s *scanner) included(rel string) bool {
+	if s.include.Match(rel, false) {
+		return true
+	}
+	for i := strings.LastIndexByte(rel, '/'); i > 0; i = strings.LastIndexByte(rel, '/') {
+		rel = rel[:i]
+		if s.include.Match(rel, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// stat builds the FileInfo for absPath from its own metadata, without
+// reading any children. parent is the directory it was found in.
+func (s *scanner) stat(path, absPath string, parent *ir.FileInfo) (*ir.FileInfo, error) {
 	info, err := os.Lstat(absPath) // Use Lstat to get info about symlink itself
 	if err != nil {
 		return nil, fmt.Errorf("failed to stat %s: %w", absPath, err)
@@ -27,6 +184,7 @@ func Scan(path string) (*ir.FileInfo, error) {
 		Name:         info.Name(),
 		Path:         path, // Original path provided
 		AbsolutePath: absPath,
+		IsDir:        info.IsDir(),
 		Size:         info.Size(),
 		Mode:         info.Mode().String(),
 		ModTime:      info.ModTime(),
@@ -55,13 +213,13 @@ func Scan(path string) (*ir.FileInfo, error) {
 	// }
 
 	// Git repository detection
-	isGit, gitDir := isGitRepo(absPath)
+	isGit, gitDir := s.git.lookup(absPath, info.IsDir())
 	if fileIR.Metadata == nil {
 		fileIR.Metadata = make(map[string]interface{})
 	}
 	fileIR.Metadata["is_git_repo"] = isGit // Corrected: Use Metadata
 	if isGit {
-		remotes, err := getGitRemotes(gitDir)
+		remotes, err := s.git.gitRemotes(gitDir)
 		if err == nil {
 			fileIR.Metadata["git_remotes"] = remotes // Corrected: Use Metadata
 		} else {
@@ -70,67 +228,157 @@ func Scan(path string) (*ir.FileInfo, error) {
 		}
 	}
 
-	if fileIR.Type == ir.FileTypeDir { // Corrected: FileTypeDir
-		entries, err := os.ReadDir(absPath)
-		if err != nil {
-			fileIR.Error += fmt.Sprintf("; failed to read directory %s: %v", absPath, err)
-			return fileIR, nil
-		}
+	if s.opts.OnEntry != nil {
+		s.opts.OnEntry(fileIR, parent)
+	}
+	return fileIR, nil
+}
+
+// dirScan is a directory waiting for its children to be scanned. rel is
+// absPath relative to the match root, "" for the root itself.
+type dirScan struct {
+	fileIR  *ir.FileInfo
+	path    string
+	absPath string
+	rel     string
+	matcher *Matcher
+	entries []os.DirEntry // Entries left after ignore rules and globs
+}
 
-		fileIR.Children = make([]*ir.FileInfo, 0, len(entries)) // Corrected: Children
-		for _, entry := range entries {
-			entryPath := filepath.Join(absPath, entry.Name())
-
-			entryIR, err := Scan(entryPath) // Recursive call
-			if err != nil {
-				errorEntryIR := &ir.FileInfo{
-					Name:         entry.Name(),
-					Path:         filepath.Join(path, entry.Name()),
-					AbsolutePath: entryPath,
-					Error:        err.Error(),
+// walk scans the tree below root one level at a time. Each level's
+// directories are read in parallel, then MaxDepth and MaxFiles are applied
+// to their entries in directory order on a single goroutine, so the same
+// entries make the cut whatever the worker count. Directories cut short are
+// marked Truncated with the number of entries left out in SkippedEntries.
+func (s *scanner) walk(root *dirScan) {
+	level := []*dirScan{root}
+	for depth := 0; len(level) > 0; depth++ {
+		s.parallel(len(level), func(i int) { s.readDir(level[i]) })
+
+		type slot struct {
+			dir   *dirScan
+			entry os.DirEntry
+			index int
+		}
+		var slots []slot
+		for _, dir := range level {
+			n := 0
+			for _, entry := range dir.entries {
+				if (s.opts.MaxDepth > 0 && depth >= s.opts.MaxDepth) || !s.reserve() {
+					dir.fileIR.Truncated = true
+					dir.fileIR.SkippedEntries++
+					continue
 				}
-				entryInfo, statErr := os.Lstat(entryPath)
-				if statErr == nil {
-					if entryInfo.IsDir() {
-						errorEntryIR.Type = ir.FileTypeDir // Corrected: FileTypeDir
-					} else if entryInfo.Mode()&os.ModeSymlink != 0 {
-						errorEntryIR.Type = ir.FileTypeSymlink
-					} else {
-						errorEntryIR.Type = ir.FileTypeFile
-					}
+				slots = append(slots, slot{dir, entry, n})
+				n++
+			}
+			dir.fileIR.Children = make([]*ir.FileInfo, n) // Corrected: Children
+		}
+
+		next := make([]*dirScan, len(slots))
+		s.parallel(len(slots), func(i int) {
+			sl := slots[i]
+			child, ok := s.scanEntry(sl.dir, sl.entry)
+			sl.dir.fileIR.Children[sl.index] = child
+			if ok && child.Type == ir.FileTypeDir {
+				next[i] = &dirScan{
+					fileIR:  child,
+					path:    child.Path,
+					absPath: child.AbsolutePath,
+					rel:     relSlash(sl.dir.rel, sl.entry.Name()),
+					matcher: sl.dir.matcher,
 				}
-				fileIR.Children = append(fileIR.Children, errorEntryIR) // Corrected: Children
-				continue
 			}
-			fileIR.Children = append(fileIR.Children, entryIR) // Corrected: Children
+		})
+
+		level = level[:0]
+		for _, dir := range next {
+			if dir != nil {
+				level = append(level, dir)
+			}
 		}
 	}
-
-	return fileIR, nil
 }
 
-// isGitRepo checks if the given path or any of its parents is a Git repository.
-// It returns true and the path to the .git directory if found.
-func isGitRepo(path string) (bool, string) {
-	currentPath := path
-	if fi, err := os.Stat(path); err == nil && !fi.IsDir() { // If path is a file, start check from its directory
-		currentPath = filepath.Dir(path)
+// readDir reads dir's entries, keeping those not skipped by ignore rules or
+// globs.
+func (s *scanner) readDir(dir *dirScan) {
+	entries, err := os.ReadDir(dir.absPath)
+	if err != nil {
+		dir.fileIR.Error += fmt.Sprintf("; failed to read directory %s: %v", dir.absPath, err)
+		return
 	}
 
-	for {
-		gitPath := filepath.Join(currentPath, ".git")
-		stat, err := os.Stat(gitPath)
-		if err == nil && stat.IsDir() {
-			return true, gitPath
+	if !s.opts.NoIgnore {
+		dir.matcher = dir.matcher.withDir(dir.absPath, dir.rel)
+	}
+	for _, entry := range entries {
+		if !s.skip(entry.Name(), relSlash(dir.rel, entry.Name()), entry.IsDir(), dir.matcher) {
+			dir.entries = append(dir.entries, entry)
 		}
+	}
+}
 
-		parent := filepath.Dir(currentPath)
-		if parent == currentPath { // Reached root or invalid path
-			break
+// parallel calls fn for each index below n on up to Workers goroutines.
+func (s *scanner) parallel(n int, fn func(i int)) {
+	workers := min(s.workers, n)
+	if workers <= 1 {
+		for i := range n {
+			fn(i)
+		}
+		return
+	}
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int(next.Add(1) - 1); i < n; i = int(next.Add(1) - 1) {
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// reserve claims a slot for one more entry under MaxFiles.
+func (s *scanner) reserve() bool {
+	if s.opts.MaxFiles <= 0 {
+		return true
+	}
+	if s.count >= s.opts.MaxFiles {
+		return false
+	}
+	s.count++
+	return true
+}
+
+// scanEntry stats one entry of dir, turning failures into an error entry.
+// It reports whether the stat succeeded.
+func (s *scanner) scanEntry(dir *dirScan, entry os.DirEntry) (*ir.FileInfo, bool) {
+	entryPath := filepath.Join(dir.absPath, entry.Name())
+	entryIR, err := s.stat(entryPath, entryPath, dir.fileIR)
+	if err == nil {
+		return entryIR, true
+	}
+	errorEntryIR := &ir.FileInfo{
+		Name:         entry.Name(),
+		Path:         filepath.Join(dir.path, entry.Name()),
+		AbsolutePath: entryPath,
+		Error:        err.Error(),
+	}
+	entryInfo, statErr := os.Lstat(entryPath)
+	if statErr == nil {
+		if entryInfo.IsDir() {
+			errorEntryIR.Type = ir.FileTypeDir // Corrected: FileTypeDir
+		} else if entryInfo.Mode()&os.ModeSymlink != 0 {
+			errorEntryIR.Type = ir.FileTypeSymlink
+		} else {
+			errorEntryIR.Type = ir.FileTypeFile
 		}
-		currentPath = parent
 	}
-	return false, ""
+	return errorEntryIR, false
 }
 
 // getGitRemotes parses the .git/config file to find remote origins.
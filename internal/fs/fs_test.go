@@ -0,0 +1,138 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"lazybox/internal/ir"
+)
+
+// writeTree creates the given files, and their directories, under root.
+func writeTree(t *testing.T, root string, files ...string) {
+	t.Helper()
+	for _, name := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// filePaths returns the slash-separated paths of the files below fi.
+func filePaths(fi *ir.FileInfo) []string {
+	var paths []string
+	var walk func(fi *ir.FileInfo, prefix string)
+	walk = func(fi *ir.FileInfo, prefix string) {
+		for _, child := range fi.Children {
+			if child.IsDir {
+				walk(child, prefix+child.Name+"/")
+			} else {
+				paths = append(paths, prefix+child.Name)
+			}
+		}
+	}
+	walk(fi, "")
+	sort.Strings(paths)
+	return paths
+}
+
+func TestScanGlobsAreRelativeToScanRoot(t *testing.T) {
+	for _, inRepo := range []bool{false, true} {
+		root := t.TempDir()
+		writeTree(t, root, "sub/docs/a.md", "sub/docs/b.txt", "sub/c.md")
+		if inRepo {
+			writeTree(t, root, ".git/")
+		}
+
+		fi, err := ScanWithOptions(filepath.Join(root, "sub"), Options{Exclude: []string{"docs/*.md"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := strings.Join(filePaths(fi), " ")
+		if want := "c.md docs/b.txt"; got != want {
+			t.Errorf("in repo %v: exclude docs/*.md kept %q, want %q", inRepo, got, want)
+		}
+
+		fi, err = ScanWithOptions(filepath.Join(root, "sub"), Options{Include: []string{"docs/*"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = strings.Join(filePaths(fi), " ")
+		if want := "docs/a.md docs/b.txt"; got != want {
+			t.Errorf("in repo %v: include docs/* kept %q, want %q", inRepo, got, want)
+		}
+	}
+}
+
+func TestScanAnchoredGlobs(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "x/1", "a/x/2", "a/z/3", "a/y/4", "b/a/z/5")
+
+	fi, err := ScanWithOptions(root, Options{Exclude: []string{"/x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(filePaths(fi), " ")
+	if want := "a/x/2 a/y/4 a/z/3 b/a/z/5"; got != want {
+		t.Errorf("exclude /x kept %q, want %q", got, want)
+	}
+
+	fi, err = ScanWithOptions(root, Options{Include: []string{"/a/z"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = strings.Join(filePaths(fi), " ")
+	if want := "a/z/3"; got != want {
+		t.Errorf("include /a/z kept %q, want %q", got, want)
+	}
+}
+
+func TestScanAppliesParentGitignore(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, ".git/", "sub/docs/a.md", "sub/docs/b.txt", "sub/keep.log", "sub/drop.log")
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("/sub/docs/*.md\n*.log\n!keep.log\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := ScanWithOptions(filepath.Join(root, "sub"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(filePaths(fi), " ")
+	if want := "docs/b.txt keep.log"; got != want {
+		t.Errorf("scan kept %q, want %q", got, want)
+	}
+}
+
+func TestScanMaxFilesIsIndependentOfWorkers(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a", "b", "c", "d"} {
+		for _, dir2 := range []string{"x", "y"} {
+			writeTree(t, root, dir+"/"+dir2+"/1", dir+"/"+dir2+"/2", dir+"/3")
+		}
+	}
+
+	// 4 top-level dirs and 12 entries below them leave one slot for the third level
+	want := "a/3 a/x/1 b/3 c/3 d/3"
+	for _, workers := range []int{1, 2, 16, 16, 16} {
+		fi, err := ScanWithOptions(root, Options{MaxFiles: 17, Workers: workers})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(filePaths(fi), " "); got != want {
+			t.Errorf("%d workers kept %q, want %q", workers, got, want)
+		}
+	}
+}
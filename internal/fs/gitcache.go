@@ -0,0 +1,84 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// gitCache memoizes git repository lookups for a scan. Every directory
+// visited while searching upwards for a .git directory is recorded, so a
+// tree is resolved with one lookup per directory instead of one walk to the
+// filesystem root per entry. It is safe for concurrent use.
+type gitCache struct {
+	mu      sync.Mutex
+	roots   map[string]string            // Directory -> .git path, "" if not in a repository
+	remotes map[string]map[string]string // .git path -> parsed remotes
+}
+
+func newGitCache() *gitCache {
+	return &gitCache{
+		roots:   make(map[string]string),
+		remotes: make(map[string]map[string]string),
+	}
+}
+
+// lookup reports whether path is in a Git repository, searching it and its
+// parents for a .git directory, and returns the path of the one found.
+// isDir tells whether path is a directory, so no stat is needed to decide
+// where the search starts.
+func (c *gitCache) lookup(path string, isDir bool) (bool, string) {
+	dir := path
+	if !isDir {
+		dir = filepath.Dir(path)
+	}
+
+	var visited []string
+	gitDir := ""
+	for {
+		c.mu.Lock()
+		cached, ok := c.roots[dir]
+		c.mu.Unlock()
+		if ok {
+			gitDir = cached
+			break
+		}
+		visited = append(visited, dir)
+		gitPath := filepath.Join(dir, ".git")
+		if stat, err := os.Stat(gitPath); err == nil && stat.IsDir() {
+			gitDir = gitPath
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir { // Reached root or invalid path
+			break
+		}
+		dir = parent
+	}
+
+	c.mu.Lock()
+	for _, v := range visited {
+		c.roots[v] = gitDir
+	}
+	c.mu.Unlock()
+	return gitDir != "", gitDir
+}
+
+// gitRemotes is the cached equivalent of getGitRemotes. The returned map is
+// shared between callers and must not be modified.
+func (c *gitCache) gitRemotes(gitDir string) (map[string]string, error) {
+	c.mu.Lock()
+	remotes, ok := c.remotes[gitDir]
+	c.mu.Unlock()
+	if ok {
+		return remotes, nil
+	}
+	remotes, err := getGitRemotes(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.remotes[gitDir] = remotes
+	c.mu.Unlock()
+	return remotes, nil
+}
@@ -0,0 +1,205 @@
+package fs
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Ignore files read in every scanned directory, in order of precedence
+// (later files override earlier ones).
+var ignoreFileNames = []string{".gitignore", ".lazyboxignore"}
+
+// ignoreRule is a single compiled gitignore-style pattern.
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	base    string // Slash-separated directory the rule is relative to, "" for the match root
+}
+
+// Matcher evaluates gitignore-style rules collected while walking a tree.
+// Paths passed to it are slash-separated and relative to the match root.
+// A Matcher is immutable; withFile returns an extended copy, so sibling
+// directories never see each other's rules.
+type Matcher struct {
+	rules []ignoreRule
+}
+
+// NewMatcher compiles patterns relative to the match root.
+func NewMatcher(patterns []string) (*Matcher, error) {
+	m := &Matcher{}
+	for _, p := range patterns {
+		rule, ok, err := compileIgnorePattern(p, "")
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			m.rules = append(m.rules, rule)
+		}
+	}
+	return m, nil
+}
+
+// Match reports whether relPath is matched by the rules. The last matching
+// rule wins, so negated patterns can re-include earlier matches.
+func (m *Matcher) Match(relPath string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	matched := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		p := relPath
+		if rule.base != "" {
+			if !strings.HasPrefix(p, rule.base+"/") {
+				continue
+			}
+			p = p[len(rule.base)+1:]
+		}
+		if rule.re.MatchString(p) {
+			matched = !rule.negate
+		}
+	}
+	return matched
+}
+
+// withFile returns a Matcher extended with the rules in the ignore file at
+// filePath, which lives in the directory base (relative to the match root).
+// Missing or unreadable files leave the matcher unchanged.
+func (m *Matcher) withFile(filePath, base string) *Matcher {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return m
+	}
+	defer f.Close()
+
+	var added []ignoreRule
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		rule, ok, err := compileIgnorePattern(sc.Text(), base)
+		if err != nil || !ok {
+			continue // Invalid patterns are ignored, like git does
+		}
+		added = append(added, rule)
+	}
+	if len(added) == 0 {
+		return m
+	}
+	next := &Matcher{}
+	if m != nil {
+		next.rules = append(next.rules, m.rules...)
+	}
+	next.rules = append(next.rules, added...)
+	return next
+}
+
+// withDir loads every ignore file found in the directory absDir.
+func (m *Matcher) withDir(absDir, base string) *Matcher {
+	for _, name := range ignoreFileNames {
+		m = m.withFile(filepath.Join(absDir, name), base)
+	}
+	return m
+}
+
+// compileIgnorePattern turns one line of an ignore file into a rule.
+// It returns ok=false for blank lines and comments.
+func compileIgnorePattern(line, base string) (ignoreRule, bool, error) {
+	rule := ignoreRule{base: base}
+
+	line = strings.TrimRight(line, "\r")
+	// Trailing spaces are ignored unless escaped with a backslash.
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false, nil
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false, nil
+	}
+
+	// A slash anywhere but the end anchors the pattern to the base directory;
+	// otherwise it matches a name at any depth.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored && !strings.HasPrefix(line, "**") {
+		re.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch c {
+		case '*':
+			if i+1 < len(line) && line[i+1] == '*' {
+				atStart := i == 0 || line[i-1] == '/'
+				atEnd := i+2 == len(line) || line[i+2] == '/'
+				if atStart && atEnd {
+					if i+2 == len(line) {
+						re.WriteString(".*") // Trailing "**": everything inside
+					} else {
+						re.WriteString("(?:.*/)?") // "**/": zero or more directories
+						i++                        // Skip the slash as well
+					}
+					i++
+					continue
+				}
+			}
+			re.WriteString("[^/]*")
+		case '?':
+			re.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end < 0 {
+				re.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.ReplaceAll(class, "\\", "\\\\") + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(line) {
+				i++
+				re.WriteString(regexp.QuoteMeta(string(line[i])))
+			}
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+
+	compiled, err := regexp.Compile(re.String())
+	if err != nil {
+		return rule, false, err
+	}
+	rule.re = compiled
+	return rule, true, nil
+}
+
+// relSlash joins a slash-separated relative directory and a name.
+func relSlash(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return path.Join(dir, name)
+}
@@ -0,0 +1,77 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{"name at any depth", []string{"*.log"}, "a/b/c.log", false, true},
+		{"name at root", []string{"*.log"}, "c.log", false, true},
+		{"no match", []string{"*.log"}, "a/c.txt", false, false},
+		{"star stays in one segment", []string{"a/*.md"}, "a/b/c.md", false, false},
+		{"anchored by inner slash", []string{"docs/*.md"}, "docs/a.md", false, true},
+		{"anchored not nested", []string{"docs/*.md"}, "sub/docs/a.md", false, false},
+		{"leading slash anchors", []string{"/build"}, "build", true, true},
+		{"leading slash not nested", []string{"/build"}, "src/build", true, false},
+		{"leading double star", []string{"**/tmp"}, "a/b/tmp", true, true},
+		{"leading double star at root", []string{"**/tmp"}, "tmp", true, true},
+		{"inner double star", []string{"a/**/z"}, "a/b/c/z", false, true},
+		{"inner double star, no dirs", []string{"a/**/z"}, "a/z", false, true},
+		{"trailing double star", []string{"out/**"}, "out/x/y", false, true},
+		{"dir-only matches dir", []string{"cache/"}, "x/cache", true, true},
+		{"dir-only skips file", []string{"cache/"}, "x/cache", false, false},
+		{"negation re-includes", []string{"*.log", "!keep.log"}, "keep.log", false, false},
+		{"negation keeps others", []string{"*.log", "!keep.log"}, "drop.log", false, true},
+		{"last rule wins", []string{"!keep.log", "*.log"}, "keep.log", false, true},
+		{"question mark", []string{"file?.go"}, "file1.go", false, true},
+		{"character class", []string{"[ab].txt"}, "b.txt", false, true},
+		{"negated class", []string{"[!ab].txt"}, "b.txt", false, false},
+		{"escaped bang", []string{`\!important`}, "!important", false, true},
+		{"comment ignored", []string{"# *.go"}, "main.go", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMatcher(tt.patterns)
+			if err != nil {
+				t.Fatalf("NewMatcher(%q): %v", tt.patterns, err)
+			}
+			if got := m.Match(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("Match(%q, %v) with %q = %v, want %v", tt.path, tt.isDir, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcherWithFileIsRelativeToItsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, ".gitignore")
+	if err := os.WriteFile(file, []byte("/gen\n*.tmp\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m := (*Matcher)(nil).withFile(file, "sub")
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"sub/gen", true},
+		{"sub/x/gen", false}, // Anchored to sub
+		{"gen", false},       // Outside sub
+		{"sub/x/a.tmp", true},
+		{"a.tmp", false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, true); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
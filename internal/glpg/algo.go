@@ -0,0 +1,482 @@
+package glpg
+
+import (
+	"errors"
+	"sort"
+)
+
+// Traversals follow outgoing edges in adjacency order, which is the order
+// the ingestors add children in. Functions returning sets of nodes return
+// them sorted by ID so results are stable.
+
+// ErrCycle is returned by TopologicalSort for graphs that have a cycle.
+var ErrCycle = errors.New("graph has a cycle")
+
+// Roots returns the IDs of the nodes with no incoming edges, sorted.
+func (g *GLPG) Roots() []string {
+	var roots []string
+	for id := range g.Nodes {
+		if len(g.IncomingEdges[id]) == 0 {
+			roots = append(roots, id)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// BFS visits the nodes reachable from start breadth-first, each once, with
+// its distance from start. It stops when visit returns false.
+func (g *GLPG) BFS(start string, visit func(node *GLPGNode, depth int) bool) {
+	if g.GetNode(start) == nil {
+		return
+	}
+	seen := map[string]bool{start: true}
+	queue := []string{start}
+	depths := map[string]int{start: 0}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if !visit(g.Nodes[id], depths[id]) {
+			return
+		}
+		for _, edge := range g.OutgoingEdges[id] {
+			if !seen[edge.TargetID] && g.GetNode(edge.TargetID) != nil {
+				seen[edge.TargetID] = true
+				depths[edge.TargetID] = depths[id] + 1
+				queue = append(queue, edge.TargetID)
+			}
+		}
+	}
+}
+
+// DFS visits the nodes reachable from start depth-first in pre-order, each
+// once, with its depth in the DFS tree. It stops when visit returns false.
+func (g *GLPG) DFS(start string, visit func(node *GLPGNode, depth int) bool) {
+	seen := make(map[string]bool)
+	var walk func(id string, depth int) bool
+	walk = func(id string, depth int) bool {
+		seen[id] = true
+		if !visit(g.Nodes[id], depth) {
+			return false
+		}
+		for _, edge := range g.OutgoingEdges[id] {
+			if !seen[edge.TargetID] && g.GetNode(edge.TargetID) != nil {
+				if !walk(edge.TargetID, depth+1) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	if g.GetNode(start) != nil {
+		walk(start, 0)
+	}
+}
+
+// BreadthFirstOrder returns every node ID, breadth-first from each root in
+// turn, followed by the nodes no root reaches (those on cycles), sorted.
+func (g *GLPG) BreadthFirstOrder() []string {
+	order := make([]string, 0, len(g.Nodes))
+	seen := make(map[string]bool, len(g.Nodes))
+	add := func(start string) {
+		if seen[start] {
+			return
+		}
+		g.BFS(start, func(node *GLPGNode, _ int) bool {
+			if !seen[node.ID] {
+				seen[node.ID] = true
+				order = append(order, node.ID)
+			}
+			return true
+		})
+	}
+	for _, root := range g.Roots() {
+		add(root)
+	}
+	for _, node := range g.sortedNodes() {
+		add(node.ID)
+	}
+	return order
+}
+
+// TopologicalSort orders the nodes so every edge points from an earlier node
+// to a later one. Ties are broken by ID. It returns ErrCycle if there is no
+// such order.
+func (g *GLPG) TopologicalSort() ([]string, error) {
+	inDegree := make(map[string]int, len(g.Nodes))
+	for _, edge := range g.Edges {
+		if g.GetNode(edge.SourceID) != nil && g.GetNode(edge.TargetID) != nil {
+			inDegree[edge.TargetID]++
+		}
+	}
+	var ready []string
+	for id := range g.Nodes {
+		if inDegree[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+	sort.Strings(ready)
+	order := make([]string, 0, len(g.Nodes))
+	for len(ready) > 0 {
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, id)
+		var next []string
+		for _, edge := range g.OutgoingEdges[id] {
+			if g.GetNode(edge.TargetID) == nil {
+				continue
+			}
+			inDegree[edge.TargetID]--
+			if inDegree[edge.TargetID] == 0 {
+				next = append(next, edge.TargetID)
+			}
+		}
+		ready = append(ready, next...)
+		sort.Strings(ready)
+	}
+	if len(order) != len(g.Nodes) {
+		return order, ErrCycle
+	}
+	return order, nil
+}
+
+// FindCycle returns the node IDs of one cycle, starting and ending with the
+// same node, or nil if the graph is acyclic.
+func (g *GLPG) FindCycle() []string {
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := make(map[string]int, len(g.Nodes))
+	var stack []string
+	var cycle []string
+	var walk func(id string) bool
+	walk = func(id string) bool {
+		state[id] = active
+		stack = append(stack, id)
+		for _, edge := range g.OutgoingEdges[id] {
+			target := edge.TargetID
+			if g.GetNode(target) == nil {
+				continue
+			}
+			switch state[target] {
+			case active:
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == target {
+						cycle = append(append([]string(nil), stack[i:]...), target)
+						return true
+					}
+				}
+			case unvisited:
+				if walk(target) {
+					return true
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = done
+		return false
+	}
+	for _, node := range g.sortedNodes() {
+		if state[node.ID] == unvisited && walk(node.ID) {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// StronglyConnectedComponents returns the graph's strongly connected
+// components (Tarjan's algorithm). Each component is sorted, and components
+// are ordered by size, largest first, then by their first ID.
+func (g *GLPG) StronglyConnectedComponents() [][]string {
+	index := make(map[string]int, len(g.Nodes))
+	low := make(map[string]int, len(g.Nodes))
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+	next := 0
+
+	var connect func(id string)
+	connect = func(id string) {
+		index[id], low[id] = next, next
+		next++
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, edge := range g.OutgoingEdges[id] {
+			target := edge.TargetID
+			if g.GetNode(target) == nil {
+				continue
+			}
+			if _, visited := index[target]; !visited {
+				connect(target)
+				low[id] = min(low[id], low[target])
+			} else if onStack[target] {
+				low[id] = min(low[id], index[target])
+			}
+		}
+		if low[id] != index[id] {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == id {
+				break
+			}
+		}
+		sort.Strings(component)
+		components = append(components, component)
+	}
+	for _, node := range g.sortedNodes() {
+		if _, visited := index[node.ID]; !visited {
+			connect(node.ID)
+		}
+	}
+	sort.Slice(components, func(i, j int) bool {
+		if len(components[i]) != len(components[j]) {
+			return len(components[i]) > len(components[j])
+		}
+		return components[i][0] < components[j][0]
+	})
+	return components
+}
+
+// ShortestPath returns the node IDs on a shortest path from one node to
+// another along outgoing edges, including both ends, or nil if there is none.
+func (g *GLPG) ShortestPath(from, to string) []string {
+	if g.GetNode(from) == nil || g.GetNode(to) == nil {
+		return nil
+	}
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == to {
+			var path []string
+			for at := to; at != ""; at = prev[at] {
+				path = append([]string{at}, path...)
+				if at == from {
+					break
+				}
+			}
+			return path
+		}
+		for _, edge := range g.OutgoingEdges[id] {
+			if _, seen := prev[edge.TargetID]; !seen && g.GetNode(edge.TargetID) != nil {
+				prev[edge.TargetID] = id
+				queue = append(queue, edge.TargetID)
+			}
+		}
+	}
+	return nil
+}
+
+// Induced returns a copy of the nodes with the given IDs and the edges
+// between them. Unknown IDs are ignored.
+func (g *GLPG) Induced(ids []string) (*GLPG, error) {
+	sub := NewGLPG()
+	for _, id := range ids {
+		if node := g.GetNode(id); node != nil {
+			sub.AddNode(&GLPGNode{
+				ID:         node.ID,
+				Labels:     append([]string(nil), node.Labels...),
+				Properties: copyProperties(node.Properties),
+			})
+		}
+	}
+	for _, id := range ids {
+		if sub.GetNode(id) == nil {
+			continue
+		}
+		for _, edge := range g.OutgoingEdges[id] {
+			if sub.GetNode(edge.TargetID) == nil || sub.GetEdge(edge.ID) != nil {
+				continue
+			}
+			err := sub.AddEdge(&GLPGEdge{
+				ID:         edge.ID,
+				SourceID:   edge.SourceID,
+				TargetID:   edge.TargetID,
+				Label:      edge.Label,
+				Properties: copyProperties(edge.Properties),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sub, nil
+}
+
+// Subgraph returns the part of the graph reachable from root in at most
+// depth hops (any depth if depth is negative).
+func (g *GLPG) Subgraph(root string, depth int) (*GLPG, error) {
+	var ids []string
+	g.BFS(root, func(node *GLPGNode, d int) bool {
+		if depth >= 0 && d > depth {
+			return false
+		}
+		ids = append(ids, node.ID)
+		return true
+	})
+	return g.Induced(ids)
+}
+
+// NodeMetrics holds the degree and centrality measures of one node.
+type NodeMetrics struct {
+	InDegree    int
+	OutDegree   int
+	Degree      float64 // (in + out) / (n - 1)
+	Betweenness float64 // normalized to [0, 1]
+	Closeness   float64 // reachable / total distance, scaled by reach
+}
+
+// Metrics computes degree, betweenness (Brandes' algorithm) and closeness
+// centrality for every node, treating edges as directed and unweighted.
+func (g *GLPG) Metrics() map[string]*NodeMetrics {
+	nodes := g.sortedNodes()
+	n := len(nodes)
+	metrics := make(map[string]*NodeMetrics, n)
+	for _, node := range nodes {
+		metrics[node.ID] = &NodeMetrics{}
+	}
+	for _, edge := range g.Edges {
+		if metrics[edge.SourceID] != nil && metrics[edge.TargetID] != nil {
+			metrics[edge.SourceID].OutDegree++
+			metrics[edge.TargetID].InDegree++
+		}
+	}
+	if n < 2 {
+		return metrics
+	}
+	for _, m := range metrics {
+		m.Degree = float64(m.InDegree+m.OutDegree) / float64(n-1)
+	}
+
+	betweenness := make(map[string]float64, n)
+	for _, source := range nodes {
+		// Single-source shortest paths, counting paths through each node
+		var order []string
+		preds := make(map[string][]string)
+		sigma := map[string]float64{source.ID: 1}
+		dist := map[string]int{source.ID: 0}
+		queue := []string{source.ID}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			order = append(order, v)
+			for _, edge := range g.OutgoingEdges[v] {
+				w := edge.TargetID
+				if metrics[w] == nil {
+					continue
+				}
+				if _, seen := dist[w]; !seen {
+					dist[w] = dist[v] + 1
+					queue = append(queue, w)
+				}
+				if dist[w] == dist[v]+1 {
+					sigma[w] += sigma[v]
+					preds[w] = append(preds[w], v)
+				}
+			}
+		}
+
+		total := 0
+		for _, d := range dist {
+			total += d
+		}
+		if reached := len(dist) - 1; total > 0 {
+			metrics[source.ID].Closeness = float64(reached) / float64(total) * float64(reached) / float64(n-1)
+		}
+
+		delta := make(map[string]float64, len(order))
+		for i := len(order) - 1; i >= 0; i-- {
+			w := order[i]
+			for _, v := range preds[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			if w != source.ID {
+				betweenness[w] += delta[w]
+			}
+		}
+	}
+	if n > 2 {
+		scale := 1 / float64((n-1)*(n-2))
+		for id, b := range betweenness {
+			metrics[id].Betweenness = b * scale
+		}
+	}
+	return metrics
+}
+
+// Forest is a spanning forest of a graph, used to print it as nested
+// elements. Each node hangs from the source of its first incoming edge (the
+// edge its ingestor created it with, such as CONTAINS or DECLARES); the
+// other edges are cross-links.
+type Forest struct {
+	Roots    []string
+	Children map[string][]*GLPGEdge // Tree edges by parent ID, in adjacency order
+	Parent   map[string]*GLPGEdge   // Tree edge into each non-root node
+}
+
+// IsTreeEdge reports whether edge is one of the forest's tree edges.
+func (f *Forest) IsTreeEdge(edge *GLPGEdge) bool {
+	return f.Parent[edge.TargetID] == edge
+}
+
+// ContainmentForest builds the graph's Forest. Nodes without incoming
+// edges are roots, in ID order, followed by one node of each cycle that no
+// root reaches.
+func (g *GLPG) ContainmentForest() *Forest {
+	f := &Forest{
+		Children: make(map[string][]*GLPGEdge),
+		Parent:   make(map[string]*GLPGEdge),
+	}
+	parentEdge := func(id string) *GLPGEdge {
+		for _, edge := range g.IncomingEdges[id] {
+			if edge.SourceID != id && g.GetNode(edge.SourceID) != nil {
+				return edge
+			}
+		}
+		return nil
+	}
+	placed := make(map[string]bool, len(g.Nodes))
+	var place func(id string)
+	place = func(id string) {
+		placed[id] = true
+		for _, edge := range g.OutgoingEdges[id] {
+			target := edge.TargetID
+			if !placed[target] && g.GetNode(target) != nil && parentEdge(target) == edge {
+				f.Children[id] = append(f.Children[id], edge)
+				f.Parent[target] = edge
+				place(target)
+			}
+		}
+	}
+	for _, root := range g.Roots() {
+		f.Roots = append(f.Roots, root)
+		place(root)
+	}
+	for _, node := range g.sortedNodes() {
+		if placed[node.ID] {
+			continue
+		}
+		// Climb to the cycle the node hangs from and start there
+		id := node.ID
+		for seen := map[string]bool{}; !seen[id]; {
+			seen[id] = true
+			edge := parentEdge(id)
+			if edge == nil {
+				break
+			}
+			id = edge.SourceID
+		}
+		f.Roots = append(f.Roots, id)
+		place(id)
+	}
+	return f
+}
@@ -0,0 +1,248 @@
+package glpg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// edgeGraph builds a graph from "a>b" edge specs, adding nodes as they are
+// first named. Bare names add isolated nodes.
+func edgeGraph(t *testing.T, specs ...string) *GLPG {
+	t.Helper()
+	g := NewGLPG()
+	addNode := func(id string) {
+		if g.GetNode(id) == nil {
+			g.AddNode(&GLPGNode{ID: id, Labels: []string{"Node"}, Properties: GLPGProperty{"Name": id}})
+		}
+	}
+	for _, spec := range specs {
+		from, to, isEdge := strings.Cut(spec, ">")
+		addNode(from)
+		if !isEdge {
+			continue
+		}
+		addNode(to)
+		if g.Connect(from, "LINK", to) == nil {
+			t.Fatalf("connecting %s", spec)
+		}
+	}
+	return g
+}
+
+// diamond is a -> b, c -> d -> e: two shortest paths from a to d and e.
+var diamond = []string{"a>b", "a>c", "b>d", "c>d", "d>e"}
+
+func TestTraversals(t *testing.T) {
+	g := edgeGraph(t, diamond...)
+	var visits []string
+	g.BFS("a", func(node *GLPGNode, depth int) bool {
+		visits = append(visits, fmt.Sprintf("%s%d", node.ID, depth))
+		return true
+	})
+	if got, want := strings.Join(visits, " "), "a0 b1 c1 d2 e3"; got != want {
+		t.Errorf("BFS = %s, want %s", got, want)
+	}
+
+	visits = nil
+	g.DFS("a", func(node *GLPGNode, depth int) bool {
+		visits = append(visits, fmt.Sprintf("%s%d", node.ID, depth))
+		return node.ID != "e"
+	})
+	if got, want := strings.Join(visits, " "), "a0 b1 d2 e3"; got != want {
+		t.Errorf("DFS stopped at e = %s, want %s", got, want)
+	}
+
+	g = edgeGraph(t, "r>s", "x>y", "y>x", "y>s")
+	if got, want := strings.Join(g.BreadthFirstOrder(), " "), "r s x y"; got != want {
+		t.Errorf("BreadthFirstOrder = %s, want %s", got, want)
+	}
+}
+
+func TestTopologicalSort(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges []string
+		want  string
+		cycle bool
+	}{
+		{"dag", []string{"a>c", "a>b", "b>d", "c>d", "e"}, "a b c d e", false},
+		{"ties by ID", []string{"z", "y", "x>w"}, "x w y z", false},
+		{"cycle", []string{"s>a", "a>b", "b>c", "c>a", "c>d"}, "s", true},
+		{"self-loop", []string{"a>a", "b"}, "b", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := edgeGraph(t, tt.edges...).TopologicalSort()
+			if got := strings.Join(order, " "); got != tt.want {
+				t.Errorf("TopologicalSort = %s, want %s", got, tt.want)
+			}
+			if got := errors.Is(err, ErrCycle); got != tt.cycle {
+				t.Errorf("TopologicalSort error = %v, want ErrCycle: %v", err, tt.cycle)
+			}
+		})
+	}
+}
+
+func TestFindCycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges []string
+		want  string
+	}{
+		{"dag", diamond, ""},
+		{"cycle", []string{"s>a", "a>b", "b>c", "c>a", "c>d"}, "a b c a"},
+		{"self-loop", []string{"a>b", "b>b"}, "b b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(edgeGraph(t, tt.edges...).FindCycle(), " "); got != tt.want {
+				t.Errorf("FindCycle = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStronglyConnectedComponents(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges []string
+		want  string
+	}{
+		{"dag", []string{"a>b", "b>c"}, "[a] [b] [c]"},
+		{"two components", []string{"a>b", "b>a", "b>c", "c>d", "d>e", "e>c", "f"}, "[c d e] [a b] [f]"},
+		{"self-loop", []string{"a>a", "a>b"}, "[a] [b]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var components []string
+			for _, c := range edgeGraph(t, tt.edges...).StronglyConnectedComponents() {
+				components = append(components, fmt.Sprint(c))
+			}
+			if got := strings.Join(components, " "); got != tt.want {
+				t.Errorf("StronglyConnectedComponents = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortestPath(t *testing.T) {
+	g := edgeGraph(t, append(diamond, "a>e", "f")...)
+	tests := []struct {
+		from, to string
+		want     string
+	}{
+		{"a", "e", "a e"},
+		{"b", "e", "b d e"},
+		{"a", "d", "a b d"}, // the first of two shortest paths, in adjacency order
+		{"c", "c", "c"},
+		{"e", "a", ""}, // edges are directed
+		{"a", "f", ""}, // unreachable
+		{"a", "missing", ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(g.ShortestPath(tt.from, tt.to), " "); got != tt.want {
+			t.Errorf("ShortestPath(%s, %s) = %q, want %q", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	type want struct {
+		in, out                        int
+		degree, betweenness, closeness float64
+	}
+	tests := []struct {
+		name  string
+		edges []string
+		want  map[string]want
+	}{
+		// Pairs through b: (a, c); n = 3 scales betweenness by 1/2
+		{"chain", []string{"a>b", "b>c"}, map[string]want{
+			"a": {0, 1, 0.5, 0, 2.0 / 3},
+			"b": {1, 1, 1, 0.5, 0.5},
+			"c": {1, 0, 0.5, 0, 0},
+		}},
+		// a reaches d and e over two paths, so b and c each carry half of
+		// (a, d) and (a, e); d carries (a, e), (b, e) and (c, e). n = 5
+		// scales betweenness by 1/12. Closeness: a reaches 4 nodes at total
+		// distance 7, b and c 2 at 3, d 1 at 1
+		{"diamond", diamond, map[string]want{
+			"a": {0, 2, 0.5, 0, 4.0 / 7},
+			"b": {1, 1, 0.5, 1.0 / 12, 1.0 / 3},
+			"c": {1, 1, 0.5, 1.0 / 12, 1.0 / 3},
+			"d": {2, 1, 0.75, 3.0 / 12, 0.25},
+			"e": {1, 0, 0.25, 0, 0},
+		}},
+		{"single node", []string{"a"}, map[string]want{"a": {}}},
+	}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := edgeGraph(t, tt.edges...).Metrics()
+			for id, w := range tt.want {
+				m := metrics[id]
+				if m.InDegree != w.in || m.OutDegree != w.out || !near(m.Degree, w.degree) ||
+					!near(m.Betweenness, w.betweenness) || !near(m.Closeness, w.closeness) {
+					t.Errorf("%s: got %+v, want %+v", id, *m, w)
+				}
+			}
+		})
+	}
+}
+
+func TestContainmentForest(t *testing.T) {
+	// c has two parents; x and y form a cycle no root reaches
+	g := edgeGraph(t, "r>a", "r>b", "a>c", "b>c", "x>y", "y>x", "y>c")
+	f := g.ContainmentForest()
+	if got, want := strings.Join(f.Roots, " "), "r x"; got != want {
+		t.Errorf("roots = %s, want %s", got, want)
+	}
+	children := func(id string) string {
+		var ids []string
+		for _, edge := range f.Children[id] {
+			ids = append(ids, edge.TargetID)
+		}
+		return strings.Join(ids, " ")
+	}
+	for id, want := range map[string]string{"r": "a b", "a": "c", "b": "", "x": "y", "y": ""} {
+		if got := children(id); got != want {
+			t.Errorf("children of %s = %q, want %q", id, got, want)
+		}
+	}
+	for _, edge := range g.GetIncomingEdges("c") {
+		if tree := f.IsTreeEdge(edge); tree != (edge.SourceID == "a") {
+			t.Errorf("%s->c tree edge: %v", edge.SourceID, tree)
+		}
+	}
+}
+
+// TestCloneKeepsEdgeOrder checks that algorithms following the adjacency
+// lists see the same order on a clone: c's first incoming edge makes its
+// tree edge, so the forest would change if Clone reordered them.
+func TestCloneKeepsEdgeOrder(t *testing.T) {
+	g := edgeGraph(t, "r>a", "r>b", "b>c", "a>c")
+	for i := 0; i < 20; i++ {
+		clone := g.Clone()
+		for _, id := range []string{"a", "b", "c", "r"} {
+			var got, want []string
+			for _, edge := range clone.GetIncomingEdges(id) {
+				got = append(got, edge.ID)
+			}
+			for _, edge := range g.GetIncomingEdges(id) {
+				want = append(want, edge.ID)
+			}
+			if strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Fatalf("incoming edges of %s in the clone = %v, want %v", id, got, want)
+			}
+		}
+		if errs := clone.CheckIntegrity(); len(errs) != 0 {
+			t.Fatalf("clone is broken: %v", errs)
+		}
+		if children := clone.ContainmentForest().Children["b"]; len(children) != 1 {
+			t.Fatalf("c is not under b, its first parent, in the clone's forest")
+		}
+	}
+}
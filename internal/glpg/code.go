@@ -0,0 +1,218 @@
+package glpg
+
+import (
+	"fmt"
+	"strings"
+
+	"lazybox/internal/ir"
+)
+
+// CodeInfoToGLPG converts an ir.CodeInfo into GLPG nodes and edges.
+// The file node DECLARES its functions, types, consts and vars and IMPORTS
+// its imports; methods are linked to their receiver type with METHOD_OF.
+func CodeInfoToGLPG(ci *ir.CodeInfo, g *GLPG) error {
+	if ci == nil {
+		return nil
+	}
+
+	fileID := fmt.Sprintf("CodeInfo_%s", sanitizeIDPart(ci.Path))
+	fileNode := &GLPGNode{
+		ID:     fileID,
+		Labels: []string{"CodeInfo", ci.Language},
+		Properties: GLPGProperty{
+			"Name":     ci.Name,
+			"Path":     ci.Path,
+			"Language": ci.Language,
+			"Package":  ci.Package,
+		},
+	}
+	if ci.Doc != "" {
+		fileNode.Properties["Doc"] = ci.Doc
+	}
+	if ci.Error != "" {
+		fileNode.Properties["Error"] = ci.Error
+	}
+	g.AddNode(fileNode)
+
+	for _, imp := range ci.Imports {
+		props := GLPGProperty{"Path": imp.Path}
+		if imp.Alias != "" {
+			props["Alias"] = imp.Alias
+		}
+		addPositionProperties(props, imp.Position)
+		node := &GLPGNode{
+			ID:         fmt.Sprintf("ImportInfo_%s", sanitizeIDPart(imp.Path)),
+			Labels:     []string{"ImportInfo"},
+			Properties: props,
+		}
+		g.AddUniqueNode(node)
+		g.Connect(fileID, "IMPORTS", node.ID)
+	}
+
+	declsToGLPG(g, fileID, "DECLARES", ci.Package, ci.Types, ci.Funcs, ci.Consts, ci.Vars)
+	return nil
+}
+
+// APIInfoToGLPG converts an ir.APIInfo into a package node that EXPORTS its
+// declarations, with the same METHOD_OF and EMBEDS links as CodeInfoToGLPG.
+func APIInfoToGLPG(api *ir.APIInfo, g *GLPG) error {
+	if api == nil {
+		return nil
+	}
+
+	pkgID := fmt.Sprintf("APIInfo_%s", sanitizeIDPart(api.Path))
+	pkgNode := &GLPGNode{
+		ID:     pkgID,
+		Labels: []string{"APIInfo", api.Language},
+		Properties: GLPGProperty{
+			"Name":     api.Name,
+			"Path":     api.Path,
+			"Language": api.Language,
+			"Files":    listValue(api.Files),
+		},
+	}
+	if api.Doc != "" {
+		pkgNode.Properties["Doc"] = api.Doc
+	}
+	g.AddNode(pkgNode)
+
+	declsToGLPG(g, pkgID, "EXPORTS", api.Name, api.Types, api.Funcs, api.Consts, api.Vars)
+	return nil
+}
+
+// declsToGLPG adds declaration nodes linked from parentID with edgeLabel.
+// Types are added first so methods can be linked to them with METHOD_OF
+// and embedded types declared alongside them with EMBEDS. Declarations
+// that share a name (init functions, blank identifiers, types declared per
+// build constraint) get IDs suffixed with a hash of their properties, which
+// include their file and position, instead of replacing each other.
+func declsToGLPG(g *GLPG, parentID, edgeLabel, pkg string, types []*ir.TypeInfo, funcs []*ir.FuncInfo, consts, vars []*ir.ValueInfo) {
+	typeIDs := make(map[string]string, len(types))
+	for _, ti := range types {
+		typeID := fmt.Sprintf("TypeInfo_%s.%s", pkg, ti.Name)
+		props := GLPGProperty{
+			"Name":       ti.Name,
+			"Kind":       ti.Kind,
+			"Definition": ti.Definition,
+			"Exported":   ti.Exported,
+		}
+		if ti.Doc != "" {
+			props["Doc"] = ti.Doc
+		}
+		if len(ti.Embeds) > 0 {
+			props["Embeds"] = listValue(ti.Embeds)
+		}
+		if ti.File != "" {
+			props["File"] = ti.File
+		}
+		addPositionProperties(props, ti.Position)
+		typeID = g.AddUniqueNode(&GLPGNode{ID: typeID, Labels: []string{"TypeInfo", ti.Kind}, Properties: props})
+		if _, ok := typeIDs[ti.Name]; !ok {
+			typeIDs[ti.Name] = typeID
+		}
+		g.Connect(parentID, edgeLabel, typeID)
+
+		for _, f := range ti.Fields {
+			name := f.Name
+			if f.Embedded {
+				name = f.Type
+			}
+			fieldProps := GLPGProperty{"Type": f.Type, "Embedded": f.Embedded}
+			if f.Name != "" {
+				fieldProps["Name"] = f.Name
+			}
+			if f.Tag != "" {
+				fieldProps["Tag"] = f.Tag
+			}
+			if f.Doc != "" {
+				fieldProps["Doc"] = f.Doc
+			}
+			fieldID := fmt.Sprintf("FieldInfo_%s.%s.%s", pkg, ti.Name, sanitizeIDPart(name))
+			fieldID = g.AddUniqueNode(&GLPGNode{ID: fieldID, Labels: []string{"FieldInfo"}, Properties: fieldProps})
+			g.Connect(typeID, "HAS_FIELD", fieldID)
+		}
+	}
+
+	for _, ti := range types {
+		for _, embedded := range ti.Embeds {
+			if embeddedID, ok := typeIDs[strings.TrimPrefix(embedded, "*")]; ok {
+				g.Connect(typeIDs[ti.Name], "EMBEDS", embeddedID)
+			}
+		}
+	}
+
+	for _, fi := range funcs {
+		funcID := fmt.Sprintf("FuncInfo_%s.%s", pkg, fi.Name)
+		label := "Function"
+		if fi.Receiver != "" {
+			funcID = fmt.Sprintf("FuncInfo_%s.%s.%s", pkg, fi.Receiver, fi.Name)
+			label = "Method"
+		}
+		props := GLPGProperty{
+			"Name":      fi.Name,
+			"Signature": fi.Signature,
+			"Exported":  fi.Exported,
+		}
+		if fi.Receiver != "" {
+			props["Receiver"] = fi.Receiver
+		}
+		if fi.Doc != "" {
+			props["Doc"] = fi.Doc
+		}
+		if fi.File != "" {
+			props["File"] = fi.File
+		}
+		addPositionProperties(props, fi.Position)
+		funcID = g.AddUniqueNode(&GLPGNode{ID: funcID, Labels: []string{"FuncInfo", label}, Properties: props})
+		g.Connect(parentID, edgeLabel, funcID)
+		if typeID, ok := typeIDs[fi.Receiver]; ok {
+			g.Connect(funcID, "METHOD_OF", typeID)
+		}
+	}
+
+	for _, values := range [][]*ir.ValueInfo{consts, vars} {
+		for _, vi := range values {
+			props := GLPGProperty{
+				"Name":     vi.Name,
+				"Kind":     vi.Kind,
+				"Exported": vi.Exported,
+			}
+			if vi.Type != "" {
+				props["Type"] = vi.Type
+			}
+			if vi.Value != "" {
+				props["Value"] = vi.Value
+			}
+			if vi.Doc != "" {
+				props["Doc"] = vi.Doc
+			}
+			if vi.File != "" {
+				props["File"] = vi.File
+			}
+			addPositionProperties(props, vi.Position)
+			valueID := fmt.Sprintf("ValueInfo_%s.%s", pkg, vi.Name)
+			valueID = g.AddUniqueNode(&GLPGNode{ID: valueID, Labels: []string{"ValueInfo", vi.Kind}, Properties: props})
+			g.Connect(parentID, edgeLabel, valueID)
+		}
+	}
+}
+
+// listValue returns values as the []interface{} the generic ingestor and
+// the loaders produce for lists, so diff does not report a graph and its
+// reloaded dump as different.
+func listValue(values []string) []interface{} {
+	list := make([]interface{}, len(values))
+	for i, v := range values {
+		list[i] = v
+	}
+	return list
+}
+
+func addPositionProperties(props GLPGProperty, pos ir.Position) {
+	props["Line"] = pos.Line
+	props["Column"] = pos.Column
+	if pos.EndLine != 0 {
+		props["EndLine"] = pos.EndLine
+		props["EndColumn"] = pos.EndColumn
+	}
+}
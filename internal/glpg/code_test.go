@@ -0,0 +1,149 @@
+package glpg
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"lazybox/internal/api"
+	"lazybox/internal/code"
+)
+
+const fixture = "../code/testdata/demo"
+
+// namedEdges lists the graph's edges with the given labels as sorted
+// source-LABEL->target strings, naming nodes by their Name property.
+func namedEdges(g *GLPG, labels ...string) string {
+	var edges []string
+	for _, edge := range g.Edges {
+		for _, label := range labels {
+			if edge.Label == label {
+				edges = append(edges, g.Nodes[edge.SourceID].Properties["Name"].(string)+"-"+label+"->"+g.Nodes[edge.TargetID].Properties["Name"].(string))
+			}
+		}
+	}
+	sort.Strings(edges)
+	return strings.Join(edges, " ")
+}
+
+// nodesNamed returns the IDs of the nodes with label and name, sorted.
+func nodesNamed(g *GLPG, label, name string) []string {
+	var ids []string
+	for id, node := range g.Nodes {
+		if node.Properties["Name"] == name && hasLabel(node.Labels, label) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// assertReloads checks that a dump of g loads back without diff changes.
+func assertReloads(t *testing.T, g *GLPG) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Encode(&buf, g, "jgf", false); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := Decode(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := Diff(g, reloaded, nil); !d.Empty() {
+		t.Errorf("reloaded dump differs from the graph: %+v", d)
+	}
+}
+
+func TestCodeInfoToGLPG(t *testing.T) {
+	ci, err := code.Extract(fixture + "/demo.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGLPG()
+	if err := CodeInfoToGLPG(ci, g); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := namedEdges(g, "METHOD_OF", "EMBEDS"),
+		"Size-METHOD_OF->Widget Widget-EMBEDS->Base Widget-EMBEDS->Named hidden-METHOD_OF->Widget"; got != want {
+		t.Errorf("METHOD_OF and EMBEDS edges = %q, want %q", got, want)
+	}
+	for id, alias := range map[string]interface{}{"ImportInfo_fmt": nil, "ImportInfo_strings": "str"} {
+		imp := g.GetNode(id)
+		if imp == nil || imp.Properties["Alias"] != alias {
+			t.Errorf("import %s = %v, want alias %v", id, imp, alias)
+		} else if in := g.GetIncomingEdges(id); len(in) != 1 || in[0].Label != "IMPORTS" {
+			t.Errorf("%s incoming edges = %v, want one IMPORTS", id, in)
+		}
+	}
+
+	// Both init functions are kept, each declared by the file
+	inits := nodesNamed(g, "FuncInfo", "init")
+	if len(inits) != 2 || inits[0] != "FuncInfo_demo.init" || !strings.HasPrefix(inits[1], "FuncInfo_demo.init_") {
+		t.Fatalf("init nodes = %v, want FuncInfo_demo.init and a suffixed one", inits)
+	}
+	for _, id := range inits {
+		if in := g.GetIncomingEdges(id); len(in) != 1 || in[0].Label != "DECLARES" {
+			t.Errorf("%s incoming edges = %v, want one DECLARES", id, in)
+		}
+	}
+
+	widget := g.GetNode("TypeInfo_demo.Widget")
+	if embeds := widget.Properties["Embeds"]; len(embeds.([]interface{})) != 2 {
+		t.Errorf("Widget Embeds = %#v, want a list of two", embeds)
+	}
+	if errs := g.CheckIntegrity(); len(errs) != 0 {
+		t.Errorf("code graph fails CheckIntegrity: %v", errs)
+	}
+	assertReloads(t, g)
+}
+
+func TestAPIInfoToGLPG(t *testing.T) {
+	info, err := api.Extract(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Add the Platform declared under the other build constraint, as an
+	// extractor honouring both would
+	other, err := code.Extract(fixture + "/platform_a.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ti := range other.Types {
+		ti.File = "platform_a.go"
+		info.Types = append(info.Types, ti)
+	}
+	info.Files = append(info.Files, "platform_a.go")
+
+	g := NewGLPG()
+	if err := APIInfoToGLPG(info, g); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := namedEdges(g, "METHOD_OF", "EMBEDS"),
+		"Size-METHOD_OF->Widget Widget-EMBEDS->Base Widget-EMBEDS->Named"; got != want {
+		t.Errorf("METHOD_OF and EMBEDS edges = %q, want %q", got, want)
+	}
+
+	platforms := nodesNamed(g, "TypeInfo", "Platform")
+	if len(platforms) != 2 {
+		t.Fatalf("Platform nodes = %v, want one per build constraint", platforms)
+	}
+	var files []string
+	for _, id := range platforms {
+		files = append(files, g.GetNode(id).Properties["File"].(string))
+		if in := g.GetIncomingEdges(id); len(in) != 1 || in[0].Label != "EXPORTS" {
+			t.Errorf("%s incoming edges = %v, want one EXPORTS", id, in)
+		}
+	}
+	sort.Strings(files)
+	if got := strings.Join(files, " "); got != "platform_a.go platform_b.go" {
+		t.Errorf("Platform files = %q", got)
+	}
+
+	pkg := g.GetNode("APIInfo_" + sanitizeIDPart(info.Path))
+	if files, ok := pkg.Properties["Files"].([]interface{}); !ok || len(files) != 3 {
+		t.Errorf("package Files = %#v, want a list of three", pkg.Properties["Files"])
+	}
+	assertReloads(t, g)
+}
@@ -0,0 +1,262 @@
+package glpg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Change kinds reported by Diff.
+const (
+	ChangeAdded     = "added"
+	ChangeRemoved   = "removed"
+	ChangeChanged   = "changed"
+	ChangeUnchanged = "unchanged"
+)
+
+// PropertyChange is a property whose value differs between two graphs. Old
+// or New is nil if the property is missing on that side.
+type PropertyChange struct {
+	Key string
+	Old interface{}
+	New interface{}
+}
+
+// NodeChange is a node present in both graphs with different labels or
+// properties.
+type NodeChange struct {
+	Old, New   *GLPGNode
+	Labels     bool // the label sets differ
+	Properties []PropertyChange
+}
+
+// EdgeChange is an edge present in both graphs with different properties.
+type EdgeChange struct {
+	Old, New   *GLPGEdge
+	Properties []PropertyChange
+}
+
+// GraphDiff is the structural difference between two graphs. Nodes are
+// matched by ID; edges by source, label and target, since edge IDs need not
+// be stable across dumps.
+type GraphDiff struct {
+	AddedNodes   []*GLPGNode
+	RemovedNodes []*GLPGNode
+	ChangedNodes []NodeChange
+	AddedEdges   []*GLPGEdge
+	RemovedEdges []*GLPGEdge
+	ChangedEdges []EdgeChange
+
+	a, b *GLPG // the compared graphs
+}
+
+// Empty reports whether the graphs were the same.
+func (d *GraphDiff) Empty() bool {
+	return len(d.AddedNodes)+len(d.RemovedNodes)+len(d.ChangedNodes)+
+		len(d.AddedEdges)+len(d.RemovedEdges)+len(d.ChangedEdges) == 0
+}
+
+// String summarizes the diff in one line.
+func (d *GraphDiff) String() string {
+	return fmt.Sprintf("nodes: +%d -%d ~%d, edges: +%d -%d ~%d",
+		len(d.AddedNodes), len(d.RemovedNodes), len(d.ChangedNodes),
+		len(d.AddedEdges), len(d.RemovedEdges), len(d.ChangedEdges))
+}
+
+// Diff compares graph a with graph b. Properties named in ignore (ModTime,
+// say) are left out of the comparison.
+func Diff(a, b *GLPG, ignore []string) *GraphDiff {
+	skip := make(map[string]bool, len(ignore))
+	for _, key := range ignore {
+		skip[key] = true
+	}
+	d := &GraphDiff{a: a, b: b}
+
+	for _, node := range a.sortedNodes() {
+		other := b.GetNode(node.ID)
+		if other == nil {
+			d.RemovedNodes = append(d.RemovedNodes, node)
+			continue
+		}
+		change := NodeChange{
+			Old:        node,
+			New:        other,
+			Labels:     !sameLabels(node.Labels, other.Labels),
+			Properties: diffProperties(node.Properties, other.Properties, skip),
+		}
+		if change.Labels || len(change.Properties) > 0 {
+			d.ChangedNodes = append(d.ChangedNodes, change)
+		}
+	}
+	for _, node := range b.sortedNodes() {
+		if a.GetNode(node.ID) == nil {
+			d.AddedNodes = append(d.AddedNodes, node)
+		}
+	}
+
+	// Pair edges with the same endpoints and label in order; the leftovers
+	// were added or removed
+	unmatched := make(map[string][]*GLPGEdge)
+	for _, edge := range b.sortedEdges() {
+		unmatched[edgeKey(edge)] = append(unmatched[edgeKey(edge)], edge)
+	}
+	for _, edge := range a.sortedEdges() {
+		key := edgeKey(edge)
+		candidates := unmatched[key]
+		if len(candidates) == 0 {
+			d.RemovedEdges = append(d.RemovedEdges, edge)
+			continue
+		}
+		other := candidates[0]
+		unmatched[key] = candidates[1:]
+		if changes := diffProperties(edge.Properties, other.Properties, skip); len(changes) > 0 {
+			d.ChangedEdges = append(d.ChangedEdges, EdgeChange{Old: edge, New: other, Properties: changes})
+		}
+	}
+	for _, edge := range b.sortedEdges() {
+		for _, candidate := range unmatched[edgeKey(edge)] {
+			if candidate == edge {
+				d.AddedEdges = append(d.AddedEdges, edge)
+			}
+		}
+	}
+	return d
+}
+
+func edgeKey(edge *GLPGEdge) string {
+	return edge.SourceID + "\x00" + edge.Label + "\x00" + edge.TargetID
+}
+
+func sameLabels(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return strings.Join(a, "\x00") == strings.Join(b, "\x00")
+}
+
+// diffProperties returns the properties that differ between before and
+// after, ordered by key.
+func diffProperties(before, after GLPGProperty, skip map[string]bool) []PropertyChange {
+	keys := make(map[string]bool, len(before)+len(after))
+	for k := range before {
+		keys[k] = true
+	}
+	for k := range after {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		if !skip[k] {
+			sorted = append(sorted, k)
+		}
+	}
+	sort.Strings(sorted)
+	var changes []PropertyChange
+	for _, k := range sorted {
+		o, inOld := before[k]
+		n, inNew := after[k]
+		if inOld && inNew && sameValue(o, n) {
+			continue
+		}
+		changes = append(changes, PropertyChange{Key: k, Old: o, New: n})
+	}
+	return changes
+}
+
+// DiffRootID is the ID of the summary node in the graph built by Graph.
+const DiffRootID = "Diff_summary"
+
+// Graph renders the diff as a GLPG so it can be printed in any mode. Every
+// added, removed or changed node appears with a Change property; changed
+// nodes carry only their changed properties, each as an Old/New pair. Edges
+// are kept between these nodes with their own Change property, and the
+// unchanged endpoints of changed edges are included (with Change set to
+// "unchanged" and no other properties) so every edge has both ends. A
+// Diff_summary node holds the counts and the names of the compared graphs.
+func (d *GraphDiff) Graph(oldName, newName string) (*GLPG, error) {
+	g := NewGLPG()
+	g.AddNode(&GLPGNode{
+		ID:     DiffRootID,
+		Labels: []string{"Diff"},
+		Properties: GLPGProperty{
+			"Old":          oldName,
+			"New":          newName,
+			"NodesAdded":   len(d.AddedNodes),
+			"NodesRemoved": len(d.RemovedNodes),
+			"NodesChanged": len(d.ChangedNodes),
+			"EdgesAdded":   len(d.AddedEdges),
+			"EdgesRemoved": len(d.RemovedEdges),
+			"EdgesChanged": len(d.ChangedEdges),
+		},
+	})
+
+	addNode := func(node *GLPGNode, change string, props GLPGProperty) {
+		props["Change"] = change
+		g.AddNode(&GLPGNode{ID: node.ID, Labels: append([]string(nil), node.Labels...), Properties: props})
+	}
+	for _, node := range d.AddedNodes {
+		addNode(node, ChangeAdded, copyProperties(node.Properties))
+	}
+	for _, node := range d.RemovedNodes {
+		addNode(node, ChangeRemoved, copyProperties(node.Properties))
+	}
+	for _, change := range d.ChangedNodes {
+		props := changedProperties(change.Properties)
+		if change.Labels {
+			props["Labels"] = map[string]interface{}{"Old": change.Old.Labels, "New": change.New.Labels}
+		}
+		addNode(change.New, ChangeChanged, props)
+	}
+
+	addEdge := func(edge *GLPGEdge, change string, props GLPGProperty) error {
+		for _, id := range []string{edge.SourceID, edge.TargetID} {
+			if g.GetNode(id) != nil {
+				continue
+			}
+			endpoint := d.b.GetNode(id)
+			if endpoint == nil {
+				endpoint = d.a.GetNode(id)
+			}
+			var labels []string
+			if endpoint != nil {
+				labels = append(labels, endpoint.Labels...)
+			}
+			g.AddNode(&GLPGNode{ID: id, Labels: labels, Properties: GLPGProperty{"Change": ChangeUnchanged}})
+		}
+		props["Change"] = change
+		return g.AddEdge(&GLPGEdge{
+			ID:         g.NewEdgeID(edge.SourceID, edge.Label, edge.TargetID),
+			SourceID:   edge.SourceID,
+			TargetID:   edge.TargetID,
+			Label:      edge.Label,
+			Properties: props,
+		})
+	}
+	for _, edge := range d.AddedEdges {
+		if err := addEdge(edge, ChangeAdded, copyProperties(edge.Properties)); err != nil {
+			return nil, err
+		}
+	}
+	for _, edge := range d.RemovedEdges {
+		if err := addEdge(edge, ChangeRemoved, copyProperties(edge.Properties)); err != nil {
+			return nil, err
+		}
+	}
+	for _, change := range d.ChangedEdges {
+		if err := addEdge(change.New, ChangeChanged, changedProperties(change.Properties)); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func changedProperties(changes []PropertyChange) GLPGProperty {
+	props := make(GLPGProperty, len(changes)+1)
+	for _, c := range changes {
+		props[c.Key] = map[string]interface{}{"Old": c.Old, "New": c.New}
+	}
+	return props
+}
@@ -0,0 +1,155 @@
+package glpg
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// diffGraphs returns two versions of a small tree: b changes main.go,
+// deletes util.go, adds doc.go and renumbers the edge to main.go.
+func diffGraphs(t *testing.T) (a, b *GLPG) {
+	t.Helper()
+	build := func(nodes []*GLPGNode, edges [][3]string, indexes map[string]int) *GLPG {
+		g := NewGLPG()
+		for _, node := range nodes {
+			g.AddNode(node)
+		}
+		for _, e := range edges {
+			edge := g.Connect(e[0], e[1], e[2])
+			if edge == nil {
+				t.Fatalf("connecting %v", e)
+			}
+			if i, ok := indexes[e[2]]; ok {
+				edge.Properties["INDEX"] = i
+			}
+		}
+		return g
+	}
+	a = build([]*GLPGNode{
+		{ID: "root", Labels: []string{"FileInfo", "directory"}, Properties: GLPGProperty{"Name": "demo"}},
+		{ID: "main", Labels: []string{"FileInfo", "file"}, Properties: GLPGProperty{"Name": "main.go", "Size": int64(100), "Lines": int64(9), "ModTime": "2024-01-01"}},
+		{ID: "util", Labels: []string{"FileInfo", "file"}, Properties: GLPGProperty{"Name": "util.go", "Size": int64(50)}},
+	}, [][3]string{
+		{"root", "CONTAINS", "main"},
+		{"root", "CONTAINS", "util"},
+		{"main", "IMPORTS", "util"},
+	}, map[string]int{"main": 0})
+	b = build([]*GLPGNode{
+		{ID: "root", Labels: []string{"directory", "FileInfo"}, Properties: GLPGProperty{"Name": "demo"}},
+		{ID: "main", Labels: []string{"FileInfo", "file", "generated"}, Properties: GLPGProperty{"Name": "main.go", "Size": 120.0, "Lines": 9.0, "ModTime": "2024-02-01"}},
+		{ID: "doc", Labels: []string{"FileInfo", "file"}, Properties: GLPGProperty{"Name": "doc.go", "Size": int64(10)}},
+	}, [][3]string{
+		{"root", "CONTAINS", "doc"},
+		{"root", "CONTAINS", "main"},
+	}, map[string]int{"main": 1})
+	return a, b
+}
+
+func nodeIDs(nodes []*GLPGNode) string {
+	ids := make([]string, len(nodes))
+	for i, node := range nodes {
+		ids[i] = node.ID
+	}
+	return strings.Join(ids, " ")
+}
+
+func edgeNames(edges []*GLPGEdge) string {
+	names := make([]string, len(edges))
+	for i, edge := range edges {
+		names[i] = edge.SourceID + "-" + edge.Label + "->" + edge.TargetID
+	}
+	return strings.Join(names, " ")
+}
+
+func TestDiff(t *testing.T) {
+	a, b := diffGraphs(t)
+	d := Diff(a, b, nil)
+
+	if got, want := d.String(), "nodes: +1 -1 ~1, edges: +1 -2 ~1"; got != want {
+		t.Fatalf("Diff = %s, want %s", got, want)
+	}
+	if got := nodeIDs(d.AddedNodes); got != "doc" {
+		t.Errorf("added nodes = %q, want doc", got)
+	}
+	if got := nodeIDs(d.RemovedNodes); got != "util" {
+		t.Errorf("removed nodes = %q, want util", got)
+	}
+	if got := edgeNames(d.AddedEdges); got != "root-CONTAINS->doc" {
+		t.Errorf("added edges = %q", got)
+	}
+	if got := edgeNames(d.RemovedEdges); got != "main-IMPORTS->util root-CONTAINS->util" {
+		t.Errorf("removed edges = %q", got)
+	}
+
+	// Label order does not count as a change (root), and numbers compare by
+	// value whatever their Go type (Lines)
+	change := d.ChangedNodes[0]
+	if change.Old.ID != "main" || !change.Labels {
+		t.Errorf("changed node = %s (labels changed: %v), want main with new labels", change.Old.ID, change.Labels)
+	}
+	want := []PropertyChange{
+		{Key: "ModTime", Old: "2024-01-01", New: "2024-02-01"},
+		{Key: "Size", Old: int64(100), New: 120.0},
+	}
+	if !reflect.DeepEqual(change.Properties, want) {
+		t.Errorf("main property changes = %v, want %v", change.Properties, want)
+	}
+
+	edge := d.ChangedEdges[0]
+	if edge.Old.TargetID != "main" || len(edge.Properties) != 1 || edge.Properties[0].Key != "INDEX" {
+		t.Errorf("changed edge = %s with %v, want root-CONTAINS->main with INDEX", edgeNames([]*GLPGEdge{edge.Old}), edge.Properties)
+	}
+}
+
+func TestDiffIgnore(t *testing.T) {
+	a, b := diffGraphs(t)
+	d := Diff(a, b, []string{"ModTime", "INDEX"})
+	if got, want := d.String(), "nodes: +1 -1 ~1, edges: +1 -2 ~0"; got != want {
+		t.Fatalf("Diff = %s, want %s", got, want)
+	}
+	for _, c := range d.ChangedNodes[0].Properties {
+		if c.Key == "ModTime" {
+			t.Errorf("ignored property ModTime reported as changed")
+		}
+	}
+
+	if d := Diff(a, a.Clone(), nil); !d.Empty() {
+		t.Errorf("Diff of a graph with its clone = %s, want empty", d)
+	}
+}
+
+func TestDiffGraph(t *testing.T) {
+	a, b := diffGraphs(t)
+	g, err := Diff(a, b, nil).Graph("old.json", "new.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := g.CheckIntegrity(); len(errs) != 0 {
+		t.Fatalf("diff graph fails CheckIntegrity: %v", errs)
+	}
+
+	var changes []string
+	for id, node := range g.Nodes {
+		if id != DiffRootID {
+			changes = append(changes, id+"="+node.Properties["Change"].(string))
+		}
+	}
+	sort.Strings(changes)
+	if got, want := strings.Join(changes, " "), "doc=added main=changed root=unchanged util=removed"; got != want {
+		t.Errorf("diff graph nodes = %q, want %q", got, want)
+	}
+
+	summary := g.GetNode(DiffRootID).Properties
+	if summary["Old"] != "old.json" || summary["NodesAdded"] != 1 || summary["EdgesRemoved"] != 2 {
+		t.Errorf("summary = %v", summary)
+	}
+	size := g.GetNode("main").Properties["Size"]
+	if want := map[string]interface{}{"Old": int64(100), "New": 120.0}; !reflect.DeepEqual(size, want) {
+		t.Errorf("main Size = %v, want %v", size, want)
+	}
+	if len(g.Edges) != 4 {
+		t.Errorf("diff graph has %d edges, want the 4 changed ones", len(g.Edges))
+	}
+}
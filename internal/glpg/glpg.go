@@ -1,6 +1,11 @@
 package glpg
 
-import "lazybox/internal/ir" // Adjust the import path for ir package
+import (
+	"errors"
+	"fmt"
+
+	"lazybox/internal/ir" // Adjust the import path for ir package
+)
 
 // GLPGProperty represents a map of key-value pairs for properties on nodes and edges.
 // It allows for mixed property types.
@@ -34,6 +39,9 @@ type GLPG struct {
 
 	// For fs target: store the original IR tree
 	OriginalFileInfo *ir.FileInfo
+
+	// Secondary indexes by label and property key, built on first lookup
+	index *index
 }
 
 // NewGLPG creates and initializes a new GLPG structure.
@@ -46,12 +54,18 @@ func NewGLPG() *GLPG {
 	}
 }
 
-// AddNode adds a node to the graph.
+// AddNode adds a node to the graph, replacing any node with the same ID.
 // It ensures the adjacency lists are initialized for the node.
 func (g *GLPG) AddNode(node *GLPGNode) {
 	if node == nil {
 		return // Or handle error
 	}
+	if g.index != nil {
+		if old := g.Nodes[node.ID]; old != nil {
+			g.index.remove(old)
+		}
+		g.index.add(node)
+	}
 	g.Nodes[node.ID] = node
 	if _, exists := g.OutgoingEdges[node.ID]; !exists {
 		g.OutgoingEdges[node.ID] = []*GLPGEdge{}
@@ -61,25 +75,33 @@ func (g *GLPG) AddNode(node *GLPGNode) {
 	}
 }
 
-// AddEdge adds an edge to the graph and updates adjacency lists.
-func (g *GLPG) AddEdge(edge *GLPGEdge) {
+// ErrDanglingEdge is returned by AddEdge for an edge whose source or target
+// is not a node of the graph.
+var ErrDanglingEdge = errors.New("dangling edge")
+
+// AddEdge adds an edge to the graph and updates adjacency lists, replacing
+// any edge with the same ID. Both endpoints must already be in the graph.
+func (g *GLPG) AddEdge(edge *GLPGEdge) error {
 	if edge == nil {
-		return // Or handle error
+		return nil
 	}
-	g.Edges[edge.ID] = edge
-
-	// Ensure source and target nodes exist in adjacency lists before appending
-	if _, exists := g.OutgoingEdges[edge.SourceID]; !exists {
-		// This case implies a node is being referenced before being added.
-		// Depending on strictness, this could be an error or an implicit node creation.
-		// For now, we'll assume nodes are added first or handle it gracefully.
-		g.OutgoingEdges[edge.SourceID] = []*GLPGEdge{}
+	for _, id := range []string{edge.SourceID, edge.TargetID} {
+		if g.Nodes[id] == nil {
+			return fmt.Errorf("%w: edge %s (%s -%s-> %s) references missing node %q",
+				ErrDanglingEdge, edge.ID, edge.SourceID, edge.Label, edge.TargetID, id)
+		}
 	}
-	g.OutgoingEdges[edge.SourceID] = append(g.OutgoingEdges[edge.SourceID], edge)
+	g.putEdge(edge)
+	return nil
+}
 
-	if _, exists := g.IncomingEdges[edge.TargetID]; !exists {
-		g.IncomingEdges[edge.TargetID] = []*GLPGEdge{}
+// putEdge adds or replaces an edge without checking its endpoints.
+func (g *GLPG) putEdge(edge *GLPGEdge) {
+	if g.Edges[edge.ID] != nil {
+		g.RemoveEdge(edge.ID)
 	}
+	g.Edges[edge.ID] = edge
+	g.OutgoingEdges[edge.SourceID] = append(g.OutgoingEdges[edge.SourceID], edge)
 	g.IncomingEdges[edge.TargetID] = append(g.IncomingEdges[edge.TargetID], edge)
 }
 
@@ -102,3 +124,87 @@ func (g *GLPG) GetOutgoingEdges(nodeID string) []*GLPGEdge {
 func (g *GLPG) GetIncomingEdges(nodeID string) []*GLPGEdge {
 	return g.IncomingEdges[nodeID]
 }
+
+// RemoveEdge removes an edge from the graph and its adjacency lists.
+func (g *GLPG) RemoveEdge(id string) {
+	edge, ok := g.Edges[id]
+	if !ok {
+		return
+	}
+	delete(g.Edges, id)
+	g.OutgoingEdges[edge.SourceID] = removeEdgeFrom(g.OutgoingEdges[edge.SourceID], id)
+	g.IncomingEdges[edge.TargetID] = removeEdgeFrom(g.IncomingEdges[edge.TargetID], id)
+}
+
+// RemoveNode removes a node together with every edge into or out of it.
+func (g *GLPG) RemoveNode(id string) {
+	if _, ok := g.Nodes[id]; !ok {
+		return
+	}
+	for _, edge := range append(append([]*GLPGEdge{}, g.OutgoingEdges[id]...), g.IncomingEdges[id]...) {
+		g.RemoveEdge(edge.ID)
+	}
+	if g.index != nil {
+		g.index.remove(g.Nodes[id])
+	}
+	delete(g.Nodes, id)
+	delete(g.OutgoingEdges, id)
+	delete(g.IncomingEdges, id)
+}
+
+// removeEdgeFrom returns edges without the edge with the given ID.
+func removeEdgeFrom(edges []*GLPGEdge, id string) []*GLPGEdge {
+	for i, edge := range edges {
+		if edge.ID == id {
+			return append(edges[:i:i], edges[i+1:]...)
+		}
+	}
+	return edges
+}
+
+// Clone returns a deep copy of the graph structure. Property maps are copied,
+// property values are shared. OriginalFileInfo is shared as well. Edges are
+// copied as they are, including any whose endpoints are missing, and both
+// adjacency lists of every node keep their order, so algorithms that follow
+// them give the same results on the clone.
+func (g *GLPG) Clone() *GLPG {
+	clone := NewGLPG()
+	clone.OriginalFileInfo = g.OriginalFileInfo
+	for _, node := range g.Nodes {
+		clone.AddNode(&GLPGNode{
+			ID:         node.ID,
+			Labels:     append([]string(nil), node.Labels...),
+			Properties: copyProperties(node.Properties),
+		})
+	}
+	for id, edge := range g.Edges {
+		clone.Edges[id] = &GLPGEdge{
+			ID:         edge.ID,
+			SourceID:   edge.SourceID,
+			TargetID:   edge.TargetID,
+			Label:      edge.Label,
+			Properties: copyProperties(edge.Properties),
+		}
+	}
+	copyAdjacency := func(dst, src map[string][]*GLPGEdge) {
+		for id, edges := range src {
+			copied := make([]*GLPGEdge, len(edges))
+			for i, edge := range edges {
+				copied[i] = clone.Edges[edge.ID]
+			}
+			dst[id] = copied
+		}
+	}
+	copyAdjacency(clone.OutgoingEdges, g.OutgoingEdges)
+	copyAdjacency(clone.IncomingEdges, g.IncomingEdges)
+	return clone
+}
+
+// copyProperties returns a shallow copy of props.
+func copyProperties(props GLPGProperty) GLPGProperty {
+	out := make(GLPGProperty, len(props))
+	for k, v := range props {
+		out[k] = v
+	}
+	return out
+}
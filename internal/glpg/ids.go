@@ -0,0 +1,120 @@
+package glpg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// IDs are derived from the data itself so identical input always produces
+// an identical graph: nodes are named after their identity (a path or name)
+// or, failing that, a hash of their parent, edge label and properties, and
+// edges after their endpoints and label. Collisions are resolved from the
+// data as well: a node whose ID is taken is suffixed with a hash of its
+// labels, properties and place in the graph, so the suffix does not depend
+// on the order nodes are added in. Only exact duplicates, which nothing
+// tells apart, chain a hash of the suffixed ID.
+
+// shortHash returns a short hex digest of the given parts.
+func shortHash(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:6])
+}
+
+// propertiesDigest returns a stable string form of props for hashing.
+// encoding/json writes map keys in sorted order.
+func propertiesDigest(props GLPGProperty) string {
+	data, err := json.Marshal(props)
+	if err != nil {
+		return fmt.Sprint(props) // fmt also sorts map keys
+	}
+	return string(data)
+}
+
+// EdgeID returns the ID of the edge labeled label from sourceID to targetID.
+func EdgeID(sourceID, label, targetID string) string {
+	return "E_" + shortHash(sourceID, label, targetID)
+}
+
+// NewEdgeID returns EdgeID(sourceID, label, targetID), with a hashed suffix
+// if the graph already has an edge with that ID (a parallel edge).
+func (g *GLPG) NewEdgeID(sourceID, label, targetID string) string {
+	base := EdgeID(sourceID, label, targetID)
+	id := base
+	for g.Edges[id] != nil {
+		id = base + "_" + shortHash(id)
+	}
+	return id
+}
+
+// NewEdge returns the edge labeled label from sourceID to targetID that
+// Connect adds, without adding it to a graph. Streamed output uses it to
+// write the edges the full graph would have.
+func NewEdge(sourceID, label, targetID string) *GLPGEdge {
+	return &GLPGEdge{
+		ID:         EdgeID(sourceID, label, targetID),
+		SourceID:   sourceID,
+		TargetID:   targetID,
+		Label:      label,
+		Properties: make(GLPGProperty),
+	}
+}
+
+// Connect adds an edge labeled label from sourceID to targetID and returns
+// it, or returns nil if either node is missing.
+func (g *GLPG) Connect(sourceID, label, targetID string) *GLPGEdge {
+	edge := NewEdge(sourceID, label, targetID)
+	edge.ID = g.NewEdgeID(sourceID, label, targetID)
+	if err := g.AddEdge(edge); err != nil {
+		return nil
+	}
+	return edge
+}
+
+// uniqueNodeID returns node's ID or, if taken reports it in use, the ID
+// suffixed with a hash of node's labels and properties and of context
+// (where the node sits in the graph, say).
+func uniqueNodeID(node *GLPGNode, taken func(id string) bool, context ...string) string {
+	if !taken(node.ID) {
+		return node.ID
+	}
+	parts := append([]string{strings.Join(node.Labels, ","), propertiesDigest(node.Properties)}, context...)
+	id := node.ID + "_" + shortHash(parts...)
+	for taken(id) {
+		id = node.ID + "_" + shortHash(id)
+	}
+	return id
+}
+
+// AddUniqueNode adds node, first giving it a hashed suffix if another node
+// already has its ID, and returns the ID it was added under. context is
+// hashed into the suffix along with the node's labels and properties.
+func (g *GLPG) AddUniqueNode(node *GLPGNode, context ...string) string {
+	node.ID = uniqueNodeID(node, func(id string) bool { return g.Nodes[id] != nil }, context...)
+	g.AddNode(node)
+	return node.ID
+}
+
+// NodeIDs resolves ID collisions like AddUniqueNode does for nodes that are
+// streamed instead of added to a GLPG. The zero value is ready to use, and
+// it is safe for concurrent use.
+type NodeIDs struct {
+	mu    sync.Mutex
+	taken map[string]bool
+}
+
+// Claim sets node's ID to the one AddUniqueNode would add it under, given
+// the nodes claimed so far, and returns it.
+func (ids *NodeIDs) Claim(node *GLPGNode, context ...string) string {
+	ids.mu.Lock()
+	defer ids.mu.Unlock()
+	if ids.taken == nil {
+		ids.taken = make(map[string]bool)
+	}
+	node.ID = uniqueNodeID(node, func(id string) bool { return ids.taken[id] }, context...)
+	ids.taken[node.ID] = true
+	return node.ID
+}
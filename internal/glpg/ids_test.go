@@ -0,0 +1,90 @@
+package glpg
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"lazybox/internal/ir"
+)
+
+// TestAddUniqueNode checks that colliding IDs are suffixed from the nodes'
+// content, whatever order they are added in.
+func TestAddUniqueNode(t *testing.T) {
+	node := func(line int) *GLPGNode {
+		return &GLPGNode{ID: "FuncInfo_main.init", Labels: []string{"FuncInfo"}, Properties: GLPGProperty{"Name": "init", "Line": line}}
+	}
+	ids := func(lines ...int) map[int]string {
+		g := NewGLPG()
+		byLine := make(map[int]string)
+		for _, line := range lines {
+			byLine[line] = g.AddUniqueNode(node(line))
+		}
+		return byLine
+	}
+	forward, backward := ids(3, 7, 9), ids(9, 7, 3)
+	for _, line := range []int{7, 9} {
+		if forward[line] == "FuncInfo_main.init" || !strings.HasPrefix(forward[line], "FuncInfo_main.init_") {
+			t.Errorf("init at line %d added as %s, want a suffixed ID", line, forward[line])
+		}
+	}
+	if forward[7] != backward[7] {
+		t.Errorf("init at line 7 is %s or %s depending on the order", forward[7], backward[7])
+	}
+
+	// Exact duplicates still get distinct IDs
+	g := NewGLPG()
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		seen[g.AddUniqueNode(node(1))] = true
+	}
+	if len(seen) != 3 || len(g.Nodes) != 3 {
+		t.Errorf("three duplicates added as %v", seen)
+	}
+}
+
+// TestNodeIDsMatchGraph checks that streamed entries get the IDs the full
+// graph gives them, including paths that sanitize to the same ID.
+func TestNodeIDsMatchGraph(t *testing.T) {
+	root := &ir.FileInfo{Name: "a", Path: "a", IsDir: true, Children: []*ir.FileInfo{
+		{Name: "b_c", Path: "a/b_c", Size: 1},
+		{Name: "b", Path: "a_b", IsDir: true, Children: []*ir.FileInfo{{Name: "c", Path: "a_b/c", Size: 2}}},
+	}}
+	g := NewGLPG()
+	if err := FileInfoToGLPG(root, g, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for id := range g.Nodes {
+		want = append(want, id)
+	}
+
+	var streamed NodeIDs
+	var got []string
+	var walk func(fi *ir.FileInfo)
+	walk = func(fi *ir.FileInfo) {
+		got = append(got, streamed.Claim(FileInfoNode(fi)))
+		for _, child := range fi.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+	sort.Strings(want)
+	sort.Strings(got)
+	if strings.Join(got, " ") != strings.Join(want, " ") || len(got) != 4 {
+		t.Errorf("streamed IDs %v, want %v", got, want)
+	}
+}
+
+func TestGenerateNodeIDSanitizesName(t *testing.T) {
+	type Item struct{ Name string }
+	g, err := ToGLPG(&Item{Name: `C:\dir/x`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := range g.Nodes {
+		if id != `Item_C\dir_x` {
+			t.Errorf("node ID = %s, want Item_C\\dir_x", id)
+		}
+	}
+}
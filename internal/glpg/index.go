@@ -0,0 +1,155 @@
+package glpg
+
+import (
+	"fmt"
+	"sort"
+)
+
+// index maps labels and property keys to the IDs of the nodes that have
+// them. It is built on the first lookup and kept current by AddNode,
+// RemoveNode, UpdateProperties and SetLabels; code that edits a node's
+// Labels or Properties directly must call Reindex afterwards.
+type index struct {
+	labels     map[string]map[string]bool
+	properties map[string]map[string]bool
+}
+
+func (ix *index) add(node *GLPGNode) {
+	for _, label := range node.Labels {
+		addToSet(ix.labels, label, node.ID)
+	}
+	for key := range node.Properties {
+		addToSet(ix.properties, key, node.ID)
+	}
+}
+
+func (ix *index) remove(node *GLPGNode) {
+	for _, label := range node.Labels {
+		removeFromSet(ix.labels, label, node.ID)
+	}
+	for key := range node.Properties {
+		removeFromSet(ix.properties, key, node.ID)
+	}
+}
+
+func addToSet(sets map[string]map[string]bool, key, id string) {
+	if sets[key] == nil {
+		sets[key] = make(map[string]bool)
+	}
+	sets[key][id] = true
+}
+
+func removeFromSet(sets map[string]map[string]bool, key, id string) {
+	delete(sets[key], id)
+	if len(sets[key]) == 0 {
+		delete(sets, key)
+	}
+}
+
+// indexed returns the graph's index, building it if needed.
+func (g *GLPG) indexed() *index {
+	if g.index == nil {
+		g.index = &index{
+			labels:     make(map[string]map[string]bool),
+			properties: make(map[string]map[string]bool),
+		}
+		for _, node := range g.Nodes {
+			g.index.add(node)
+		}
+	}
+	return g.index
+}
+
+// Reindex discards the label and property indexes so the next lookup
+// rebuilds them. Call it after editing node Labels or Properties in place.
+func (g *GLPG) Reindex() {
+	g.index = nil
+}
+
+// nodesIn returns the nodes with the given IDs, sorted by ID.
+func (g *GLPG) nodesIn(ids map[string]bool) []*GLPGNode {
+	nodes := make([]*GLPGNode, 0, len(ids))
+	for id := range ids {
+		nodes = append(nodes, g.Nodes[id])
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+// NodesByLabel returns the nodes carrying label, sorted by ID.
+func (g *GLPG) NodesByLabel(label string) []*GLPGNode {
+	return g.nodesIn(g.indexed().labels[label])
+}
+
+// NodesWithProperty returns the nodes that have the property key, sorted by ID.
+func (g *GLPG) NodesWithProperty(key string) []*GLPGNode {
+	return g.nodesIn(g.indexed().properties[key])
+}
+
+// NodesByProperty returns the nodes whose property key equals value, sorted
+// by ID. Scalars compare by value, so an int matches the same int64.
+func (g *GLPG) NodesByProperty(key string, value interface{}) []*GLPGNode {
+	var nodes []*GLPGNode
+	for _, node := range g.NodesWithProperty(key) {
+		if sameValue(node.Properties[key], value) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// UpdateProperties sets the given properties on a node; a nil value removes
+// the property.
+func (g *GLPG) UpdateProperties(id string, props GLPGProperty) error {
+	node := g.Nodes[id]
+	if node == nil {
+		return fmt.Errorf("no node %q", id)
+	}
+	if g.index != nil {
+		g.index.remove(node)
+		defer g.index.add(node)
+	}
+	if node.Properties == nil {
+		node.Properties = make(GLPGProperty, len(props))
+	}
+	applyProperties(node.Properties, props)
+	return nil
+}
+
+// UpdateEdgeProperties sets the given properties on an edge; a nil value
+// removes the property.
+func (g *GLPG) UpdateEdgeProperties(id string, props GLPGProperty) error {
+	edge := g.Edges[id]
+	if edge == nil {
+		return fmt.Errorf("no edge %q", id)
+	}
+	if edge.Properties == nil {
+		edge.Properties = make(GLPGProperty, len(props))
+	}
+	applyProperties(edge.Properties, props)
+	return nil
+}
+
+func applyProperties(dst, props GLPGProperty) {
+	for k, v := range props {
+		if v == nil {
+			delete(dst, k)
+		} else {
+			dst[k] = v
+		}
+	}
+}
+
+// SetLabels replaces a node's labels.
+func (g *GLPG) SetLabels(id string, labels []string) error {
+	node := g.Nodes[id]
+	if node == nil {
+		return fmt.Errorf("no node %q", id)
+	}
+	if g.index != nil {
+		g.index.remove(node)
+		defer g.index.add(node)
+	}
+	node.Labels = append([]string(nil), labels...)
+	return nil
+}
@@ -1,15 +1,16 @@
 package glpg
 
 import (
+	"encoding/base64"
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"lazybox/internal/ir" // Corrected import path
-
-	"github.com/google/uuid"
 )
 
 // ToGLPG converts any supported IR struct into a GLPG.
@@ -20,223 +21,453 @@ func ToGLPG(data interface{}) (*GLPG, error) {
 	if fi, ok := data.(*ir.FileInfo); ok {
 		g.OriginalFileInfo = fi
 	}
-	err := ingestToGLPG(data, g, "", "") // No parent node or edge label for the root
+	// Source code gets a dedicated ingestor so declarations become linked nodes
+	if ci, ok := data.(*ir.CodeInfo); ok {
+		if err := CodeInfoToGLPG(ci, g); err != nil {
+			return nil, err
+		}
+		return g, nil
+	}
+	if api, ok := data.(*ir.APIInfo); ok {
+		if err := APIInfoToGLPG(api, g); err != nil {
+			return nil, err
+		}
+		return g, nil
+	}
+	if pkgInfo, ok := data.(*ir.PackageInfo); ok {
+		if err := PackageInfoToGLPG(pkgInfo, g); err != nil {
+			return nil, err
+		}
+		return g, nil
+	}
+	err := ingestToGLPG(data, g, "", link{}) // No parent node or edge label for the root
 	if err != nil {
 		return nil, err
 	}
 	return g, nil
 }
 
-// ingestToGLPG is the core recursive function that converts IR structs to GLPG nodes and edges.
-// parentNodeID and edgeLabelToParent are used to link child nodes to their parent.
-func ingestToGLPG(data interface{}, g *GLPG, parentNodeID string, edgeLabelToParent string) error {
-	if data == nil || (reflect.ValueOf(data).Kind() == reflect.Ptr && reflect.ValueOf(data).IsNil()) {
-		return nil // Skip nil data
+// link describes how a node hangs from its parent: the edge label, the
+// edge's properties (the KEY or INDEX of a map or slice element) and the
+// node's label, if it replaces the type name. unordered leaves slice
+// elements without an INDEX.
+type link struct {
+	edge      string
+	props     GLPGProperty
+	label     string
+	unordered bool
+}
+
+// element returns the link for an element of a slice (key "INDEX") or a map
+// (key "KEY"). Elements of nested collections get a list of indexes or keys.
+func (l link) element(key string, index interface{}) link {
+	props := copyProperties(l.props)
+	if props == nil {
+		props = make(GLPGProperty)
+	}
+	if outer, ok := props[key]; ok {
+		if path, ok := outer.([]interface{}); ok {
+			index = append(append([]interface{}(nil), path...), index)
+		} else {
+			index = []interface{}{outer, index}
+		}
 	}
+	props[key] = index
+	return link{edge: l.edge, props: props, label: l.label, unordered: l.unordered}
+}
 
+// ingestToGLPG is the core recursive function that converts IR structs to GLPG nodes and edges.
+// parentNodeID and to are used to link child nodes to their parent. Struct
+// fields are read according to their glpg tags (see tags.go).
+func ingestToGLPG(data interface{}, g *GLPG, parentNodeID string, to link) error {
 	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil // Skip nil data
+		}
 		val = val.Elem()
 	}
 
-	// Handle slices by iterating and ingesting each element
-	if val.Kind() == reflect.Slice {
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		// Each element in the slice will be connected to the same parentNodeID
+		// with the same edge label, as a new node recording its INDEX unless
+		// the order is meaningless
 		for i := 0; i < val.Len(); i++ {
-			// Each element in the slice will be connected to the same parentNodeID
-			// with the same edgeLabelToParent, but as a new node.
-			err := ingestToGLPG(val.Index(i).Interface(), g, parentNodeID, edgeLabelToParent)
-			if err != nil {
-				// Potentially collect errors and continue, or return immediately
+			elem := to
+			if !to.unordered {
+				elem = to.element("INDEX", i)
+			}
+			if err := ingestToGLPG(val.Index(i).Interface(), g, parentNodeID, elem); err != nil {
 				return fmt.Errorf("error ingesting slice element %d: %w", i, err)
 			}
 		}
 		return nil
+	case reflect.Map:
+		// Map values are linked like slice elements, recording their KEY, in
+		// key order
+		for _, key := range sortedMapKeys(val) {
+			k := fmt.Sprint(key.Interface())
+			if err := ingestToGLPG(val.MapIndex(key).Interface(), g, parentNodeID, to.element("KEY", k)); err != nil {
+				return fmt.Errorf("error ingesting map value %q: %w", k, err)
+			}
+		}
+		return nil
+	case reflect.Struct:
+	default:
+		// Primitives only become properties of their parent's node, which
+		// collect handles
+		return nil
 	}
 
-	// Expect a struct at this point for creating a node
-	if val.Kind() != reflect.Struct {
-		// If we have a parent and an edge label, but the data is a primitive,
-		// it might be a property of the parent. However, our design is to make nodes from structs.
-		// This case might indicate an unexpected data structure or a need to handle direct properties.
-		// For now, we skip non-structs that are not part of a slice handled above.
-		// If parentNodeID is not empty, this primitive could have been a property of the parent.
-		// However, the main loop over struct fields handles properties.
-		return nil
+	label := to.label
+	if label == "" {
+		label = val.Type().Name()
+	}
+	node, fields := structNode(val, label)
+	if node.ID == "" {
+		node.ID = generateNodeID(label, val, parentNodeID, to.edge, node.Properties)
 	}
+	// Another node may have the same identity (two structs with the same
+	// Name, say): qualify the ID with where this one sits in the graph
+	nodeID := g.AddUniqueNode(node, parentNodeID, to.edge, propertiesDigest(to.props))
 
-	nodeID := generateNodeID(val.Type().Name(), val)
+	// If this node has a parent, create an edge to it
+	if parentNodeID != "" && to.edge != "" {
+		if edge := g.Connect(parentNodeID, to.edge, nodeID); edge != nil && len(to.props) > 0 {
+			edge.Properties = to.props
+		}
+	}
+
+	// Child nodes are ingested once this node has been added, since their
+	// edges need its final ID
+	for _, child := range fields.children {
+		if err := ingestToGLPG(child.value, g, nodeID, child.link); err != nil {
+			return fmt.Errorf("error ingesting field %s: %w", child.edge, err)
+		}
+	}
+
+	return nil
+}
+
+// structNode builds the node for the struct val, labeled label, from its
+// glpg tags. Its ID is left empty unless a field is tagged id. The fields
+// that become child nodes are returned with the rest of what was collected.
+func structNode(val reflect.Value, label string) (*GLPGNode, *nodeFields) {
+	fields := &nodeFields{props: make(GLPGProperty)}
+	fields.collect(val)
 	node := &GLPGNode{
-		ID:         nodeID,
-		Labels:     []string{val.Type().Name()},
-		Properties: make(GLPGProperty),
+		Labels:     append([]string{label}, fields.labels...),
+		Properties: fields.props,
 	}
+	if fields.id != "" {
+		node.ID = label + "_" + sanitizeIDPart(fields.id)
+	}
+	return node, fields
+}
+
+// nodeFields collects what the fields of a struct contribute to its node.
+type nodeFields struct {
+	props    GLPGProperty
+	labels   []string
+	children []childField
+	id       string // Value of the field tagged id
+}
+
+// childField is a field that becomes child nodes.
+type childField struct {
+	link
+	value interface{}
+}
 
-	// Add properties from struct fields
+// collect reads the exported fields of the struct val. By default, structs
+// behind pointers or interfaces, and slices and maps of structs, become child
+// nodes, embedded structs are inlined and everything else becomes a
+// property; glpg tags override this.
+func (f *nodeFields) collect(val reflect.Value) {
 	for i := 0; i < val.NumField(); i++ {
 		field := val.Type().Field(i)
 		fieldVal := val.Field(i)
 
 		// Skip unexported fields
-		if field.PkgPath != "" {
+		if field.PkgPath != "" || !fieldVal.CanInterface() {
+			continue
+		}
+		tag := parseFieldTag(field)
+		if tag.Skip {
 			continue
 		}
 
-		// Handle embedded structs (only if the embedded struct itself is an exported type)
-		if field.Anonymous && fieldVal.Kind() == reflect.Struct && fieldVal.Type().PkgPath() == "" {
-			// Recursively add properties from exported fields of the embedded struct
-			for j := 0; j < fieldVal.NumField(); j++ {
-				embeddedField := fieldVal.Type().Field(j)
-				embeddedFieldVal := fieldVal.Field(j)
-				// Skip unexported fields in embedded struct
-				if embeddedField.PkgPath != "" {
-					continue
-				}
-				if embeddedFieldVal.CanInterface() {
-					addProperty(node.Properties, embeddedField.Name, embeddedFieldVal.Interface())
-				}
+		if tag.Inline || (field.Anonymous && !tag.Node && !tag.Prop) {
+			inner := reflect.Indirect(fieldVal)
+			if inner.Kind() == reflect.Struct && !isTime(inner.Type()) {
+				f.collect(inner)
+				continue
+			}
+			if tag.Inline && !inner.IsValid() {
+				continue // A nil pointer has nothing to merge
 			}
-			continue // Done with this embedded struct field
 		}
 
-		kind := fieldVal.Kind()
-		fieldType := fieldVal.Type()
-
-		// Determine if the field's type is an exported struct or pointer to an exported struct
-		isExportedStructType := false
-		if kind == reflect.Struct && fieldType.PkgPath() == "" {
-			isExportedStructType = true
-		} else if kind == reflect.Ptr && fieldType.Elem().Kind() == reflect.Struct && fieldType.Elem().PkgPath() == "" {
-			isExportedStructType = true
+		if tag.ID {
+			if s := scalarString(fieldVal); s != "" {
+				f.id = s
+			}
+		}
+		if tag.AsLabel {
+			if s := scalarString(fieldVal); s != "" {
+				f.labels = append(f.labels, s)
+			}
 		}
 
-		// If field is an exported struct, pointer to an exported struct, or a slice,
-		// it might become new node(s) or its elements processed.
-		if isExportedStructType || kind == reflect.Slice {
-			if fieldVal.CanInterface() {
-				err := ingestToGLPG(fieldVal.Interface(), g, nodeID, field.Name)
-				if err != nil {
-					return fmt.Errorf("error ingesting field %s: %w", field.Name, err)
-				}
+		if tag.Flatten {
+			if v, ok := propertyValue(fieldVal); ok {
+				addFlattened(f.props, tag.Name, v, tag.Omit)
 			}
-		} else {
-			// Otherwise, add as a property (e.g. basic types, or non-pointer, non-slice structs
-			// that are not meant to be separate nodes but rather flattened - addProperty will handle this).
-			if fieldVal.CanInterface() {
-				addProperty(node.Properties, field.Name, fieldVal.Interface())
+			continue
+		}
+
+		// An interface is judged by the value it holds
+		dynamic := fieldVal
+		if dynamic.Kind() == reflect.Interface && !dynamic.IsNil() {
+			dynamic = dynamic.Elem()
+		}
+		if !tag.Prop && (tag.Node || isNodeType(dynamic.Type())) && canBeNode(dynamic.Type()) {
+			f.children = append(f.children, childField{link{edge: tag.Edge, label: tag.Label, unordered: tag.NoIndex}, fieldVal.Interface()})
+			continue
+		}
+		if tag.Omit {
+			if v, ok := propertyValue(fieldVal); !ok || fieldVal.IsZero() || isEmptyValue(v) {
+				continue
 			}
 		}
+		addProperty(f.props, tag.Name, fieldVal.Interface())
 	}
+}
 
-	g.AddNode(node)
-
-	// If this node has a parent, create an edge to it
-	if parentNodeID != "" && edgeLabelToParent != "" {
-		edge := &GLPGEdge{
-			ID:         uuid.NewString(), // Simple unique ID for the edge
-			SourceID:   parentNodeID,
-			TargetID:   nodeID,
-			Label:      edgeLabelToParent,
-			Properties: make(GLPGProperty), // Edges can also have properties if needed
+// addFlattened adds the property value v under key, or, if v is a map, each
+// of its entries under key_<entry key>, flattening nested maps in turn.
+// With omitEmpty, empty values are left out.
+func addFlattened(props GLPGProperty, key string, v interface{}, omitEmpty bool) {
+	if m, ok := v.(map[string]interface{}); ok {
+		for k, inner := range m {
+			addFlattened(props, key+"_"+k, inner, omitEmpty)
 		}
-		g.AddEdge(edge)
+		return
+	}
+	if v == nil || (omitEmpty && isEmptyValue(v)) {
+		return
+	}
+	if items, ok := v.([]interface{}); ok && len(items) == 0 {
+		return
 	}
+	props[key] = v
+}
 
-	return nil
+// isEmptyValue reports whether the property value v is the zero value of
+// its type, an empty list or an empty map.
+func isEmptyValue(v interface{}) bool {
+	switch c := v.(type) {
+	case nil:
+		return true
+	case []interface{}:
+		return len(c) == 0
+	case map[string]interface{}:
+		return len(c) == 0
+	}
+	return reflect.ValueOf(v).IsZero()
 }
 
-// addProperty adds a value to the properties map, handling common types.
-func addProperty(props GLPGProperty, key string, value interface{}) {
-	refVal := reflect.ValueOf(value) // Value is from fieldVal.Interface()
+var timeType = reflect.TypeOf(time.Time{})
+
+func isTime(t reflect.Type) bool {
+	return t == timeType
+}
+
+// isNodeType reports whether a field of type t becomes child nodes when it
+// has no tag: a pointer to a struct, or a (possibly nested) slice, array or
+// map of structs or of pointers to them. Times are always properties.
+func isNodeType(t reflect.Type) bool {
+	elem := containedType(t)
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	} else if elem == t {
+		return false // A struct value is a property
+	}
+	return elem.Kind() == reflect.Struct && !isTime(elem)
+}
+
+// canBeNode reports whether a field of type t can become child nodes, which
+// the node tag asks for: a struct, a pointer to one, or a slice, array or
+// map of either.
+func canBeNode(t reflect.Type) bool {
+	t = containedType(t)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !isTime(t)
+}
+
+// containedType returns the element type of t after unwrapping any slices,
+// arrays and maps.
+func containedType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	return t
+}
 
-	switch refVal.Kind() {
+// scalarString formats a string, bool or number field for use in an ID or a
+// label, or returns "" for other kinds.
+func scalarString(v reflect.Value) string {
+	switch v.Kind() {
 	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
-		props[key] = value
-	default:
-		// Special handling for time.Time
-		if t, ok := value.(time.Time); ok {
-			props[key] = t.Format(time.RFC3339)
-		} else if refVal.Kind() == reflect.Struct {
-			// This handles structs that are direct fields (not pointers/slices that become nodes)
-			// and are intended to be flattened as properties (e.g., ir.ReadabilityScores).
-			// Ensure the struct type itself is exported.
-			if refVal.Type().PkgPath() == "" { // Exported struct type
-				structProps := make(map[string]interface{})
-				for i := 0; i < refVal.NumField(); i++ {
-					structField := refVal.Type().Field(i)
-					structFieldVal := refVal.Field(i)
-
-					// Only consider exported fields of this struct
-					if structField.PkgPath == "" && structFieldVal.CanInterface() {
-						fieldInterface := structFieldVal.Interface()
-						// Handle time.Time within these structs specifically
-						if t, ok := fieldInterface.(time.Time); ok {
-							structProps[structField.Name] = t.Format(time.RFC3339)
-						} else {
-							// Add only if it's a basic type to prevent deep nesting issues.
-							switch structFieldVal.Kind() {
-							case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-								reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
-								structProps[structField.Name] = fieldInterface
-								// Other nested complex types within these flattened structs are skipped.
-							}
-						}
-					}
-				}
-				if len(structProps) > 0 {
-					props[key] = structProps
-				}
+		return fmt.Sprint(v.Interface())
+	}
+	return ""
+}
+
+// addProperty adds a value to the properties map, converted by
+// propertyValue. Nil values and empty maps and slices are left out.
+func addProperty(props GLPGProperty, key string, value interface{}) {
+	v, ok := propertyValue(reflect.ValueOf(value))
+	if !ok {
+		return
+	}
+	switch c := v.(type) {
+	case map[string]interface{}:
+		if len(c) == 0 {
+			return
+		}
+	case []interface{}:
+		if len(c) == 0 {
+			return
+		}
+	}
+	props[key] = v
+}
+
+// propertyValue converts any Go value to a property value without losing
+// data: scalars keep their type, times become RFC 3339 strings, byte slices
+// become strings (base64 if they are not UTF-8), structs (by their glpg
+// names) and maps become map[string]interface{} and slices and arrays
+// become []interface{}, recursively. Nil pointers, maps, slices and
+// interfaces, funcs and channels have no value and report false; inside
+// lists they are kept as nil so indexes are preserved.
+func propertyValue(v reflect.Value) (interface{}, bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, false
+		}
+		return propertyValue(v.Elem())
+	case reflect.String:
+		return v.String(), true // Named string types, like enums, become plain strings
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return v.Interface(), true
+	case reflect.Complex64, reflect.Complex128:
+		return fmt.Sprint(v.Interface()), true
+	case reflect.Struct:
+		if isTime(v.Type()) {
+			return v.Interface().(time.Time).Format(time.RFC3339), true
+		}
+		m := make(map[string]interface{})
+		addStructValues(m, v)
+		return m, true
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, false
+		}
+		m := make(map[string]interface{}, v.Len())
+		for _, key := range sortedMapKeys(v) {
+			if value, ok := propertyValue(v.MapIndex(key)); ok {
+				m[fmt.Sprint(key.Interface())] = value
 			}
-		} else if refVal.Kind() == reflect.Map {
-			// Handle maps (simplistic: string keys, basic value types)
-			mapInterface := make(map[string]interface{})
-			iter := refVal.MapRange()
-			for iter.Next() {
-				k := iter.Key()
-				v := iter.Value()
-				if k.CanInterface() && v.CanInterface() {
-					// Ensure value is a basic type or time.Time for simplicity in properties
-					valInterface := v.Interface()
-					if t, ok := valInterface.(time.Time); ok {
-						mapInterface[fmt.Sprintf("%v", k.Interface())] = t.Format(time.RFC3339)
-					} else {
-						switch v.Kind() {
-						case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-							reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
-							mapInterface[fmt.Sprintf("%v", k.Interface())] = valInterface
-						}
-					}
-				}
+		}
+		return m, true
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, false
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if b := v.Bytes(); !utf8.Valid(b) {
+				return base64.StdEncoding.EncodeToString(b), true
 			}
-			if len(mapInterface) > 0 {
-				props[key] = mapInterface
+			return string(v.Bytes()), true
+		}
+		fallthrough
+	case reflect.Array:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i], _ = propertyValue(v.Index(i))
+		}
+		return items, true
+	}
+	return nil, false
+}
+
+// addStructValues adds the exported fields of the struct v to m, named and
+// skipped by their glpg tags; embedded and inline structs are merged in.
+func addStructValues(m map[string]interface{}, v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := parseFieldTag(field)
+		if tag.Skip {
+			continue
+		}
+		fieldVal := v.Field(i)
+		if tag.Inline || field.Anonymous {
+			if inner := reflect.Indirect(fieldVal); inner.Kind() == reflect.Struct && !isTime(inner.Type()) {
+				addStructValues(m, inner)
+				continue
 			}
 		}
-		// Other complex types not explicitly handled are skipped as properties.
+		if value, ok := propertyValue(fieldVal); ok {
+			m[tag.Name] = value
+		}
 	}
 }
 
-// generateNodeID creates a unique ID for a node.
-// This is a simple implementation and might need to be more robust
-// to ensure global uniqueness if graphs are merged, etc.
-// For ir.FileInfo, using the path is a good candidate for uniqueness.
-func generateNodeID(typeName string, val reflect.Value) string {
-	// Attempt to find a unique identifier field like 'Path' or 'Name'
+// sortedMapKeys returns the keys of the map v ordered by their formatted
+// value, so maps are ingested in a stable order.
+func sortedMapKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
+}
+
+// generateNodeID derives a node's ID from its content, so the same input
+// always yields the same ID. A Path or Name field identifies the node;
+// otherwise the ID is a hash of where the node hangs in the graph (its
+// parent and edge label) and its properties. Path and Name are sanitized
+// alike. The ID is only a candidate: callers resolve collisions when adding
+// the node.
+func generateNodeID(typeName string, val reflect.Value, parentNodeID, edgeLabel string, props GLPGProperty) string {
 	if val.Kind() == reflect.Struct {
 		pathField := val.FieldByName("Path")
 		if pathField.IsValid() && pathField.Kind() == reflect.String && pathField.String() != "" {
-			// Sanitize path to be a valid ID component
-			cleanPath := strings.ReplaceAll(filepath.ToSlash(pathField.String()), "/", "_")
-			cleanPath = strings.ReplaceAll(cleanPath, ":", "") // Remove colons for Windows paths
-			return fmt.Sprintf("%s_%s", typeName, cleanPath)
+			return fmt.Sprintf("%s_%s", typeName, sanitizeIDPart(pathField.String()))
 		}
 		nameField := val.FieldByName("Name")
 		if nameField.IsValid() && nameField.Kind() == reflect.String && nameField.String() != "" {
-			return fmt.Sprintf("%s_%s", typeName, nameField.String())
+			return fmt.Sprintf("%s_%s", typeName, sanitizeIDPart(nameField.String()))
 		}
 	}
-	// Fallback to UUID if no suitable field is found or not a struct
-	return fmt.Sprintf("%s_%s", typeName, uuid.NewString())
+	return fmt.Sprintf("%s_%s", typeName, shortHash(parentNodeID, edgeLabel, propertiesDigest(props)))
+}
+
+// sanitizeIDPart makes a path usable as an ID component.
+func sanitizeIDPart(path string) string {
+	cleanPath := strings.ReplaceAll(filepath.ToSlash(path), "/", "_")
+	return strings.ReplaceAll(cleanPath, ":", "") // Remove colons for Windows paths
 }
 
 // Specific Ingestors (can be added for more control if needed, but generic one is powerful)
@@ -244,108 +475,30 @@ func generateNodeID(typeName string, val reflect.Value) string {
 // FileInfoToGLPG converts an ir.FileInfo struct and its children into GLPG nodes and edges.
 // This is an example of a specific ingestor, though the generic one aims to handle this.
 func FileInfoToGLPG(fi *ir.FileInfo, g *GLPG, parentNodeID string, edgeLabel string) error {
+	return fileInfoToGLPG(fi, g, parentNodeID, edgeLabel, nil)
+}
+
+// fileInfoToGLPG is FileInfoToGLPG, recording the ID each entry was added
+// under in ids if it is not nil. Paths that sanitize to the same ID (a/b_c
+// and a_b/c) get suffixed IDs, so callers cannot recompute them.
+func fileInfoToGLPG(fi *ir.FileInfo, g *GLPG, parentNodeID string, edgeLabel string, ids map[*ir.FileInfo]string) error {
 	if fi == nil {
 		return nil
 	}
 
-	nodeID := generateNodeID("FileInfo", reflect.ValueOf(*fi)) // Use path for FileInfo ID
-	node := &GLPGNode{
-		ID:     nodeID,
-		Labels: []string{"FileInfo", string(fi.Type)}, // Add FileType as a label
-		Properties: GLPGProperty{
-			"Name":         fi.Name,
-			"Path":         fi.Path,
-			"AbsolutePath": fi.AbsolutePath,
-			"Type":         string(fi.Type),
-			"Size":         fi.Size,
-			"Mode":         fi.Mode,
-			"ModTime":      fi.ModTime.Format(time.RFC3339),
-			"IsDir":        fi.IsDir,     // Corrected: Direct field access
-			"IsSymlink":    fi.IsSymlink, // Corrected: Direct field access
-		},
-	}
-
-	if fi.SymlinkTarget != "" {
-		node.Properties["SymlinkTarget"] = fi.SymlinkTarget
-	}
-	if !fi.CreateTime.IsZero() {
-		node.Properties["CreateTime"] = fi.CreateTime.Format(time.RFC3339)
-	}
-	if fi.Owner != "" {
-		node.Properties["Owner"] = fi.Owner
-	}
-	if fi.Group != "" {
-		node.Properties["Group"] = fi.Group
-	}
-	if fi.Extension != "" {
-		node.Properties["Extension"] = fi.Extension
-	}
-	if fi.Error != "" {
-		node.Properties["Error"] = fi.Error
-	}
-
-	// Handle content: if present, add as property. Could also be a separate node for large content.
-	if fi.Content != nil && *fi.Content != "" {
-		// Decide on a strategy: store directly, or hash, or summarize for large content
-		// For now, storing directly if not too large, otherwise a placeholder or hash.
-		const maxContentLength = 1024 // Example limit
-		if len(*fi.Content) > maxContentLength {
-			node.Properties["ContentSummary"] = (*fi.Content)[:maxContentLength] + "... (truncated)"
-		} else {
-			node.Properties["Content"] = *fi.Content
-		}
+	nodeID := g.AddUniqueNode(FileInfoNode(fi))
+	if ids != nil {
+		ids[fi] = nodeID
 	}
 
-	if fi.TextAnalysis != nil {
-		// Flatten TextAnalysis properties with a prefix
-		node.Properties["TextAnalysis_LineCount"] = fi.TextAnalysis.LineCount
-		node.Properties["TextAnalysis_WordCount"] = fi.TextAnalysis.WordCount
-		node.Properties["TextAnalysis_CharCount"] = fi.TextAnalysis.CharCount
-		if fi.TextAnalysis.DetectedLanguage != "" {
-			node.Properties["TextAnalysis_DetectedLanguage"] = fi.TextAnalysis.DetectedLanguage
-		}
-		// Add other TextAnalysis fields as needed, checking for nil pointers if applicable
-		if fi.TextAnalysis.Readability != nil {
-			node.Properties["TextAnalysis_Readability_FleschKincaidGradeLevel"] = fi.TextAnalysis.Readability.FleschKincaidGradeLevel
-			node.Properties["TextAnalysis_Readability_GunningFogIndex"] = fi.TextAnalysis.Readability.GunningFogIndex
-		}
-		if fi.TextAnalysis.Sentiment != nil {
-			node.Properties["TextAnalysis_Sentiment_Polarity"] = fi.TextAnalysis.Sentiment.Polarity
-			node.Properties["TextAnalysis_Sentiment_Subjectivity"] = fi.TextAnalysis.Sentiment.Subjectivity
-		}
-	}
-
-	if fi.GitRemoteURL != "" {
-		node.Properties["GitRemoteURL"] = fi.GitRemoteURL
-	}
-	if fi.GitCurrentBranch != "" {
-		node.Properties["GitCurrentBranch"] = fi.GitCurrentBranch
-	}
-
-	// Add any custom metadata, prefixing keys to avoid collisions
-	for k, v := range fi.Metadata {
-		propsKey := "Metadata_" + k
-		// Ensure the value is suitable for GLPGProperty (e.g., basic types, strings)
-		// This might require more sophisticated type handling if metadata contains complex structs
-		node.Properties[propsKey] = fmt.Sprintf("%v", v) // Simple conversion to string
-	}
-
-	g.AddNode(node)
-
 	if parentNodeID != "" && edgeLabel != "" {
-		edge := &GLPGEdge{
-			ID:       uuid.NewString(),
-			SourceID: parentNodeID,
-			TargetID: nodeID,
-			Label:    edgeLabel,
-		}
-		g.AddEdge(edge)
+		g.Connect(parentNodeID, edgeLabel, nodeID)
 	}
 
 	// Recursively process children
 	for _, child := range fi.Children {
 		// Children are linked to the current node with an edge type like "CONTAINS" or "CHILD_OF"
-		err := FileInfoToGLPG(child, g, nodeID, "CONTAINS")
+		err := fileInfoToGLPG(child, g, nodeID, "CONTAINS", ids)
 		if err != nil {
 			return fmt.Errorf("failed to ingest child FileInfo for %s: %w", child.Name, err)
 		}
@@ -354,6 +507,19 @@ func FileInfoToGLPG(fi *ir.FileInfo, g *GLPG, parentNodeID string, edgeLabel str
 	return nil
 }
 
+// FileInfoNode builds the node FileInfoToGLPG creates for fi, without its
+// children or edges. It is also used to stream entries as they are scanned.
+// The node is built from fi's glpg tags, exactly like the generic ingestor
+// builds it.
+func FileInfoNode(fi *ir.FileInfo) *GLPGNode {
+	val := reflect.ValueOf(*fi)
+	node, _ := structNode(val, "FileInfo")
+	if node.ID == "" {
+		node.ID = generateNodeID("FileInfo", val, "", "", node.Properties)
+	}
+	return node
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -0,0 +1,90 @@
+package glpg
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CheckIntegrity reports every inconsistency in the graph's structure:
+// nodes or edges stored under the wrong ID, edges whose endpoints are
+// missing, and adjacency lists that disagree with Edges. A graph built only
+// through AddNode, AddEdge and the Remove methods always passes.
+func (g *GLPG) CheckIntegrity() []error {
+	var problems []error
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	for _, id := range sortedKeys(g.Nodes) {
+		switch node := g.Nodes[id]; {
+		case node == nil:
+			fail("node %q is nil", id)
+		case node.ID != id:
+			fail("node %q is stored under ID %q", node.ID, id)
+		}
+	}
+
+	for _, id := range sortedKeys(g.Edges) {
+		edge := g.Edges[id]
+		if edge == nil {
+			fail("edge %q is nil", id)
+			continue
+		}
+		if edge.ID != id {
+			fail("edge %q is stored under ID %q", edge.ID, id)
+		}
+		if g.Nodes[edge.SourceID] == nil {
+			fail("edge %q: source node %q does not exist", id, edge.SourceID)
+		}
+		if g.Nodes[edge.TargetID] == nil {
+			fail("edge %q: target node %q does not exist", id, edge.TargetID)
+		}
+		if n := countEdge(g.OutgoingEdges[edge.SourceID], edge); n != 1 {
+			fail("edge %q appears %d times in the outgoing edges of %q", id, n, edge.SourceID)
+		}
+		if n := countEdge(g.IncomingEdges[edge.TargetID], edge); n != 1 {
+			fail("edge %q appears %d times in the incoming edges of %q", id, n, edge.TargetID)
+		}
+	}
+
+	checkAdjacency := func(direction string, lists map[string][]*GLPGEdge, endpoint func(*GLPGEdge) string) {
+		for _, nodeID := range sortedKeys(lists) {
+			edges := lists[nodeID]
+			if len(edges) > 0 && g.Nodes[nodeID] == nil {
+				fail("%s edges listed for missing node %q", direction, nodeID)
+			}
+			for _, edge := range edges {
+				switch {
+				case edge == nil:
+					fail("nil edge in the %s edges of %q", direction, nodeID)
+				case g.Edges[edge.ID] != edge:
+					fail("%s edges of %q hold edge %q, which is not in the graph", direction, nodeID, edge.ID)
+				case endpoint(edge) != nodeID:
+					fail("%s edges of %q hold edge %q, which belongs to %q", direction, nodeID, edge.ID, endpoint(edge))
+				}
+			}
+		}
+	}
+	checkAdjacency("outgoing", g.OutgoingEdges, func(e *GLPGEdge) string { return e.SourceID })
+	checkAdjacency("incoming", g.IncomingEdges, func(e *GLPGEdge) string { return e.TargetID })
+	return problems
+}
+
+func countEdge(edges []*GLPGEdge, edge *GLPGEdge) int {
+	n := 0
+	for _, e := range edges {
+		if e == edge {
+			n++
+		}
+	}
+	return n
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,94 @@
+package glpg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func integrityGraph(t *testing.T) *GLPG {
+	t.Helper()
+	g := NewGLPG()
+	for _, id := range []string{"a", "b", "c"} {
+		g.AddNode(&GLPGNode{ID: id, Labels: []string{"Node"}, Properties: GLPGProperty{}})
+	}
+	for _, e := range [][3]string{{"ab", "a", "b"}, {"bc", "b", "c"}} {
+		if err := g.AddEdge(&GLPGEdge{ID: e[0], SourceID: e[1], TargetID: e[2], Label: "LINK", Properties: GLPGProperty{}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g
+}
+
+func errorStrings(errs []error) string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	if errs := integrityGraph(t).CheckIntegrity(); len(errs) != 0 {
+		t.Fatalf("CheckIntegrity of a well-formed graph: %v", errs)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(g *GLPG)
+		want   string
+	}{
+		{"dangling target", func(g *GLPG) {
+			g.putEdge(&GLPGEdge{ID: "e", SourceID: "a", TargetID: "gone", Label: "LINK", Properties: GLPGProperty{}})
+		}, `edge "e": target node "gone" does not exist` + "\n" + `incoming edges listed for missing node "gone"`},
+		{"dangling source", func(g *GLPG) {
+			g.putEdge(&GLPGEdge{ID: "e", SourceID: "gone", TargetID: "c", Label: "LINK", Properties: GLPGProperty{}})
+		}, `edge "e": source node "gone" does not exist` + "\n" + `outgoing edges listed for missing node "gone"`},
+		{"node deleted behind the graph's back", func(g *GLPG) {
+			delete(g.Nodes, "c")
+		}, `edge "bc": target node "c" does not exist` + "\n" + `incoming edges listed for missing node "c"`},
+		{"node under the wrong ID", func(g *GLPG) {
+			g.Nodes["c"].ID = "d"
+		}, `node "d" is stored under ID "c"`},
+		{"edge missing from adjacency", func(g *GLPG) {
+			g.OutgoingEdges["a"] = nil
+		}, `edge "ab" appears 0 times in the outgoing edges of "a"`},
+		{"stray adjacency entry", func(g *GLPG) {
+			edge := g.Edges["ab"]
+			delete(g.Edges, edge.ID)
+			g.IncomingEdges["b"] = nil
+		}, `outgoing edges of "a" hold edge "ab", which is not in the graph`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := integrityGraph(t)
+			tt.modify(g)
+			if got := errorStrings(g.CheckIntegrity()); got != tt.want {
+				t.Errorf("CheckIntegrity =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadUncheckedKeepsDanglingEdges(t *testing.T) {
+	dump := `{"graph": {"directed": true,
+		"nodes": {"a": {"label": "Node"}},
+		"edges": [{"id": "e", "source": "a", "target": "missing", "relation": "LINK"}]}}`
+	path := filepath.Join(t.TempDir(), "dump.json")
+	if err := os.WriteFile(path, []byte(dump), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(path); err == nil {
+		t.Errorf("Load accepted an edge to a missing node")
+	}
+	g, err := LoadUnchecked(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := g.CheckIntegrity()
+	if len(errs) == 0 || errs[0].Error() != `edge "e": target node "missing" does not exist` {
+		t.Errorf("CheckIntegrity = %v, want the dangling edge reported", errs)
+	}
+}
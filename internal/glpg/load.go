@@ -0,0 +1,481 @@
+package glpg
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Load reads a serialized GLPG from path, or from stdin if path is "-".
+// See Decode for the accepted formats.
+func Load(path string) (*GLPG, error) {
+	data, err := readGraph(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+// LoadUnchecked is like Load, but keeps the graph as written so that
+// CheckIntegrity can report what is wrong with it: edges whose endpoints
+// are missing are kept, and the adjacency lists of lazybox IR JSON are read
+// back instead of being rebuilt from the edges.
+func LoadUnchecked(path string) (*GLPG, error) {
+	data, err := readGraph(path)
+	if err != nil {
+		return nil, err
+	}
+	return decode(data, false)
+}
+
+// readGraph reads path, or stdin if path is "-".
+func readGraph(path string) ([]byte, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	return data, err
+}
+
+// Decode parses a serialized GLPG. It accepts a JSON Graph Format document
+// (as written by --ir, or by other tools: nodes may be an object keyed by ID
+// or a list, and only the first graph of a "graphs" list is read),
+// lazybox's native IR JSON (the GLPG struct as marshaled by jsonify), or
+// GraphML or GEXF as written by --ir-format. GraphML and GEXF only keep
+// each property column's type, so a property holding strings on some nodes
+// and numbers on others is read back as strings.
+func Decode(data []byte) (*GLPG, error) {
+	return decode(data, true)
+}
+
+// decode parses a serialized GLPG. Unless checked, edges are stored as
+// written, without AddEdge's endpoint check; see LoadUnchecked.
+func decode(data []byte, checked bool) (*GLPG, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '<' {
+		return decodeXML(data, checked)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("parsing graph JSON: %w", err)
+	}
+	raw = normalizeNumbers(raw).(map[string]any)
+
+	if graph, ok := raw["graph"].(map[string]any); ok {
+		return decodeJGF(graph, checked)
+	}
+	if graphs, ok := raw["graphs"].([]any); ok {
+		if len(graphs) == 0 {
+			return NewGLPG(), nil
+		}
+		if graph, ok := graphs[0].(map[string]any); ok {
+			return decodeJGF(graph, checked)
+		}
+	}
+	if _, ok := raw["Nodes"]; ok {
+		return decodeNative(raw, checked)
+	}
+	return nil, fmt.Errorf("unrecognized graph format: expected a JSON Graph Format document or lazybox IR JSON")
+}
+
+// decodeJGF builds a GLPG from the "graph" object of a JGF document.
+func decodeJGF(graph map[string]any, checked bool) (*GLPG, error) {
+	g := NewGLPG()
+	switch nodes := graph["nodes"].(type) {
+	case map[string]any:
+		ids := make([]string, 0, len(nodes))
+		for id := range nodes {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			obj, _ := nodes[id].(map[string]any)
+			g.AddNode(jgfNode(id, obj))
+		}
+	case []any:
+		for i, n := range nodes {
+			obj, ok := n.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("node %d is not an object", i)
+			}
+			id, _ := obj["id"].(string)
+			if id == "" {
+				return nil, fmt.Errorf("node %d has no id", i)
+			}
+			g.AddNode(jgfNode(id, obj))
+		}
+	case nil:
+	default:
+		return nil, fmt.Errorf("graph nodes must be an object or a list")
+	}
+
+	edges, _ := graph["edges"].([]any)
+	for i, e := range edges {
+		obj, ok := e.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("edge %d is not an object", i)
+		}
+		edge := &GLPGEdge{Properties: make(GLPGProperty)}
+		edge.ID, _ = obj["id"].(string)
+		edge.SourceID, _ = obj["source"].(string)
+		edge.TargetID, _ = obj["target"].(string)
+		if edge.SourceID == "" || edge.TargetID == "" {
+			return nil, fmt.Errorf("edge %d is missing a source or target", i)
+		}
+		if edge.Label, _ = obj["relation"].(string); edge.Label == "" {
+			edge.Label, _ = obj["label"].(string)
+		}
+		if edge.ID == "" {
+			edge.ID = g.NewEdgeID(edge.SourceID, edge.Label, edge.TargetID)
+		}
+		if meta, ok := obj["metadata"].(map[string]any); ok {
+			edge.Properties = metadataProperties(meta)
+		}
+		if !checked {
+			g.putEdge(edge)
+		} else if err := g.AddEdge(edge); err != nil {
+			return nil, fmt.Errorf("edge %d: %w", i, err)
+		}
+	}
+	return g, nil
+}
+
+// jgfNode converts a JGF node object. Labels come from metadata.labels,
+// falling back to the JGF label.
+func jgfNode(id string, obj map[string]any) *GLPGNode {
+	node := &GLPGNode{ID: id, Properties: make(GLPGProperty)}
+	meta, _ := obj["metadata"].(map[string]any)
+	if labels, ok := meta["labels"].([]any); ok {
+		node.Labels = stringList(labels)
+	} else if label, ok := obj["label"].(string); ok && label != "" {
+		node.Labels = []string{label}
+	}
+	if meta != nil {
+		node.Properties = metadataProperties(meta)
+	}
+	return node
+}
+
+// metadataProperties returns metadata.properties, or for JGF written by other
+// tools, every metadata entry other than labels.
+func metadataProperties(meta map[string]any) GLPGProperty {
+	if props, ok := meta["properties"].(map[string]any); ok {
+		return GLPGProperty(props)
+	}
+	props := make(GLPGProperty)
+	for k, v := range meta {
+		if k != "labels" {
+			props[k] = v
+		}
+	}
+	return props
+}
+
+// decodeNative builds a GLPG from the JSON encoding of the GLPG struct.
+// Adjacency lists are rebuilt from the edges rather than read back, unless
+// the graph is loaded unchecked.
+func decodeNative(raw map[string]any, checked bool) (*GLPG, error) {
+	g := NewGLPG()
+	nodes, _ := raw["Nodes"].(map[string]any)
+	ids := make([]string, 0, len(nodes))
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		obj, ok := nodes[id].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("node %q is not an object", id)
+		}
+		node := &GLPGNode{ID: id, Properties: make(GLPGProperty)}
+		if nodeID, ok := obj["ID"].(string); ok && nodeID != "" {
+			node.ID = nodeID
+		}
+		if labels, ok := obj["Labels"].([]any); ok {
+			node.Labels = stringList(labels)
+		}
+		if props, ok := obj["Properties"].(map[string]any); ok {
+			node.Properties = GLPGProperty(props)
+		}
+		g.AddNode(node)
+	}
+
+	edges, _ := raw["Edges"].(map[string]any)
+	edgeIDs := make([]string, 0, len(edges))
+	for id := range edges {
+		edgeIDs = append(edgeIDs, id)
+	}
+	sort.Strings(edgeIDs)
+	for _, id := range edgeIDs {
+		obj, ok := edges[id].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("edge %q is not an object", id)
+		}
+		edge := nativeEdge(id, obj)
+		if !checked {
+			g.Edges[id] = edge
+		} else if err := g.AddEdge(edge); err != nil {
+			return nil, err
+		}
+	}
+	if !checked {
+		g.OutgoingEdges = nativeAdjacency(g, raw["OutgoingEdges"])
+		g.IncomingEdges = nativeAdjacency(g, raw["IncomingEdges"])
+	}
+	return g, nil
+}
+
+// decodeXML builds a GLPG from a GraphML or GEXF document, telling them
+// apart by the root element.
+func decodeXML(data []byte, checked bool) (*GLPG, error) {
+	var root struct{ XMLName xml.Name }
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parsing graph XML: %w", err)
+	}
+	switch root.XMLName.Local {
+	case "graphml":
+		var doc graphMLDoc
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parsing GraphML: %w", err)
+		}
+		return decodeGraphML(&doc, checked)
+	case "gexf":
+		var doc gexfDoc
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parsing GEXF: %w", err)
+		}
+		return decodeGEXF(&doc, checked)
+	}
+	return nil, fmt.Errorf("unrecognized graph format: expected GraphML or GEXF, found <%s>", root.XMLName.Local)
+}
+
+// decodeGraphML builds a GLPG from a GraphML document. The keys EncodeGraphML
+// reserves for node labels (nlabels) and edge labels (elabel) are read back
+// as labels; every other key becomes a property.
+func decodeGraphML(doc *graphMLDoc, checked bool) (*GLPG, error) {
+	keys := make(map[string]graphMLKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		keys[k.ID] = k
+	}
+	g := NewGLPG()
+	for _, n := range doc.Graph.Nodes {
+		node := &GLPGNode{ID: n.ID, Properties: make(GLPGProperty)}
+		for _, d := range n.Data {
+			if d.Key == "nlabels" {
+				node.Labels = splitLabels(d.Value)
+				continue
+			}
+			k, ok := keys[d.Key]
+			if !ok {
+				k = graphMLKey{AttrName: d.Key}
+			}
+			node.Properties[k.AttrName] = parseAttrValue(d.Value, k.AttrType, isJSONKey(k.ID))
+		}
+		g.AddNode(node)
+	}
+	for i, e := range doc.Graph.Edges {
+		edge := &GLPGEdge{ID: e.ID, SourceID: e.Source, TargetID: e.Target, Properties: make(GLPGProperty)}
+		for _, d := range e.Data {
+			if d.Key == "elabel" {
+				edge.Label = d.Value
+				continue
+			}
+			k, ok := keys[d.Key]
+			if !ok {
+				k = graphMLKey{AttrName: d.Key}
+			}
+			edge.Properties[k.AttrName] = parseAttrValue(d.Value, k.AttrType, isJSONKey(k.ID))
+		}
+		if err := addDecodedEdge(g, edge, checked); err != nil {
+			return nil, fmt.Errorf("edge %d: %w", i, err)
+		}
+	}
+	return g, nil
+}
+
+// decodeGEXF builds a GLPG from a GEXF document. Node labels come from the
+// nlabels attribute EncodeGEXF writes, falling back to the GEXF label.
+func decodeGEXF(doc *gexfDoc, checked bool) (*GLPG, error) {
+	attrs := make(map[string]map[string]gexfAttribute)
+	for _, list := range doc.Graph.Attributes {
+		if attrs[list.Class] == nil {
+			attrs[list.Class] = make(map[string]gexfAttribute)
+		}
+		for _, a := range list.Attributes {
+			attrs[list.Class][a.ID] = a
+		}
+	}
+	properties := func(class string, values *gexfAttValues) (GLPGProperty, []string) {
+		props := make(GLPGProperty)
+		var labels []string
+		if values == nil {
+			return props, nil
+		}
+		for _, v := range values.Values {
+			if class == "node" && v.For == "nlabels" {
+				labels = splitLabels(v.Value)
+				continue
+			}
+			a, ok := attrs[class][v.For]
+			if !ok {
+				a = gexfAttribute{Title: v.For}
+			}
+			props[a.Title] = parseAttrValue(v.Value, a.Type, isJSONKey(a.ID))
+		}
+		return props, labels
+	}
+
+	g := NewGLPG()
+	for _, n := range doc.Graph.Nodes {
+		props, labels := properties("node", n.AttValues)
+		if labels == nil && n.Label != "" {
+			labels = []string{n.Label}
+		}
+		g.AddNode(&GLPGNode{ID: n.ID, Labels: labels, Properties: props})
+	}
+	for i, e := range doc.Graph.Edges {
+		props, _ := properties("edge", e.AttValues)
+		edge := &GLPGEdge{ID: e.ID, SourceID: e.Source, TargetID: e.Target, Label: e.Label, Properties: props}
+		if err := addDecodedEdge(g, edge, checked); err != nil {
+			return nil, fmt.Errorf("edge %d: %w", i, err)
+		}
+	}
+	return g, nil
+}
+
+// addDecodedEdge adds an edge read from a document, giving it an ID if it
+// has none. Unless checked, it is stored as written, like decodeJGF does.
+func addDecodedEdge(g *GLPG, edge *GLPGEdge, checked bool) error {
+	if edge.SourceID == "" || edge.TargetID == "" {
+		return fmt.Errorf("missing a source or target")
+	}
+	if edge.ID == "" {
+		edge.ID = g.NewEdgeID(edge.SourceID, edge.Label, edge.TargetID)
+	}
+	if !checked {
+		g.putEdge(edge)
+		return nil
+	}
+	return g.AddEdge(edge)
+}
+
+// splitLabels reverses labelsValue.
+func splitLabels(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ";")
+}
+
+// parseAttrValue reverses attrKey.value for a GraphML or GEXF value of the
+// given attribute type. Values of a JSON column are decoded as JSON; any
+// other string is kept as written, whatever it looks like.
+func parseAttrValue(s, attrType string, jsonValue bool) any {
+	if jsonValue {
+		dec := json.NewDecoder(strings.NewReader(s))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err == nil && !dec.More() {
+			return normalizeNumbers(v)
+		}
+		return s
+	}
+	switch attrType {
+	case "boolean":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case "long", "integer", "int":
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	case "double", "float":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+// nativeEdge converts an edge object of lazybox IR JSON.
+func nativeEdge(id string, obj map[string]any) *GLPGEdge {
+	edge := &GLPGEdge{ID: id, Properties: make(GLPGProperty)}
+	if edgeID, ok := obj["ID"].(string); ok && edgeID != "" {
+		edge.ID = edgeID
+	}
+	edge.SourceID, _ = obj["SourceID"].(string)
+	edge.TargetID, _ = obj["TargetID"].(string)
+	edge.Label, _ = obj["Label"].(string)
+	if props, ok := obj["Properties"].(map[string]any); ok {
+		edge.Properties = GLPGProperty(props)
+	}
+	return edge
+}
+
+// nativeAdjacency reads back an adjacency map of lazybox IR JSON. Entries
+// are resolved to g's edges by ID; entries for unknown edges are kept as
+// separate edges.
+func nativeAdjacency(g *GLPG, raw any) map[string][]*GLPGEdge {
+	lists := make(map[string][]*GLPGEdge)
+	byNode, _ := raw.(map[string]any)
+	for nodeID, list := range byNode {
+		entries, _ := list.([]any)
+		for _, entry := range entries {
+			obj, _ := entry.(map[string]any)
+			id, _ := obj["ID"].(string)
+			edge := g.Edges[id]
+			if edge == nil {
+				edge = nativeEdge(id, obj)
+			}
+			lists[nodeID] = append(lists[nodeID], edge)
+		}
+	}
+	return lists
+}
+
+// stringList converts a decoded JSON array to strings, skipping non-strings.
+func stringList(values []any) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// normalizeNumbers replaces json.Number values with int64 where the number
+// is integral and float64 otherwise, so decoded properties render the same
+// way as properties ingested from Go IR.
+func normalizeNumbers(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			val[k] = normalizeNumbers(child)
+		}
+		return val
+	case []any:
+		for i, child := range val {
+			val[i] = normalizeNumbers(child)
+		}
+		return val
+	case json.Number:
+		if i, err := strconv.ParseInt(string(val), 10, 64); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	default:
+		return v
+	}
+}
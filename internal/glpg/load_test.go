@@ -0,0 +1,113 @@
+package glpg
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// roundTripGraph builds a graph with every kind of property value the
+// encoders write: strings, integers, floats, booleans, lists and maps, and
+// strings that look like JSON, in a column of their own and next to lists.
+func roundTripGraph(t *testing.T) *GLPG {
+	t.Helper()
+	g := NewGLPG()
+	g.AddNode(&GLPGNode{ID: "pkg", Labels: []string{"PackageInfo"}, Properties: GLPGProperty{"Name": "demo", "Files": int64(2)}})
+	g.AddNode(&GLPGNode{ID: "main", Labels: []string{"FileInfo", "file"}, Properties: GLPGProperty{
+		"Name":     "main.go",
+		"Size":     int64(1200),
+		"Ratio":    0.25,
+		"IsDir":    false,
+		"Keywords": []any{"main", "fmt"},
+		"Metadata": map[string]any{"lines": int64(40), "lang": "go"},
+	}})
+	g.AddNode(&GLPGNode{ID: "readme", Labels: []string{"FileInfo", "file"}, Properties: GLPGProperty{
+		"Name":     "README.md",
+		"Size":     int64(512),
+		"Ratio":    1.5,
+		"IsDir":    false,
+		"Content":  `{"x": 1}`,
+		"Keywords": "[draft]",
+	}})
+	if g.Connect("pkg", "CONTAINS", "main") == nil || g.Connect("pkg", "CONTAINS", "readme") == nil {
+		t.Fatal("connecting test graph")
+	}
+	g.Connect("main", "REFERS_TO", "readme").Properties["Weight"] = 0.5
+	return g
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	for _, format := range IRFormats {
+		t.Run(format, func(t *testing.T) {
+			g := roundTripGraph(t)
+			var buf bytes.Buffer
+			if err := Encode(&buf, g, format, true); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Decode(buf.Bytes())
+			if err != nil {
+				t.Fatalf("Decode: %v\n%s", err, buf.String())
+			}
+
+			if len(got.Nodes) != len(g.Nodes) {
+				t.Fatalf("decoded %d nodes, want %d", len(got.Nodes), len(g.Nodes))
+			}
+			for id, want := range g.Nodes {
+				node := got.GetNode(id)
+				if node == nil {
+					t.Errorf("node %s is missing", id)
+					continue
+				}
+				if !reflect.DeepEqual(node.Labels, want.Labels) {
+					t.Errorf("node %s labels = %v, want %v", id, node.Labels, want.Labels)
+				}
+				if !reflect.DeepEqual(node.Properties, want.Properties) {
+					t.Errorf("node %s properties = %#v, want %#v", id, node.Properties, want.Properties)
+				}
+			}
+
+			if len(got.Edges) != len(g.Edges) {
+				t.Fatalf("decoded %d edges, want %d", len(got.Edges), len(g.Edges))
+			}
+			for id, want := range g.Edges {
+				edge, ok := got.Edges[id]
+				if !ok {
+					t.Errorf("edge %s is missing", id)
+					continue
+				}
+				if edge.SourceID != want.SourceID || edge.Label != want.Label || edge.TargetID != want.TargetID {
+					t.Errorf("edge %s = %s-%s->%s, want %s-%s->%s", id, edge.SourceID, edge.Label, edge.TargetID, want.SourceID, want.Label, want.TargetID)
+				}
+				if !reflect.DeepEqual(edge.Properties, want.Properties) {
+					t.Errorf("edge %s properties = %#v, want %#v", id, edge.Properties, want.Properties)
+				}
+			}
+			if errs := got.CheckIntegrity(); len(errs) != 0 {
+				t.Errorf("decoded graph fails CheckIntegrity: %v", errs)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"not a graph", `{"foo": 1}`, "unrecognized graph format"},
+		{"unknown XML", `<svg></svg>`, "unrecognized graph format"},
+		{"broken XML", `<graphml><graph>`, "parsing"},
+		{"dangling GraphML edge", `<graphml><graph><node id="a"/><edge source="a" target="b"/></graph></graphml>`, "edge 0"},
+		{"dangling GEXF edge", `<gexf><graph><nodes><node id="a"/></nodes><edges><edge source="b" target="a"/></edges></graph></gexf>`, "edge 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Decode error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package glpg
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ConflictPolicy decides what Merge does when two graphs give the same node
+// or edge different values for a property.
+type ConflictPolicy string
+
+const (
+	ConflictFirst ConflictPolicy = "first" // keep the value from the earlier graph
+	ConflictLast  ConflictPolicy = "last"  // take the value from the later graph
+	ConflictList  ConflictPolicy = "list"  // keep every distinct value, in order, as a list
+	ConflictError ConflictPolicy = "error" // fail the merge
+)
+
+// ConflictPolicies lists the accepted policies.
+var ConflictPolicies = []ConflictPolicy{ConflictFirst, ConflictLast, ConflictList, ConflictError}
+
+// MergeRootID is the ID of the synthetic root node Merge adds.
+const MergeRootID = "Merge_root"
+
+// MergePart is one graph to merge and the name of the source it came from.
+type MergePart struct {
+	Source string
+	Graph  *GLPG
+}
+
+// Merge combines graphs into one under a synthetic Merge root. The root has
+// an INCLUDES edge, with a Source property, to every node of each part that
+// has no incoming edges. Since IDs are derived from content, nodes with the
+// same ID are the same entity: they are merged into one node carrying the
+// union of their labels, with conflicting properties resolved by policy.
+// Edges with the same ID and endpoints are merged the same way.
+func Merge(parts []MergePart, policy ConflictPolicy) (*GLPG, error) {
+	if !validPolicy(policy) {
+		return nil, fmt.Errorf("unknown conflict policy %q (expected one of %v)", policy, ConflictPolicies)
+	}
+	merged := NewGLPG()
+	sources := make([]string, 0, len(parts))
+	for _, part := range parts {
+		sources = append(sources, part.Source)
+	}
+	merged.AddNode(&GLPGNode{
+		ID:     MergeRootID,
+		Labels: []string{"Merge"},
+		Properties: GLPGProperty{
+			"Sources": sources,
+			"Policy":  string(policy),
+		},
+	})
+
+	lists := make(conflictLists)
+	for _, part := range parts {
+		if part.Graph == nil {
+			continue
+		}
+		nodes := part.Graph.sortedNodes()
+		for _, node := range nodes {
+			if err := mergeNode(merged, node, policy, lists); err != nil {
+				return nil, fmt.Errorf("%s: %w", part.Source, err)
+			}
+		}
+		// Walk the adjacency lists so each node keeps its outgoing edge order
+		for _, node := range nodes {
+			for _, edge := range part.Graph.GetOutgoingEdges(node.ID) {
+				if err := mergeEdge(merged, edge, policy, lists); err != nil {
+					return nil, fmt.Errorf("%s: %w", part.Source, err)
+				}
+			}
+		}
+		for _, node := range nodes {
+			if len(part.Graph.GetIncomingEdges(node.ID)) == 0 {
+				merged.Connect(MergeRootID, "INCLUDES", node.ID).Properties["Source"] = part.Source
+			}
+		}
+	}
+	return merged, nil
+}
+
+func validPolicy(policy ConflictPolicy) bool {
+	for _, known := range ConflictPolicies {
+		if policy == known {
+			return true
+		}
+	}
+	return false
+}
+
+// conflictLists records, for each merged node ("node ID") and edge ("edge
+// ID"), which properties hold a list of conflicting values built by
+// ConflictList, so a property whose value merely is a list is not mistaken
+// for one.
+type conflictLists map[string]map[string]bool
+
+// of returns the set of conflict-list properties of owner.
+func (c conflictLists) of(owner string) map[string]bool {
+	if c[owner] == nil {
+		c[owner] = make(map[string]bool)
+	}
+	return c[owner]
+}
+
+func mergeNode(g *GLPG, node *GLPGNode, policy ConflictPolicy, lists conflictLists) error {
+	existing := g.GetNode(node.ID)
+	if existing == nil {
+		g.AddNode(&GLPGNode{
+			ID:         node.ID,
+			Labels:     append([]string(nil), node.Labels...),
+			Properties: copyProperties(node.Properties),
+		})
+		return nil
+	}
+	for _, label := range node.Labels {
+		if !hasLabel(existing.Labels, label) {
+			existing.Labels = append(existing.Labels, label)
+		}
+	}
+	if err := mergeProperties(existing.Properties, node.Properties, policy, lists.of("node "+node.ID)); err != nil {
+		return fmt.Errorf("node %s: %w", node.ID, err)
+	}
+	return nil
+}
+
+func mergeEdge(g *GLPG, edge *GLPGEdge, policy ConflictPolicy, lists conflictLists) error {
+	existing := g.GetEdge(edge.ID)
+	if existing != nil && existing.SourceID == edge.SourceID && existing.TargetID == edge.TargetID && existing.Label == edge.Label {
+		if err := mergeProperties(existing.Properties, edge.Properties, policy, lists.of("edge "+edge.ID)); err != nil {
+			return fmt.Errorf("edge %s: %w", edge.ID, err)
+		}
+		return nil
+	}
+	id := edge.ID
+	if existing != nil {
+		// Same ID, different edge (IDs loaded from a dump need not be ours)
+		id = g.NewEdgeID(edge.SourceID, edge.Label, edge.TargetID)
+	}
+	return g.AddEdge(&GLPGEdge{
+		ID:         id,
+		SourceID:   edge.SourceID,
+		TargetID:   edge.TargetID,
+		Label:      edge.Label,
+		Properties: copyProperties(edge.Properties),
+	})
+}
+
+// mergeProperties merges src into dst according to policy. listKeys holds
+// the keys of dst whose values are conflict lists built by ConflictList.
+func mergeProperties(dst, src GLPGProperty, policy ConflictPolicy, listKeys map[string]bool) error {
+	keys := make([]string, 0, len(src))
+	for k := range src {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value := src[k]
+		current, ok := dst[k]
+		if !ok {
+			dst[k] = value
+			continue
+		}
+		if sameValue(current, value) || (listKeys[k] && containsValue(current.([]interface{}), value)) {
+			continue
+		}
+		switch policy {
+		case ConflictFirst:
+		case ConflictLast:
+			dst[k] = value
+		case ConflictList:
+			if listKeys[k] {
+				dst[k] = append(append([]interface{}(nil), current.([]interface{})...), value)
+			} else {
+				dst[k] = []interface{}{current, value}
+				listKeys[k] = true
+			}
+		case ConflictError:
+			return fmt.Errorf("conflicting values for %s: %v and %v", k, current, value)
+		}
+	}
+	return nil
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if sameValue(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameValue reports whether two property values are equal. Scalars compare
+// by value across named and sized types, so an ir.FileType equals the same
+// string and an int equals the same int64 read back from a dump.
+func sameValue(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	ka, kb := scalarClass(a), scalarClass(b)
+	return ka != "" && ka == kb && fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func scalarClass(v interface{}) string {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return ""
+}
+
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
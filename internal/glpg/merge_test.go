@@ -0,0 +1,133 @@
+package glpg
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// mergeParts returns two graphs that share the file node "f": they agree on
+// its Size (as different Go types) and disagree on its Mode.
+func mergeParts(t *testing.T) []MergePart {
+	t.Helper()
+	fs := NewGLPG()
+	fs.AddNode(&GLPGNode{ID: "dir", Labels: []string{"FileInfo"}, Properties: GLPGProperty{"Name": "src"}})
+	fs.AddNode(&GLPGNode{ID: "f", Labels: []string{"FileInfo", "file"}, Properties: GLPGProperty{"Size": int64(10), "Mode": "-rw-r--r--"}})
+	fs.Connect("dir", "CONTAINS", "f")
+
+	pkg := NewGLPG()
+	pkg.AddNode(&GLPGNode{ID: "f", Labels: []string{"FileInfo", "source"}, Properties: GLPGProperty{"Size": 10, "Mode": "-rwxr-xr-x", "Package": "main"}})
+	return []MergePart{{Source: "fs:src", Graph: fs}, {Source: "pkg:src", Graph: pkg}}
+}
+
+func TestMergeConflictPolicies(t *testing.T) {
+	tests := []struct {
+		policy ConflictPolicy
+		mode   interface{}
+	}{
+		{ConflictFirst, "-rw-r--r--"},
+		{ConflictLast, "-rwxr-xr-x"},
+		{ConflictList, []interface{}{"-rw-r--r--", "-rwxr-xr-x"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			g, err := Merge(mergeParts(t), tt.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := g.GetNode("f")
+			if got := f.Properties["Mode"]; !reflect.DeepEqual(got, tt.mode) {
+				t.Errorf("Mode = %#v, want %#v", got, tt.mode)
+			}
+			// Equal values and properties only one side has are not conflicts
+			if f.Properties["Size"] != int64(10) || f.Properties["Package"] != "main" {
+				t.Errorf("properties = %v, want the first Size and pkg's Package", f.Properties)
+			}
+			if got := strings.Join(f.Labels, " "); got != "FileInfo file source" {
+				t.Errorf("labels = %q, want the union of both parts' labels", got)
+			}
+		})
+	}
+}
+
+// TestMergeListValues checks that the list policy wraps list-valued
+// properties instead of taking them for its own list of conflicts.
+func TestMergeListValues(t *testing.T) {
+	part := func(source string, files ...interface{}) MergePart {
+		g := NewGLPG()
+		g.AddNode(&GLPGNode{ID: "p", Labels: []string{"APIInfo"}, Properties: GLPGProperty{"Files": files}})
+		return MergePart{Source: source, Graph: g}
+	}
+	tests := []struct {
+		name  string
+		parts []MergePart
+		want  interface{}
+	}{
+		{"two lists", []MergePart{part("a", "a.go", "b.go"), part("b", "a.go", "c.go")},
+			[]interface{}{[]interface{}{"a.go", "b.go"}, []interface{}{"a.go", "c.go"}}},
+		{"three lists", []MergePart{part("a", "a.go"), part("b", "b.go"), part("c", "c.go")},
+			[]interface{}{[]interface{}{"a.go"}, []interface{}{"b.go"}, []interface{}{"c.go"}}},
+		{"repeated list", []MergePart{part("a", "a.go"), part("b", "b.go"), part("c", "a.go")},
+			[]interface{}{[]interface{}{"a.go"}, []interface{}{"b.go"}}},
+		{"equal lists", []MergePart{part("a", "a.go", "b.go"), part("b", "a.go", "b.go")},
+			[]interface{}{"a.go", "b.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := Merge(tt.parts, ConflictList)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := g.GetNode("p").Properties["Files"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Files = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeConflictError(t *testing.T) {
+	_, err := Merge(mergeParts(t), ConflictError)
+	if err == nil || !strings.Contains(err.Error(), "pkg:src: node f: conflicting values for Mode") {
+		t.Errorf("Merge error = %v, want a Mode conflict on node f", err)
+	}
+
+	// Without a disagreement the error policy merges cleanly
+	parts := mergeParts(t)
+	parts[1].Graph.GetNode("f").Properties["Mode"] = "-rw-r--r--"
+	if _, err := Merge(parts, ConflictError); err != nil {
+		t.Errorf("Merge of agreeing graphs: %v", err)
+	}
+
+	if _, err := Merge(mergeParts(t), "newest"); err == nil {
+		t.Errorf("Merge accepted an unknown policy")
+	}
+}
+
+func TestMergeRoot(t *testing.T) {
+	g, err := Merge(mergeParts(t), ConflictFirst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := g.CheckIntegrity(); len(errs) != 0 {
+		t.Fatalf("merged graph fails CheckIntegrity: %v", errs)
+	}
+
+	// Each part's roots hang off the merge root: dir for fs, and f for pkg
+	// since nothing in pkg points at it
+	var included []string
+	for _, edge := range g.GetOutgoingEdges(MergeRootID) {
+		included = append(included, edge.Properties["Source"].(string)+"="+edge.TargetID)
+	}
+	sort.Strings(included)
+	if got, want := strings.Join(included, " "), "fs:src=dir pkg:src=f"; got != want {
+		t.Errorf("INCLUDES edges = %q, want %q", got, want)
+	}
+	if got := len(g.GetIncomingEdges("f")); got != 2 {
+		t.Errorf("f has %d incoming edges, want CONTAINS and INCLUDES", got)
+	}
+	root := g.GetNode(MergeRootID).Properties
+	if !reflect.DeepEqual(root["Sources"], []string{"fs:src", "pkg:src"}) || root["Policy"] != "first" {
+		t.Errorf("merge root properties = %v", root)
+	}
+}
@@ -0,0 +1,90 @@
+package glpg
+
+import (
+	"fmt"
+	"strings"
+
+	"lazybox/internal/ir"
+)
+
+// PackageInfoToGLPG converts an ir.PackageInfo into a package node that
+// CONTAINS the scanned tree. File contents are split out of the file nodes
+// into FileContent nodes linked with HAS_CONTENT, so modes can render or
+// drop them independently of the file metadata.
+func PackageInfoToGLPG(pkgInfo *ir.PackageInfo, g *GLPG) error {
+	if pkgInfo == nil {
+		return nil
+	}
+
+	pkgID := fmt.Sprintf("PackageInfo_%s", sanitizeIDPart(pkgInfo.Path))
+	g.AddNode(&GLPGNode{
+		ID:     pkgID,
+		Labels: []string{"PackageInfo"},
+		Properties: GLPGProperty{
+			"Name":            pkgInfo.Name,
+			"Path":            pkgInfo.Path,
+			"FileCount":       pkgInfo.FileCount,
+			"TextFileCount":   pkgInfo.TextFileCount,
+			"BinaryFileCount": pkgInfo.BinaryFileCount,
+			"TotalSize":       pkgInfo.TotalSize,
+		},
+	})
+
+	ids := make(map[*ir.FileInfo]string)
+	if err := fileInfoToGLPG(pkgInfo.Root, g, pkgID, "CONTAINS", ids); err != nil {
+		return err
+	}
+	addContentNodes(pkgInfo.Root, g, ids)
+	return nil
+}
+
+// addContentNodes moves file contents from the FileInfo nodes created by
+// FileInfoToGLPG into dedicated FileContent nodes. ids maps each entry to
+// its node ID.
+func addContentNodes(fi *ir.FileInfo, g *GLPG, ids map[*ir.FileInfo]string) {
+	if fi == nil {
+		return
+	}
+	for _, child := range fi.Children {
+		addContentNodes(child, g, ids)
+	}
+	if fi.Content == nil {
+		return
+	}
+
+	fileID := ids[fi]
+	fileNode := g.GetNode(fileID)
+	if fileNode == nil {
+		return
+	}
+	delete(fileNode.Properties, "Content")
+
+	// Name the content after the file node, which already has a unique ID
+	contentNode := FileContentNode(fi)
+	contentNode.ID = "FileContent_" + strings.TrimPrefix(fileID, "FileInfo_")
+	g.AddUniqueNode(contentNode)
+	g.Connect(fileID, "HAS_CONTENT", contentNode.ID)
+}
+
+// FileContentNode builds the FileContent node holding fi's full content.
+// fi.Content must not be nil.
+func FileContentNode(fi *ir.FileInfo) *GLPGNode {
+	props := GLPGProperty{
+		"Content": *fi.Content,
+		"Size":    len(*fi.Content),
+	}
+	if fi.Truncated {
+		props["Truncated"] = true
+	}
+	if fi.TextAnalysis != nil {
+		props["LineCount"] = fi.TextAnalysis.LineCount
+		if fi.TextAnalysis.MimeType != "" {
+			props["MimeType"] = fi.TextAnalysis.MimeType
+		}
+	}
+	return &GLPGNode{
+		ID:         fmt.Sprintf("FileContent_%s", sanitizeIDPart(fi.Path)),
+		Labels:     []string{"FileContent"},
+		Properties: props,
+	}
+}
@@ -0,0 +1,573 @@
+package glpg
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported formats for serializing a GLPG with the --ir flag.
+const (
+	FormatJGF     = "jgf"
+	FormatGraphML = "graphml"
+	FormatGEXF    = "gexf"
+)
+
+// IRFormats lists the formats accepted by Encode, the default first.
+var IRFormats = []string{FormatJGF, FormatGraphML, FormatGEXF}
+
+// Encode writes the graph to w in the given format. When indent is false the
+// output is written without insignificant whitespace.
+func Encode(w io.Writer, g *GLPG, format string, indent bool) error {
+	switch strings.ToLower(format) {
+	case "", FormatJGF, "json":
+		return EncodeJGF(w, g, indent)
+	case FormatGraphML:
+		return EncodeGraphML(w, g, indent)
+	case FormatGEXF:
+		return EncodeGEXF(w, g, indent)
+	default:
+		return fmt.Errorf("unknown IR format %q (expected one of %s)", format, strings.Join(IRFormats, ", "))
+	}
+}
+
+// sortedNodes returns the graph's nodes ordered by ID.
+func (g *GLPG) sortedNodes() []*GLPGNode {
+	nodes := make([]*GLPGNode, 0, len(g.Nodes))
+	for _, node := range g.Nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+// sortedEdges returns the graph's edges ordered by source, target, label and ID.
+func (g *GLPG) sortedEdges() []*GLPGEdge {
+	edges := make([]*GLPGEdge, 0, len(g.Edges))
+	for _, edge := range g.Edges {
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.SourceID != b.SourceID {
+			return a.SourceID < b.SourceID
+		}
+		if a.TargetID != b.TargetID {
+			return a.TargetID < b.TargetID
+		}
+		if a.Label != b.Label {
+			return a.Label < b.Label
+		}
+		return a.ID < b.ID
+	})
+	return edges
+}
+
+// JSON Graph Format (https://jsongraphformat.info, version 2).
+//
+// Nodes are keyed by ID. The first node label is used as the JGF label and the
+// full label list and properties are kept in metadata; edge labels become the
+// JGF relation:
+//
+//	{"graph": {
+//	  "type": "glpg", "directed": true,
+//	  "metadata": {"generator": "lazybox"},
+//	  "nodes": {"<id>": {"label": "<first label>", "metadata": {"labels": [...], "properties": {...}}}},
+//	  "edges": [{"id": "<id>", "source": "<id>", "target": "<id>", "relation": "<label>", "directed": true,
+//	             "metadata": {"properties": {...}}}]
+//	}}
+
+// JGFDocument is the top-level JSON Graph Format object.
+type JGFDocument struct {
+	Graph JGFGraph `json:"graph"`
+}
+
+// JGFGraph is a single graph in a JGF document.
+type JGFGraph struct {
+	ID       string             `json:"id,omitempty"`
+	Type     string             `json:"type,omitempty"`
+	Label    string             `json:"label,omitempty"`
+	Directed bool               `json:"directed"`
+	Metadata map[string]any     `json:"metadata,omitempty"`
+	Nodes    map[string]JGFNode `json:"nodes"`
+	Edges    []JGFEdge          `json:"edges"`
+}
+
+// JGFNode is a node in a JGF graph.
+type JGFNode struct {
+	Label    string          `json:"label,omitempty"`
+	Metadata JGFNodeMetadata `json:"metadata"`
+}
+
+// JGFNodeMetadata holds the GLPG labels and properties of a node.
+type JGFNodeMetadata struct {
+	Labels     []string     `json:"labels"`
+	Properties GLPGProperty `json:"properties,omitempty"`
+}
+
+// JGFEdge is an edge in a JGF graph.
+type JGFEdge struct {
+	ID       string          `json:"id,omitempty"`
+	Source   string          `json:"source"`
+	Target   string          `json:"target"`
+	Relation string          `json:"relation,omitempty"`
+	Directed bool            `json:"directed"`
+	Metadata JGFEdgeMetadata `json:"metadata"`
+}
+
+// JGFEdgeMetadata holds the GLPG properties of an edge.
+type JGFEdgeMetadata struct {
+	Properties GLPGProperty `json:"properties,omitempty"`
+}
+
+// ToJGF converts the graph to a JSON Graph Format document.
+func ToJGF(g *GLPG) *JGFDocument {
+	doc := &JGFDocument{Graph: JGFGraph{
+		Type:     "glpg",
+		Directed: true,
+		Metadata: map[string]any{"generator": "lazybox"},
+		Nodes:    make(map[string]JGFNode, len(g.Nodes)),
+		Edges:    make([]JGFEdge, 0, len(g.Edges)),
+	}}
+	for _, node := range g.Nodes {
+		jn := JGFNode{Metadata: JGFNodeMetadata{Labels: node.Labels, Properties: node.Properties}}
+		if jn.Metadata.Labels == nil {
+			jn.Metadata.Labels = []string{}
+		}
+		if len(node.Labels) > 0 {
+			jn.Label = node.Labels[0]
+		}
+		doc.Graph.Nodes[node.ID] = jn
+	}
+	for _, edge := range g.sortedEdges() {
+		doc.Graph.Edges = append(doc.Graph.Edges, JGFEdge{
+			ID:       edge.ID,
+			Source:   edge.SourceID,
+			Target:   edge.TargetID,
+			Relation: edge.Label,
+			Directed: true,
+			Metadata: JGFEdgeMetadata{Properties: edge.Properties},
+		})
+	}
+	return doc
+}
+
+// EncodeJGF writes the graph as a JSON Graph Format document.
+func EncodeJGF(w io.Writer, g *GLPG, indent bool) error {
+	enc := json.NewEncoder(w)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(ToJGF(g))
+}
+
+// attrKey describes one property column shared by GraphML and GEXF output.
+type attrKey struct {
+	ID   string
+	Name string
+	Type string // "boolean", "long", "double" or "string"
+	JSON bool   // values are JSON-encoded; see jsonKeyID
+}
+
+// jsonKeyInfix follows the "n" or "e" prefix in the ID of a column holding
+// lists, maps or values of mixed kinds. Such columns are typed as strings
+// and every value in them, strings included, is JSON-encoded, so a reader
+// decodes exactly these columns and leaves strings that merely look like
+// JSON alone.
+const jsonKeyInfix = "json"
+
+// isJSONKey reports whether a key ID names a JSON-encoded column.
+func isJSONKey(id string) bool {
+	return len(id) > 1 && strings.HasPrefix(id[1:], jsonKeyInfix)
+}
+
+// value formats a property value for this column, reporting false for nil.
+func (k attrKey) value(v any) (string, bool) {
+	if !k.JSON {
+		return attrValue(v)
+	}
+	if _, ok := attrValue(v); !ok {
+		return "", false
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return attrValue(v)
+	}
+	return string(data), true
+}
+
+// collectAttrKeys scans the given property maps and returns one key per
+// property name, typed by the narrowest type that fits every value.
+// The reserved "labels" key always comes first.
+func collectAttrKeys(prefix string, props []GLPGProperty, withLabels bool) []attrKey {
+	types := make(map[string]string)
+	for _, p := range props {
+		for name, value := range p {
+			t := attrType(value)
+			if t == "" {
+				continue
+			}
+			types[name] = widenAttrType(types[name], t)
+		}
+	}
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var keys []attrKey
+	if withLabels {
+		keys = append(keys, attrKey{ID: prefix + "labels", Name: "labels", Type: "string"})
+	}
+	for i, name := range names {
+		if types[name] == "json" {
+			keys = append(keys, attrKey{ID: prefix + jsonKeyInfix + strconv.Itoa(i), Name: name, Type: "string", JSON: true})
+			continue
+		}
+		keys = append(keys, attrKey{ID: prefix + strconv.Itoa(i), Name: name, Type: types[name]})
+	}
+	return keys
+}
+
+// attrType returns the attribute type of a property value, or "" for nil.
+// Lists, maps and structs other than times are "json".
+func attrType(value any) string {
+	v := reflect.ValueOf(value)
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return ""
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "long"
+	case reflect.Float32, reflect.Float64:
+		if f := v.Float(); f == float64(int64(f)) {
+			return "long"
+		}
+		return "double"
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return "json"
+	case reflect.Struct:
+		if _, ok := v.Interface().(time.Time); !ok {
+			return "json"
+		}
+	}
+	return "string"
+}
+
+// widenAttrType returns the narrowest type that can hold both a and b.
+// Only a JSON column can hold composite values next to anything else.
+func widenAttrType(a, b string) string {
+	switch {
+	case a == "" || a == b:
+		return b
+	case a == "json" || b == "json":
+		return "json"
+	case (a == "long" && b == "double") || (a == "double" && b == "long"):
+		return "double"
+	default:
+		return "string"
+	}
+}
+
+// attrValue formats a property value as an attribute string. Scalars are
+// printed as-is; times use RFC 3339 and everything else is encoded as JSON,
+// which only happens in a JSON column (see attrKey.value).
+func attrValue(value any) (string, bool) {
+	v := reflect.ValueOf(value)
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return "", false
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339Nano), true
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), true
+	case reflect.String:
+		return v.String(), true
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return fmt.Sprint(v.Interface()), true
+	}
+	return string(data), true
+}
+
+// labelsValue joins node labels for the "labels" attribute.
+func labelsValue(labels []string) string {
+	return strings.Join(labels, ";")
+}
+
+// nodeProperties and edgeProperties collect property maps for collectAttrKeys.
+func nodeProperties(nodes []*GLPGNode) []GLPGProperty {
+	props := make([]GLPGProperty, len(nodes))
+	for i, node := range nodes {
+		props[i] = node.Properties
+	}
+	return props
+}
+
+func edgeProperties(edges []*GLPGEdge) []GLPGProperty {
+	props := make([]GLPGProperty, len(edges))
+	for i, edge := range edges {
+		props[i] = edge.Properties
+	}
+	return props
+}
+
+// encodeXML writes the XML header followed by v.
+func encodeXML(w io.Writer, v any, indent bool) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if indent {
+		enc.Indent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// GraphML (http://graphml.graphdrawing.org).
+//
+// Every node and edge property becomes a <key>; node labels are joined with
+// ";" in the "labels" key and edge labels are stored in the "label" key.
+// Lists and maps are JSON-encoded in string keys marked by jsonKeyInfix.
+
+type graphMLDoc struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr,omitempty"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// EncodeGraphML writes the graph as a GraphML document.
+func EncodeGraphML(w io.Writer, g *GLPG, indent bool) error {
+	nodes := g.sortedNodes()
+	edges := g.sortedEdges()
+	nodeKeys := collectAttrKeys("n", nodeProperties(nodes), true)
+	edgeKeys := collectAttrKeys("e", edgeProperties(edges), true)
+	// The edge "labels" key holds the single edge label
+	edgeKeys[0].ID, edgeKeys[0].Name = "elabel", "label"
+
+	doc := graphMLDoc{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Graph: graphMLGraph{ID: "G", EdgeDefault: "directed"},
+	}
+	for _, k := range nodeKeys {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: k.ID, For: "node", AttrName: k.Name, AttrType: k.Type})
+	}
+	for _, k := range edgeKeys {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: k.ID, For: "edge", AttrName: k.Name, AttrType: k.Type})
+	}
+
+	for _, node := range nodes {
+		gn := graphMLNode{ID: node.ID}
+		gn.Data = append(gn.Data, graphMLData{Key: nodeKeys[0].ID, Value: labelsValue(node.Labels)})
+		for _, k := range nodeKeys[1:] {
+			if value, ok := k.value(node.Properties[k.Name]); ok {
+				gn.Data = append(gn.Data, graphMLData{Key: k.ID, Value: value})
+			}
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gn)
+	}
+	for _, edge := range edges {
+		ge := graphMLEdge{ID: edge.ID, Source: edge.SourceID, Target: edge.TargetID}
+		ge.Data = append(ge.Data, graphMLData{Key: edgeKeys[0].ID, Value: edge.Label})
+		for _, k := range edgeKeys[1:] {
+			if value, ok := k.value(edge.Properties[k.Name]); ok {
+				ge.Data = append(ge.Data, graphMLData{Key: k.ID, Value: value})
+			}
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, ge)
+	}
+	return encodeXML(w, doc, indent)
+}
+
+// GEXF 1.3 (https://gexf.net).
+//
+// Node labels are joined with ";" in the "labels" attribute and the first
+// label is used as the GEXF node label; edge labels map to the GEXF edge label.
+// Composite values are stored as in GraphML.
+
+type gexfDoc struct {
+	XMLName xml.Name  `xml:"gexf"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Meta    gexfMeta  `xml:"meta"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfMeta struct {
+	Creator     string `xml:"creator"`
+	Description string `xml:"description"`
+}
+
+type gexfGraph struct {
+	DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+	Mode            string           `xml:"mode,attr"`
+	Attributes      []gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode       `xml:"nodes>node"`
+	Edges           []gexfEdge       `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues *gexfAttValues `xml:"attvalues,omitempty"`
+}
+
+type gexfEdge struct {
+	ID        string         `xml:"id,attr"`
+	Source    string         `xml:"source,attr"`
+	Target    string         `xml:"target,attr"`
+	Label     string         `xml:"label,attr,omitempty"`
+	AttValues *gexfAttValues `xml:"attvalues,omitempty"`
+}
+
+type gexfAttValues struct {
+	Values []gexfAttValue `xml:"attvalue"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// EncodeGEXF writes the graph as a GEXF document.
+func EncodeGEXF(w io.Writer, g *GLPG, indent bool) error {
+	nodes := g.sortedNodes()
+	edges := g.sortedEdges()
+	nodeKeys := collectAttrKeys("n", nodeProperties(nodes), true)
+	edgeKeys := collectAttrKeys("e", edgeProperties(edges), false)
+
+	doc := gexfDoc{
+		Xmlns:   "http://gexf.net/1.3",
+		Version: "1.3",
+		Meta:    gexfMeta{Creator: "lazybox", Description: "Generalized Labeled Property Graph"},
+		Graph:   gexfGraph{DefaultEdgeType: "directed", Mode: "static"},
+	}
+	doc.Graph.Attributes = append(doc.Graph.Attributes, gexfAttributeList("node", nodeKeys))
+	if len(edgeKeys) > 0 {
+		doc.Graph.Attributes = append(doc.Graph.Attributes, gexfAttributeList("edge", edgeKeys))
+	}
+
+	for _, node := range nodes {
+		gn := gexfNode{ID: node.ID, Label: node.ID}
+		if len(node.Labels) > 0 {
+			gn.Label = node.Labels[0]
+		}
+		gn.AttValues = gexfValues(nodeKeys, node.Properties, labelsValue(node.Labels))
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gn)
+	}
+	for i, edge := range edges {
+		ge := gexfEdge{ID: edge.ID, Source: edge.SourceID, Target: edge.TargetID, Label: edge.Label}
+		if ge.ID == "" {
+			ge.ID = strconv.Itoa(i)
+		}
+		ge.AttValues = gexfValues(edgeKeys, edge.Properties, "")
+		doc.Graph.Edges = append(doc.Graph.Edges, ge)
+	}
+	return encodeXML(w, doc, indent)
+}
+
+// gexfAttributeList converts attribute keys to a GEXF <attributes> block.
+func gexfAttributeList(class string, keys []attrKey) gexfAttributes {
+	attrs := gexfAttributes{Class: class}
+	for _, k := range keys {
+		attrs.Attributes = append(attrs.Attributes, gexfAttribute{ID: k.ID, Title: k.Name, Type: k.Type})
+	}
+	return attrs
+}
+
+// gexfValues returns the <attvalues> block for one node or edge, or nil if it
+// has no values. The reserved node "labels" key is given the labels value.
+func gexfValues(keys []attrKey, props GLPGProperty, labels string) *gexfAttValues {
+	values := &gexfAttValues{}
+	for _, k := range keys {
+		if k.ID == "nlabels" {
+			values.Values = append(values.Values, gexfAttValue{For: k.ID, Value: labels})
+			continue
+		}
+		if value, ok := k.value(props[k.Name]); ok {
+			values.Values = append(values.Values, gexfAttValue{For: k.ID, Value: value})
+		}
+	}
+	if len(values.Values) == 0 {
+		return nil
+	}
+	return values
+}
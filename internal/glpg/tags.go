@@ -0,0 +1,103 @@
+package glpg
+
+import (
+	"reflect"
+	"strings"
+)
+
+// The generic ingestor reads `glpg:"..."` struct tags, so IR types can shape
+// their graph without a dedicated ingestor. A tag is a comma-separated list
+// of options; a word that is not an option names the property or edge:
+//
+//	Name     string         `glpg:"Name"`            // property key
+//	Path     string         `glpg:"Path,id"`         // the node's ID is Type_<Path>
+//	Type     FileType       `glpg:"Type,label"`      // also added as a node label
+//	Children []*FileInfo    `glpg:"edge=CONTAINS"`   // child nodes, linked by CONTAINS
+//	Entries  []*Entry       `glpg:"unordered"`       // child nodes without an INDEX
+//	Stats    Stats          `glpg:"inline"`          // fields merged into this node
+//	Pos      Position       `glpg:"prop"`            // a map property, not a node
+//	Owner    string         `glpg:"Owner,omitempty"` // left out when empty
+//	Meta     map[string]any `glpg:"Meta,flatten"`    // one Meta_<key> property per entry
+//	Cache    *Cache         `glpg:"skip"`            // left out (so is "-")
+//
+// node forces a struct or slice field to become child nodes (the default for
+// both), prop forces it to become a property, and label=X labels the child
+// nodes X instead of with their type name. unordered leaves the INDEX out
+// of the edges to a slice's elements, for collections whose order means
+// nothing (directory entries, say), so inserting one element does not
+// change the edges to all the ones after it. flatten turns a struct or map
+// into one property per entry, nested ones included, instead of a nested
+// value or child nodes; with omitempty, zero entries are left out. Fields
+// without a glpg name use their Go name, never their json name, so a field
+// has the same key whichever struct it is ingested through; json:"-" fields
+// are skipped unless they have a glpg tag.
+
+// fieldTag is a parsed glpg struct tag.
+type fieldTag struct {
+	Name    string // Property key or edge label
+	Skip    bool
+	Node    bool
+	Prop    bool
+	Edge    string // Edge label for child nodes (implies Node)
+	ID      bool
+	Inline  bool
+	Label   string // Label for child nodes
+	AsLabel bool   // Add the field's value as a node label
+	Omit    bool   // Leave the property out when its value is empty
+	Flatten bool   // Add a struct or map as Name_<key> properties
+	NoIndex bool   // Leave the INDEX out of edges to slice elements
+}
+
+// parseFieldTag reads the glpg tag of field, filling in the name from the
+// field name.
+func parseFieldTag(field reflect.StructField) fieldTag {
+	var tag fieldTag
+	glpgTag, hasTag := field.Tag.Lookup("glpg")
+	if glpgTag == "-" {
+		return fieldTag{Skip: true}
+	}
+	if hasTag {
+		for _, opt := range strings.Split(glpgTag, ",") {
+			opt = strings.TrimSpace(opt)
+			key, value, hasValue := strings.Cut(opt, "=")
+			switch {
+			case opt == "":
+			case opt == "skip":
+				tag.Skip = true
+			case opt == "node":
+				tag.Node = true
+			case opt == "prop":
+				tag.Prop = true
+			case opt == "id":
+				tag.ID = true
+			case opt == "inline":
+				tag.Inline = true
+			case opt == "label":
+				tag.AsLabel = true
+			case opt == "omitempty":
+				tag.Omit = true
+			case opt == "flatten":
+				tag.Flatten = true
+			case opt == "unordered":
+				tag.NoIndex = true
+			case key == "edge" && hasValue:
+				tag.Edge = value
+				tag.Node = true
+			case key == "label" && hasValue:
+				tag.Label = value
+			default:
+				tag.Name = opt
+			}
+		}
+	}
+	if !hasTag && field.Tag.Get("json") == "-" {
+		tag.Skip = true
+	}
+	if tag.Name == "" {
+		tag.Name = field.Name
+	}
+	if tag.Edge == "" {
+		tag.Edge = tag.Name
+	}
+	return tag
+}
@@ -26,28 +26,44 @@ const (
 )
 
 // FileInfo represents the intermediate representation for a file or directory.
+// Its glpg tags shape its graph node, which glpg.FileInfoNode builds the same
+// way for the pkg target and for streamed output.
 type FileInfo struct {
-	Name             string                 `json:"name"`
-	Path             string                 `json:"path"`
-	AbsolutePath     string                 `json:"absolute_path"`
-	Type             FileType               `json:"type"`
-	IsDir            bool                   `json:"is_dir"`
-	IsSymlink        bool                   `json:"is_symlink,omitempty"`
-	SymlinkTarget    string                 `json:"symlink_target,omitempty"`
-	Size             int64                  `json:"size"`
-	Mode             string                 `json:"mode,omitempty"` // Store as string from os.FileMode.String()
-	ModTime          time.Time              `json:"mod_time,omitempty"`
-	CreateTime       time.Time              `json:"create_time,omitempty"` // OS-dependent
-	Owner            string                 `json:"owner,omitempty"`       // OS-dependent
-	Group            string                 `json:"group,omitempty"`       // OS-dependent
-	Extension        string                 `json:"extension,omitempty"`
-	Content          *string                `json:"content,omitempty"` // Pointer to allow nil for non-text or large files not read
-	Error            string                 `json:"error,omitempty"`
-	Children         []*FileInfo            `json:"children,omitempty"`           // For directories
-	TextAnalysis     *TextInfo              `json:"text_analysis,omitempty"`      // For text files
-	GitRemoteURL     string                 `json:"git_remote_url,omitempty"`     // For git repositories
-	GitCurrentBranch string                 `json:"git_current_branch,omitempty"` // For git repositories
-	Metadata         map[string]interface{} `json:"metadata,omitempty"`           // For any other specific metadata
+	Name             string                 `json:"name" glpg:"Name"`
+	Path             string                 `json:"path" glpg:"Path,id"`
+	AbsolutePath     string                 `json:"absolute_path" glpg:"AbsolutePath"`
+	Type             FileType               `json:"type" glpg:"Type,label"`
+	IsDir            bool                   `json:"is_dir" glpg:"IsDir"`
+	IsSymlink        bool                   `json:"is_symlink,omitempty" glpg:"IsSymlink"`
+	SymlinkTarget    string                 `json:"symlink_target,omitempty" glpg:"SymlinkTarget,omitempty"`
+	Size             int64                  `json:"size" glpg:"Size"`
+	Mode             string                 `json:"mode,omitempty" glpg:"Mode"` // Store as string from os.FileMode.String()
+	ModTime          time.Time              `json:"mod_time,omitempty" glpg:"ModTime"`
+	CreateTime       time.Time              `json:"create_time,omitempty" glpg:"CreateTime,omitempty"` // OS-dependent
+	Owner            string                 `json:"owner,omitempty" glpg:"Owner,omitempty"`            // OS-dependent
+	Group            string                 `json:"group,omitempty" glpg:"Group,omitempty"`            // OS-dependent
+	Extension        string                 `json:"extension,omitempty" glpg:"Extension,omitempty"`
+	Content          *string                `json:"content,omitempty" glpg:"Content,omitempty"` // Pointer to allow nil for non-text or large files not read
+	Error            string                 `json:"error,omitempty" glpg:"Error,omitempty"`
+	Truncated        bool                   `json:"truncated,omitempty" glpg:"Truncated,omitempty"`                 // Children or content were cut short by a scan limit
+	SkippedEntries   int                    `json:"skipped_entries,omitempty" glpg:"SkippedEntries,omitempty"`      // Directory entries left out because of a scan limit
+	Children         []*FileInfo            `json:"children,omitempty" glpg:"edge=CONTAINS,unordered"`              // For directories
+	TextAnalysis     *TextInfo              `json:"text_analysis,omitempty" glpg:"inline"`                          // For text files
+	GitRemoteURL     string                 `json:"git_remote_url,omitempty" glpg:"GitRemoteURL,omitempty"`         // For git repositories
+	GitCurrentBranch string                 `json:"git_current_branch,omitempty" glpg:"GitCurrentBranch,omitempty"` // For git repositories
+	Metadata         map[string]interface{} `json:"metadata,omitempty" glpg:"Metadata,flatten"`                     // For any other specific metadata
+}
+
+// PackageInfo is the intermediate representation produced by the pkg crawler:
+// a scanned tree whose text files carry their content and text analysis.
+type PackageInfo struct {
+	Name            string    `json:"name"`
+	Path            string    `json:"path"`
+	Root            *FileInfo `json:"root"`
+	FileCount       int       `json:"file_count"`
+	TextFileCount   int       `json:"text_file_count"`
+	BinaryFileCount int       `json:"binary_file_count"`
+	TotalSize       int64     `json:"total_size"`
 }
 
 // KeywordFrequency stores a keyword and its count.
@@ -69,20 +85,22 @@ type SentimentAnalysis struct {
 	Subjectivity float64 `json:"subjectivity,omitempty"` // e.g., 0 (objective) to 1 (subjective)
 }
 
-// TextInfo holds detailed analysis of text content.
+// TextInfo holds detailed analysis of text content. Its graph properties
+// are the same whether it is ingested on its own (the text target) or
+// inlined into the node of the file it analyzes.
 type TextInfo struct {
 	LineCount             int                `json:"line_count"`
 	WordCount             int                `json:"word_count"`
 	CharCount             int                `json:"char_count"`
-	Keywords              []KeywordFrequency `json:"keywords,omitempty"`
-	DetectedLanguage      string             `json:"detected_language,omitempty"`
-	Readability           *ReadabilityScores `json:"readability,omitempty"`
-	Sentiment             *SentimentAnalysis `json:"sentiment,omitempty"`
-	IsBinary              bool               `json:"is_binary,omitempty"`
-	MimeType              string             `json:"mime_type,omitempty"`
-	Encoding              string             `json:"encoding,omitempty"`
-	AverageWordLength     float64            `json:"average_word_length,omitempty"`
-	AverageSentenceLength float64            `json:"average_sentence_length,omitempty"`
+	Keywords              []KeywordFrequency `json:"keywords,omitempty" glpg:"prop,omitempty"`
+	DetectedLanguage      string             `json:"detected_language,omitempty" glpg:"omitempty"`
+	Readability           *ReadabilityScores `json:"readability,omitempty" glpg:"flatten,omitempty"`
+	Sentiment             *SentimentAnalysis `json:"sentiment,omitempty" glpg:"flatten,omitempty"`
+	IsBinary              bool               `json:"is_binary,omitempty" glpg:"omitempty"`
+	MimeType              string             `json:"mime_type,omitempty" glpg:"omitempty"`
+	Encoding              string             `json:"encoding,omitempty" glpg:"omitempty"`
+	AverageWordLength     float64            `json:"average_word_length,omitempty" glpg:"omitempty"`
+	AverageSentenceLength float64            `json:"average_sentence_length,omitempty" glpg:"omitempty"`
 }
 
 // NewFileInfo creates a basic FileInfo struct.
@@ -118,3 +136,91 @@ func (fi *FileInfo) SetContent(content string) {
 func (fi *FileInfo) AddChild(child *FileInfo) {
 	fi.Children = append(fi.Children, child)
 }
+
+// Position records where a declaration appears in a source file.
+type Position struct {
+	Line      int `json:"line"`
+	Column    int `json:"column"`
+	EndLine   int `json:"end_line,omitempty"`
+	EndColumn int `json:"end_column,omitempty"`
+}
+
+// CodeInfo represents the intermediate representation for a parsed source file.
+type CodeInfo struct {
+	Name     string        `json:"name"`
+	Path     string        `json:"path"`
+	Language string        `json:"language"`
+	Package  string        `json:"package"`
+	Doc      string        `json:"doc,omitempty"` // Package doc comment
+	Imports  []*ImportInfo `json:"imports,omitempty"`
+	Funcs    []*FuncInfo   `json:"funcs,omitempty"` // Functions and methods
+	Types    []*TypeInfo   `json:"types,omitempty"`
+	Consts   []*ValueInfo  `json:"consts,omitempty"`
+	Vars     []*ValueInfo  `json:"vars,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// APIInfo represents the exported API surface of a package.
+type APIInfo struct {
+	Name     string       `json:"name"` // Package name
+	Path     string       `json:"path"` // Package directory
+	Language string       `json:"language"`
+	Doc      string       `json:"doc,omitempty"`
+	Files    []string     `json:"files,omitempty"`
+	Funcs    []*FuncInfo  `json:"funcs,omitempty"`
+	Types    []*TypeInfo  `json:"types,omitempty"`
+	Consts   []*ValueInfo `json:"consts,omitempty"`
+	Vars     []*ValueInfo `json:"vars,omitempty"`
+}
+
+// ImportInfo describes a single import spec.
+type ImportInfo struct {
+	Path     string   `json:"path"`
+	Alias    string   `json:"alias,omitempty"` // Explicit name, "_" or "."
+	Position Position `json:"position"`
+}
+
+// FuncInfo describes a function or method declaration.
+type FuncInfo struct {
+	Name      string   `json:"name"`
+	Receiver  string   `json:"receiver,omitempty"` // Receiver type name without pointer, empty for plain functions
+	Signature string   `json:"signature"`          // Declaration without the body
+	Doc       string   `json:"doc,omitempty"`
+	Exported  bool     `json:"exported"`
+	File      string   `json:"file,omitempty"` // Source file, set when extracted from a package
+	Position  Position `json:"position"`
+}
+
+// FieldInfo describes a struct field or interface method.
+type FieldInfo struct {
+	Name     string `json:"name,omitempty"` // Empty for embedded fields
+	Type     string `json:"type"`
+	Tag      string `json:"tag,omitempty"`
+	Doc      string `json:"doc,omitempty"`
+	Embedded bool   `json:"embedded,omitempty"`
+}
+
+// TypeInfo describes a type declaration.
+type TypeInfo struct {
+	Name       string       `json:"name"`
+	Kind       string       `json:"kind"` // struct, interface, func, map, alias, ...
+	Definition string       `json:"definition"`
+	Doc        string       `json:"doc,omitempty"`
+	Exported   bool         `json:"exported"`
+	Fields     []*FieldInfo `json:"fields,omitempty"` // Struct fields or interface methods
+	Embeds     []string     `json:"embeds,omitempty"` // Embedded types or interfaces
+	File       string       `json:"file,omitempty"`
+	Position   Position     `json:"position"`
+}
+
+// ValueInfo describes a const or var declaration.
+type ValueInfo struct {
+	Name     string   `json:"name"`
+	Kind     string   `json:"kind"` // const or var
+	Type     string   `json:"type,omitempty"`
+	Value    string   `json:"value,omitempty"`
+	Doc      string   `json:"doc,omitempty"`
+	Exported bool     `json:"exported"`
+	File     string   `json:"file,omitempty"`
+	Position Position `json:"position"`
+}
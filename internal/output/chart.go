@@ -0,0 +1,537 @@
+package output
+
+import (
+	"fmt"
+	"lazybox/internal/glpg"
+	"lazybox/internal/theme"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Output formats for graphify.
+const (
+	ChartText = "text"
+	ChartSVG  = "svg"
+)
+
+// ChartFormats lists the formats accepted by PrintGLPGAsCharts, the default first.
+var ChartFormats = []string{ChartText, ChartSVG}
+
+// PrintGLPGAsCharts renders charts of the graph's numeric properties: total
+// file size by extension, a histogram of line counts, a disk-usage treemap
+// of the scanned directory and keyword frequencies, each drawn only
+// when the graph has the data for it. Charts are drawn in the terminal
+// (text) or as a standalone SVG document (svg), in the colors of the
+// default Base16 theme. --less keeps the 5 largest entries of each chart
+// instead of 15.
+func PrintGLPGAsCharts(data *glpg.GLPG, flags map[string]bool, format string) error {
+	limit := 15
+	if flags["less"] {
+		limit = 5
+	}
+	charts := collectCharts(data, limit)
+	if len(charts) == 0 {
+		return fmt.Errorf("nothing to chart: the graph has no file sizes, line counts or keyword frequencies")
+	}
+	switch strings.ToLower(format) {
+	case "", ChartText:
+		fmt.Print(renderChartsText(charts, terminalWidth()))
+		return nil
+	case ChartSVG:
+		fmt.Print(renderChartsSVG(charts))
+		return nil
+	}
+	return fmt.Errorf("unknown chart format %q (expected one of %s)", format, strings.Join(ChartFormats, ", "))
+}
+
+// Kinds of chart.
+const (
+	chartBars = iota
+	chartHistogram
+	chartTreemap
+)
+
+// chart is one chart: bars, histogram bins or treemap tiles, largest first
+// except for histogram bins, which are in order.
+type chart struct {
+	kind   int
+	title  string
+	color  theme.Base16Color // Bar color
+	format func(float64) string
+	items  []chartItem
+}
+
+type chartItem struct {
+	label string
+	value float64
+}
+
+// collectCharts builds every chart the graph has data for, keeping up to
+// limit entries in bar charts and treemaps.
+func collectCharts(data *glpg.GLPG, limit int) []*chart {
+	ct := theme.GetDefaultTheme()
+	order := data.BreadthFirstOrder()
+	var charts []*chart
+
+	sizes := make(map[string]float64)
+	var lines []float64
+	keywords := make(map[string]float64)
+	for _, id := range order {
+		node := data.Nodes[id]
+		if hasLabel(node, "FileContent") {
+			continue // Duplicates the counts of its file
+		}
+		if size, ok := chartNumber(node.Properties["Size"]); ok && isFileEntry(node) && !isDirEntry(node) {
+			sizes[fileExtension(node)] += size
+		}
+		if n, ok := chartNumber(node.Properties["LineCount"]); ok {
+			lines = append(lines, n)
+		}
+		// Keywords is a list of {Keyword, Count} maps on files and text nodes
+		list, _ := node.Properties["Keywords"].([]interface{})
+		for _, item := range list {
+			kw, _ := item.(map[string]interface{})
+			keyword, _ := kw["Keyword"].(string)
+			if count, ok := chartNumber(kw["Count"]); ok && keyword != "" {
+				keywords[keyword] += count
+			}
+		}
+	}
+
+	// topItems drops zero totals, so charts are only added if it keeps some
+	if items := topItems(sizes, limit); len(items) > 0 {
+		charts = append(charts, &chart{kind: chartBars, title: "File size by extension", color: ct.Base0D, format: formatBytes, items: items})
+	}
+	if len(lines) > 0 {
+		charts = append(charts, &chart{kind: chartHistogram, title: "Lines per file", color: ct.Base0B, format: formatCount, items: lineHistogram(lines)})
+	}
+	if title, tiles := diskUsage(data, order); len(tiles) > 0 {
+		total := make(map[string]float64, len(tiles))
+		for _, tile := range tiles {
+			total[tile.label] += tile.value
+		}
+		if items := topItems(total, limit); len(items) > 0 {
+			charts = append(charts, &chart{kind: chartTreemap, title: title, format: formatBytes, items: items})
+		}
+	}
+	if items := topItems(keywords, limit); len(items) > 0 {
+		charts = append(charts, &chart{kind: chartBars, title: "Keyword frequency", color: ct.Base0E, format: formatCount, items: items})
+	}
+	return charts
+}
+
+func hasLabel(node *glpg.GLPGNode, label string) bool {
+	for _, l := range node.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// isFileEntry reports whether node is a scanned file or directory.
+func isFileEntry(node *glpg.GLPGNode) bool {
+	path, _ := node.Properties["Path"].(string)
+	_, ok := chartNumber(node.Properties["Size"])
+	return path != "" && ok
+}
+
+func isDirEntry(node *glpg.GLPGNode) bool {
+	isDir, _ := node.Properties["IsDir"].(bool)
+	return isDir || hasLabel(node, "directory")
+}
+
+func fileExtension(node *glpg.GLPGNode) string {
+	ext, _ := node.Properties["Extension"].(string)
+	if ext == "" {
+		name, _ := node.Properties["Name"].(string)
+		ext = filepath.Ext(name)
+	}
+	if ext == "" {
+		return "(none)"
+	}
+	return ext
+}
+
+// chartNumber converts a numeric property value to float64.
+func chartNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// topItems returns the limit largest totals, largest first, with the rest
+// summed into one last item.
+func topItems(totals map[string]float64, limit int) []chartItem {
+	items := make([]chartItem, 0, len(totals))
+	for label, value := range totals {
+		if value > 0 {
+			items = append(items, chartItem{label, value})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].value != items[j].value {
+			return items[i].value > items[j].value
+		}
+		return items[i].label < items[j].label
+	})
+	if len(items) > limit+1 {
+		rest := 0.0
+		for _, item := range items[limit:] {
+			rest += item.value
+		}
+		items = append(items[:limit], chartItem{fmt.Sprintf("(%d more)", len(items)-limit), rest})
+	}
+	return items
+}
+
+// lineHistogram counts line counts into power-of-two bins (0, 1, 2-3, 4-7,
+// ...), from the lowest bin used to the highest.
+func lineHistogram(lines []float64) []chartItem {
+	counts := make(map[int]float64)
+	lo, hi := math.MaxInt, 0
+	for _, n := range lines {
+		bin := bits.Len64(uint64(max(n, 0)))
+		counts[bin]++
+		lo, hi = min(lo, bin), max(hi, bin)
+	}
+	var items []chartItem
+	for bin := lo; bin <= hi; bin++ {
+		label := "0"
+		if bin > 0 {
+			from, to := uint64(1)<<(bin-1), uint64(1)<<bin-1
+			label = strconv.FormatUint(from, 10)
+			if to > from {
+				label += "-" + strconv.FormatUint(to, 10)
+			}
+		}
+		items = append(items, chartItem{label, counts[bin]})
+	}
+	return items
+}
+
+// diskUsage returns the entries of the first directory in order that
+// contains others (see glpg.GLPG.ContainmentForest), sized by the total
+// size of the files under them.
+func diskUsage(data *glpg.GLPG, order []string) (string, []chartItem) {
+	forest := data.ContainmentForest()
+	entry := func(id string) bool {
+		node := data.GetNode(id)
+		return node != nil && isFileEntry(node)
+	}
+	var total func(id string) float64
+	total = func(id string) float64 {
+		node := data.Nodes[id]
+		if !isDirEntry(node) {
+			size, _ := chartNumber(node.Properties["Size"])
+			return size
+		}
+		sum := 0.0
+		for _, edge := range forest.Children[id] {
+			if entry(edge.TargetID) {
+				sum += total(edge.TargetID)
+			}
+		}
+		return sum
+	}
+	for _, id := range order {
+		if !entry(id) || !isDirEntry(data.Nodes[id]) {
+			continue
+		}
+		if parent := forest.Parent[id]; parent != nil && entry(parent.SourceID) {
+			continue
+		}
+		var tiles []chartItem
+		for _, edge := range forest.Children[id] {
+			if entry(edge.TargetID) {
+				child := data.Nodes[edge.TargetID]
+				name, _ := child.Properties["Name"].(string)
+				if name == "" {
+					name = child.ID
+				}
+				if isDirEntry(child) {
+					name += "/"
+				}
+				tiles = append(tiles, chartItem{name, total(edge.TargetID)})
+			}
+		}
+		if len(tiles) > 0 {
+			name, _ := data.Nodes[id].Properties["Name"].(string)
+			return fmt.Sprintf("Disk usage of %s (%s)", name, formatBytes(total(id))), tiles
+		}
+	}
+	return "", nil
+}
+
+func formatCount(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// formatBytes formats a size with binary units, e.g. "1.5 KB".
+func formatBytes(v float64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	i := 0
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", v, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", v, units[i])
+}
+
+// chartRect is a rectangle in a treemap.
+type chartRect struct {
+	x, y, w, h float64
+}
+
+// squarify lays out positive values, largest first, as rectangles filling
+// r with areas in proportion to the values, keeping them close to square
+// (Bruls, Huizing and van Wijk's squarified treemap).
+func squarify(values []float64, r chartRect) []chartRect {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	areas := make([]float64, len(values))
+	for i, v := range values {
+		areas[i] = v * r.w * r.h / total
+	}
+	worst := func(row []float64, side float64) float64 {
+		sum, hi, lo := 0.0, 0.0, math.Inf(1)
+		for _, a := range row {
+			sum += a
+			hi, lo = max(hi, a), min(lo, a)
+		}
+		return max(side*side*hi/(sum*sum), sum*sum/(side*side*lo))
+	}
+
+	rects := make([]chartRect, 0, len(values))
+	for start := 0; start < len(areas); {
+		side := min(r.w, r.h)
+		end := start + 1
+		for end < len(areas) && worst(areas[start:end+1], side) <= worst(areas[start:end], side) {
+			end++
+		}
+		sum := 0.0
+		for _, a := range areas[start:end] {
+			sum += a
+		}
+		if r.w >= r.h {
+			// A column along the left edge
+			w, y := sum/r.h, r.y
+			for _, a := range areas[start:end] {
+				rects = append(rects, chartRect{r.x, y, w, a / w})
+				y += a / w
+			}
+			r.x, r.w = r.x+w, r.w-w
+		} else {
+			// A row along the top edge
+			h, x := sum/r.w, r.x
+			for _, a := range areas[start:end] {
+				rects = append(rects, chartRect{x, r.y, a / h, h})
+				x += a / h
+			}
+			r.y, r.h = r.y+h, r.h-h
+		}
+		start = end
+	}
+	return rects
+}
+
+// tilePalette returns the accent colors of the theme, used in turn for
+// treemap tiles.
+func tilePalette(ct *theme.Base16Theme) []theme.Base16Color {
+	return []theme.Base16Color{ct.Base08, ct.Base09, ct.Base0A, ct.Base0B, ct.Base0C, ct.Base0D, ct.Base0E, ct.Base0F}
+}
+
+// terminalWidth returns the width to draw charts at: $COLUMNS if set,
+// otherwise 80.
+func terminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n >= 40 {
+		return n
+	}
+	return 80
+}
+
+// truncate shortens s to at most n terminal cells, ending it with an
+// ellipsis. Wide runes count as two cells.
+func truncate(s string, n int) string {
+	if lipgloss.Width(s) <= n {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+	var b strings.Builder
+	used := 0
+	for _, r := range s {
+		w := lipgloss.Width(string(r))
+		if used+w > n-1 {
+			break
+		}
+		b.WriteRune(r)
+		used += w
+	}
+	return b.String() + "…"
+}
+
+// renderChartsText draws the charts for a terminal width columns wide.
+func renderChartsText(charts []*chart, width int) string {
+	ct := theme.GetDefaultTheme()
+	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ct.Base0A)).Bold(true)
+	headingStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ct.Base0D)).Bold(true)
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("GLPG Charts") + "\n")
+	for _, c := range charts {
+		b.WriteString("\n" + headingStyle.Render(c.title) + "\n")
+		if c.kind == chartTreemap {
+			b.WriteString(renderTreemapText(c, width, 16))
+		} else {
+			b.WriteString(renderBarsText(c, width))
+		}
+	}
+	return b.String()
+}
+
+// renderBarsText draws a horizontal bar per item, in eighths of a cell.
+func renderBarsText(c *chart, width int) string {
+	ct := theme.GetDefaultTheme()
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ct.Base05))
+	barStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(c.color))
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(ct.Base04))
+
+	labelWidth, valueWidth, peak := 0, 0, 0.0
+	for _, item := range c.items {
+		labelWidth = max(labelWidth, lipgloss.Width(item.label))
+		valueWidth = max(valueWidth, len(c.format(item.value)))
+		peak = max(peak, item.value)
+	}
+	labelWidth = min(labelWidth, 24)
+	barWidth := max(width-labelWidth-valueWidth-4, 10)
+
+	const eighths = " ▏▎▍▌▋▊▉"
+	partials := []rune(eighths)
+	var b strings.Builder
+	for _, item := range c.items {
+		label := truncate(item.label, labelWidth)
+		n := 0
+		if peak > 0 {
+			n = int(math.Round(item.value / peak * float64(barWidth*8)))
+		}
+		bar := strings.Repeat("█", n/8)
+		if n%8 > 0 {
+			bar += string(partials[n%8])
+		}
+		pad := barWidth - utf8.RuneCountInString(bar)
+		fmt.Fprintf(&b, "%s%s %s%s %s\n",
+			labelStyle.Render(label), strings.Repeat(" ", labelWidth-lipgloss.Width(label)),
+			barStyle.Render(bar), strings.Repeat(" ", pad),
+			valueStyle.Render(c.format(item.value)))
+	}
+	return b.String()
+}
+
+// renderTreemapText draws a treemap as colored blocks, each labelled with
+// its name and size where there is room. Cells are about twice as tall as
+// they are wide, so the layout is computed at twice the height.
+func renderTreemapText(c *chart, width, height int) string {
+	ct := theme.GetDefaultTheme()
+	palette := tilePalette(ct)
+	values := make([]float64, len(c.items))
+	for i, item := range c.items {
+		values[i] = item.value
+	}
+	rects := squarify(values, chartRect{0, 0, float64(width), float64(height * 2)})
+
+	tiles := make([][]int, height)
+	text := make([][]rune, height)
+	for y := range tiles {
+		tiles[y] = make([]int, width)
+		text[y] = []rune(strings.Repeat(" ", width))
+		for x := range tiles[y] {
+			tiles[y][x] = -1
+		}
+	}
+	for i, r := range rects {
+		x0, x1 := int(math.Round(r.x)), int(math.Round(r.x+r.w))
+		y0, y1 := int(math.Round(r.y/2)), int(math.Round((r.y+r.h)/2))
+		x1, y1 = min(x1, width), min(y1, height)
+		if x0 >= x1 || y0 >= y1 {
+			continue
+		}
+		// Mark the left and top edges, so tiles stay apart without colors
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				tiles[y][x] = i
+			}
+			text[y][x0] = '▏'
+		}
+		for x := x0 + 1; x < x1; x++ {
+			text[y0][x] = '▔'
+		}
+		lines := []string{c.items[i].label, c.format(c.items[i].value)}
+		for j, line := range lines {
+			if y0+j >= y1 || x1-x0-2 < 3 {
+				break
+			}
+			// Wide runes take two cells; the second is left empty (0)
+			x := x0 + 1
+			for _, r := range truncate(line, x1-x0-2) {
+				w := max(lipgloss.Width(string(r)), 1)
+				if x+w > x1-1 {
+					break
+				}
+				text[y0+j][x] = r
+				for k := 1; k < w; k++ {
+					text[y0+j][x+k] = 0
+				}
+				x += w
+			}
+		}
+	}
+
+	var b strings.Builder
+	for y := range tiles {
+		for x := 0; x < width; {
+			tile := tiles[y][x]
+			start := x
+			for x < width && tiles[y][x] == tile {
+				x++
+			}
+			run := strings.ReplaceAll(string(text[y][start:x]), "\x00", "")
+			if tile < 0 {
+				b.WriteString(run)
+				continue
+			}
+			style := lipgloss.NewStyle().
+				Background(lipgloss.Color(palette[tile%len(palette)])).
+				Foreground(lipgloss.Color(ct.Base00))
+			b.WriteString(style.Render(run))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
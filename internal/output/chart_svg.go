@@ -0,0 +1,101 @@
+package output
+
+import (
+	"fmt"
+	"lazybox/internal/theme"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Dimensions of SVG charts, in pixels.
+const (
+	svgWidth      = 720
+	svgMargin     = 16
+	svgCharWidth  = 7.2 // Width of a 12px monospace character
+	svgRowHeight  = 20
+	svgMapHeight  = 320
+	svgHeadHeight = 32
+)
+
+// renderChartsSVG draws the charts one under another as a standalone SVG
+// document, with the same colors as the terminal charts.
+func renderChartsSVG(charts []*chart) string {
+	ct := theme.GetDefaultTheme()
+	var body strings.Builder
+	y := svgMargin + 24
+	fmt.Fprintf(&body, "  <text x=\"%d\" y=\"%d\" fill=\"%s\" font-size=\"18\" font-weight=\"bold\">GLPG Charts</text>\n", svgMargin, y-6, ct.Base0A)
+	for _, c := range charts {
+		fmt.Fprintf(&body, "  <g transform=\"translate(%d,%d)\">\n", svgMargin, y)
+		fmt.Fprintf(&body, "    <text y=\"22\" fill=\"%s\" font-size=\"14\" font-weight=\"bold\">%s</text>\n", ct.Base0D, xmlText(c.title))
+		if c.kind == chartTreemap {
+			writeTreemapSVG(&body, c, svgHeadHeight)
+			y += svgHeadHeight + svgMapHeight
+		} else {
+			writeBarsSVG(&body, c, svgHeadHeight)
+			y += svgHeadHeight + len(c.items)*svgRowHeight
+		}
+		body.WriteString("  </g>\n")
+		y += svgMargin
+	}
+
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" font-family=\"monospace\" font-size=\"12\">\n", svgWidth, y, svgWidth, y)
+	fmt.Fprintf(&b, "  <rect width=\"100%%\" height=\"100%%\" fill=\"%s\"/>\n", ct.Base00)
+	b.WriteString(body.String())
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// writeBarsSVG draws a row per item: its label, a bar scaled to the
+// largest item, and its value.
+func writeBarsSVG(b *strings.Builder, c *chart, top int) {
+	ct := theme.GetDefaultTheme()
+	labelChars, valueChars, peak := 0, 0, 0.0
+	for _, item := range c.items {
+		labelChars = max(labelChars, lipgloss.Width(item.label))
+		valueChars = max(valueChars, len(c.format(item.value)))
+		peak = max(peak, item.value)
+	}
+	labelChars = min(labelChars, 24)
+	labelWidth := float64(labelChars)*svgCharWidth + 12
+	barWidth := float64(svgWidth-2*svgMargin) - labelWidth - float64(valueChars)*svgCharWidth - 12
+
+	for i, item := range c.items {
+		y := top + i*svgRowHeight
+		w := 0.0
+		if peak > 0 {
+			w = item.value / peak * barWidth
+		}
+		fmt.Fprintf(b, "    <text y=\"%d\" fill=\"%s\">%s</text>\n", y+14, ct.Base05, xmlText(truncate(item.label, labelChars)))
+		fmt.Fprintf(b, "    <rect x=\"%.1f\" y=\"%d\" width=\"%.1f\" height=\"%d\" rx=\"2\" fill=\"%s\"><title>%s: %s</title></rect>\n",
+			labelWidth, y+3, w, svgRowHeight-6, c.color, xmlText(item.label), xmlText(c.format(item.value)))
+		fmt.Fprintf(b, "    <text x=\"%.1f\" y=\"%d\" fill=\"%s\">%s</text>\n", labelWidth+w+6, y+14, ct.Base04, xmlText(c.format(item.value)))
+	}
+}
+
+// writeTreemapSVG draws a treemap the full width of the document, with
+// names and sizes in the tiles large enough to hold them.
+func writeTreemapSVG(b *strings.Builder, c *chart, top int) {
+	ct := theme.GetDefaultTheme()
+	palette := tilePalette(ct)
+	values := make([]float64, len(c.items))
+	for i, item := range c.items {
+		values[i] = item.value
+	}
+	rects := squarify(values, chartRect{0, float64(top), svgWidth - 2*svgMargin, svgMapHeight})
+	for i, r := range rects {
+		item := c.items[i]
+		fmt.Fprintf(b, "    <rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" fill=\"%s\" stroke=\"%s\" stroke-width=\"2\"><title>%s: %s</title></rect>\n",
+			r.x, r.y, r.w, r.h, palette[i%len(palette)], ct.Base00, xmlText(item.label), xmlText(c.format(item.value)))
+		chars := int((r.w - 8) / svgCharWidth)
+		if chars < 3 || r.h < 18 {
+			continue
+		}
+		fmt.Fprintf(b, "    <text x=\"%.1f\" y=\"%.1f\" fill=\"%s\">%s</text>\n", r.x+4, r.y+15, ct.Base00, xmlText(truncate(item.label, chars)))
+		if r.h >= 34 {
+			fmt.Fprintf(b, "    <text x=\"%.1f\" y=\"%.1f\" fill=\"%s\">%s</text>\n", r.x+4, r.y+30, ct.Base00, xmlText(truncate(c.format(item.value), chars)))
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package output
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"lazybox/internal/glpg"
+)
+
+// chartGraph is specialGraph with a second file whose name and keywords
+// are wide runes, and a keyword too long for the label column.
+func chartGraph(t *testing.T) *glpg.GLPG {
+	t.Helper()
+	g := specialGraph(t)
+	g.AddNode(&glpg.GLPGNode{ID: "doc", Labels: []string{"FileInfo", "file"}, Properties: glpg.GLPGProperty{
+		"Name": "説明書.md", "Path": "src/説明書.md", "Size": int64(512), "Extension": ".md", "LineCount": 3,
+		"Keywords": []interface{}{
+			map[string]interface{}{"Keyword": "日本語の長いキーワードです", "Count": 2},
+			map[string]interface{}{"Keyword": "func", "Count": 1},
+			map[string]interface{}{"Keyword": "an-unusually-long-keyword-name", "Count": 1},
+		}}})
+	g.Connect("dir", "CONTAINS", "doc")
+	return g
+}
+
+func TestRenderChartsText(t *testing.T) {
+	const width = 60
+	got := string(ansiEscape.ReplaceAll([]byte(renderChartsText(collectCharts(chartGraph(t), 15), width)), nil))
+	for i, line := range strings.Split(got, "\n") {
+		if w := lipgloss.Width(line); w > width {
+			t.Errorf("line %d is %d cells wide, more than %d: %q", i+1, w, width, line)
+		}
+	}
+	checkGolden(t, "charts.txt", got)
+}
+
+func TestRenderChartsSVG(t *testing.T) {
+	checkGolden(t, "charts.svg", renderChartsSVG(collectCharts(chartGraph(t), 15)))
+}
+
+func TestPrintGLPGAsChartsErrors(t *testing.T) {
+	empty := glpg.NewGLPG()
+	empty.AddNode(&glpg.GLPGNode{ID: "a", Labels: []string{"Node"}, Properties: glpg.GLPGProperty{"Name": "a"}})
+	if err := PrintGLPGAsCharts(empty, nil, ChartText); err == nil || !strings.Contains(err.Error(), "nothing to chart") {
+		t.Errorf("charting a graph without numbers: %v", err)
+	}
+	if err := PrintGLPGAsCharts(chartGraph(t), nil, "png"); err == nil || !strings.Contains(err.Error(), `unknown chart format "png"`) {
+		t.Errorf("charting as png: %v", err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"abc", 3, "abc"},
+		{"abcd", 3, "ab…"},
+		{"日本", 4, "日本"},
+		{"日本語", 4, "日…"},
+		{"日本語", 3, "日…"},
+		{"日本", 1, "…"},
+		{"naïve", 5, "naïve"},
+		{"abc", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.in, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
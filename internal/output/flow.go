@@ -1,47 +1,143 @@
 package output
 
 import (
+	"bufio"
 	"fmt"
 	"lazybox/internal/glpg"
-	"lazybox/internal/theme"
-	"github.com/charmbracelet/lipgloss"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
-// PrintGLPGAsFlow renders the GLPG as a styled flowchart/diagram (ASCII art, theme-styled)
-func PrintGLPGAsFlow(data *glpg.GLPG, flags map[string]bool) error {
-	ct := theme.GetDefaultTheme()
-	box := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color(ct.Base0D)).
-		Foreground(lipgloss.Color(ct.Base05)).
-		Padding(0, 1)
-	arrow := lipgloss.NewStyle().Foreground(lipgloss.Color(ct.Base0E)).Render("→")
-
-title := lipgloss.NewStyle().Foreground(lipgloss.Color(ct.Base0A)).Bold(true).Render("GLPG Flowchart")
-
-	var b strings.Builder
-	b.WriteString(title + "\n\n")
-
-	nodeIDs := make([]string, 0, len(data.Nodes))
-	for id := range data.Nodes {
-		nodeIDs = append(nodeIDs, id)
-	}
-
-	// For each node, print the box and its outgoing edges as arrows to other boxes
-	for _, nodeID := range nodeIDs {
-		node := data.Nodes[nodeID]
-		b.WriteString(box.Render(node.ID))
-		outEdges := data.GetOutgoingEdges(nodeID)
-		for _, edge := range outEdges {
-			target := data.GetNode(edge.TargetID)
-			if target != nil {
-				b.WriteString("\n  " + arrow + " " + box.Render(target.ID) + "\n")
+// Diagram formats for flowify.
+const (
+	FlowASCII   = "ascii"
+	FlowMermaid = "mermaid"
+	FlowDOT     = "dot"
+)
+
+// FlowFormats lists the formats accepted by PrintGLPGAsFlow, the default first.
+var FlowFormats = []string{FlowASCII, FlowMermaid, FlowDOT}
+
+// PrintGLPGAsFlow renders the GLPG as a diagram: a layered layout drawn with
+// box-drawing characters for the terminal (ascii), a Mermaid flowchart or a
+// Graphviz DOT digraph. --less leaves out edge labels.
+func PrintGLPGAsFlow(data *glpg.GLPG, flags map[string]bool, format string) error {
+	switch strings.ToLower(format) {
+	case "", FlowASCII:
+		return printFlowLayout(data, flags)
+	case FlowMermaid:
+		w := bufio.NewWriter(os.Stdout)
+		writeMermaid(w, data, flags)
+		return w.Flush()
+	case FlowDOT, "graphviz":
+		w := bufio.NewWriter(os.Stdout)
+		writeDOT(w, data, flags)
+		return w.Flush()
+	}
+	return fmt.Errorf("unknown flow format %q (expected one of %s)", format, strings.Join(FlowFormats, ", "))
+}
+
+// flowNodeText names a node in a diagram: its first label and its Name (or
+// the base of its Path), falling back to its ID.
+func flowNodeText(node *glpg.GLPGNode) string {
+	name := ""
+	if s, ok := node.Properties["Name"].(string); ok && s != "" {
+		name = s
+	} else if s, ok := node.Properties["Path"].(string); ok && s != "" {
+		name = filepath.Base(s)
+	}
+	switch {
+	case name == "":
+		return node.ID
+	case len(node.Labels) == 0:
+		return name
+	}
+	return node.Labels[0] + ": " + name
+}
+
+// flowEdges returns the edges between existing nodes, grouped by source in
+// the order of nodeIDs and by adjacency order within a source.
+func flowEdges(data *glpg.GLPG, nodeIDs []string) []*glpg.GLPGEdge {
+	var edges []*glpg.GLPGEdge
+	for _, id := range nodeIDs {
+		for _, edge := range data.OutgoingEdges[id] {
+			if data.GetNode(edge.TargetID) != nil {
+				edges = append(edges, edge)
 			}
 		}
-		b.WriteString("\n")
 	}
+	return edges
+}
+
+// writeMermaid writes the graph as a Mermaid flowchart. Nodes get short
+// generated IDs, since Mermaid IDs cannot hold most punctuation.
+func writeMermaid(w *bufio.Writer, data *glpg.GLPG, flags map[string]bool) {
+	order := data.BreadthFirstOrder()
+	ids := make(map[string]string, len(order))
+	w.WriteString("flowchart TD\n")
+	for i, id := range order {
+		ids[id] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(w, "    %s[\"%s\"]\n", ids[id], mermaidText(flowNodeText(data.Nodes[id])))
+	}
+	for _, edge := range flowEdges(data, order) {
+		if edge.Label == "" || flags["less"] {
+			fmt.Fprintf(w, "    %s --> %s\n", ids[edge.SourceID], ids[edge.TargetID])
+		} else {
+			fmt.Fprintf(w, "    %s -->|\"%s\"| %s\n", ids[edge.SourceID], mermaidText(edge.Label), ids[edge.TargetID])
+		}
+	}
+}
+
+// mermaidText escapes text for a quoted Mermaid label. Mermaid reads
+// HTML in labels and #name; as an entity, so <, > and # are escaped too.
+func mermaidText(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;", "#", "#35;", "\n", " ").Replace(s)
+}
+
+// writeDOT writes the graph as a Graphviz digraph. Nodes that contain others
+// (see glpg.ContainmentForest) become clusters holding themselves and
+// everything nested under them.
+func writeDOT(w *bufio.Writer, data *glpg.GLPG, flags map[string]bool) {
+	forest := data.ContainmentForest()
+	w.WriteString("digraph lazybox {\n")
+	w.WriteString("  rankdir=TB;\n")
+	w.WriteString("  node [shape=box, style=rounded];\n")
+	clusters := 0
+	var writeNode func(id string, depth int)
+	writeNode = func(id string, depth int) {
+		pad := strings.Repeat("  ", depth)
+		node := data.Nodes[id]
+		text := dotQuote(flowNodeText(node))
+		children := forest.Children[id]
+		if len(children) == 0 {
+			fmt.Fprintf(w, "%s%s [label=%s];\n", pad, dotQuote(id), text)
+			return
+		}
+		fmt.Fprintf(w, "%ssubgraph cluster_%d {\n", pad, clusters)
+		clusters++
+		fmt.Fprintf(w, "%s  label=%s;\n", pad, text)
+		fmt.Fprintf(w, "%s  style=rounded;\n", pad)
+		fmt.Fprintf(w, "%s  %s [label=%s];\n", pad, dotQuote(id), text)
+		for _, edge := range children {
+			writeNode(edge.TargetID, depth+1)
+		}
+		fmt.Fprintf(w, "%s}\n", pad)
+	}
+	for _, root := range forest.Roots {
+		writeNode(root, 1)
+	}
+	for _, edge := range flowEdges(data, data.BreadthFirstOrder()) {
+		if edge.Label == "" || flags["less"] {
+			fmt.Fprintf(w, "  %s -> %s;\n", dotQuote(edge.SourceID), dotQuote(edge.TargetID))
+		} else {
+			fmt.Fprintf(w, "  %s -> %s [label=%s];\n", dotQuote(edge.SourceID), dotQuote(edge.TargetID), dotQuote(edge.Label))
+		}
+	}
+	w.WriteString("}\n")
+}
 
-	fmt.Println(b.String())
-	return nil
+// dotQuote returns s as a quoted DOT ID.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
 }
@@ -0,0 +1,538 @@
+package output
+
+import (
+	"fmt"
+	"lazybox/internal/glpg"
+	"lazybox/internal/theme"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The terminal flowchart is a simplified Sugiyama layout: edges that close
+// cycles are reversed, nodes are assigned to layers by longest path from
+// the roots, edges spanning several layers are split by dummy vertices,
+// layers are reordered by the barycenter of their neighbours to reduce
+// crossings, and edges are routed through the channels between layers.
+
+// flowVertex is a node placed in the layout, or a dummy vertex carrying a
+// long edge through an intermediate layer.
+type flowVertex struct {
+	id       string // Node ID, "" for dummies
+	text     string
+	layer    int
+	pos      int     // Index within its layer
+	key      float64 // Sort key within its layer
+	x, width int
+	up, down []*flowVertex
+}
+
+func (v *flowVertex) center() int { return v.x + v.width/2 }
+
+// flowSegment joins vertices in adjacent layers; an edge spanning several
+// layers becomes a chain of segments.
+type flowSegment struct {
+	from, to  *flowVertex // from is in the upper layer
+	label     string      // Set on the first segment of an edge
+	arrowDown bool        // An edge points down into to
+	arrowUp   bool        // An edge points up into from
+}
+
+// layoutFlow assigns every node of data to a layer and a column.
+func layoutFlow(data *glpg.GLPG, withLabels bool) ([][]*flowVertex, []*flowSegment) {
+	order := data.BreadthFirstOrder()
+	rank := make(map[string]int, len(order))
+	for i, id := range order {
+		rank[id] = i
+	}
+
+	// Edges that close a cycle in a depth-first walk are drawn reversed
+	state := make(map[string]int, len(order))
+	back := make(map[*glpg.GLPGEdge]bool)
+	selfLoops := make(map[string]bool)
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = 1
+		for _, edge := range data.OutgoingEdges[id] {
+			switch target := edge.TargetID; {
+			case data.GetNode(target) == nil:
+			case target == id:
+				selfLoops[id] = true
+			case state[target] == 1:
+				back[edge] = true
+			case state[target] == 0:
+				visit(target)
+			}
+		}
+		state[id] = 2
+	}
+	for _, id := range order {
+		if state[id] == 0 {
+			visit(id)
+		}
+	}
+
+	// Merge parallel edges, oriented downwards
+	type pair struct {
+		from, to string
+		labels   []string
+		down, up bool
+	}
+	var pairs []*pair
+	pairIndex := make(map[[2]string]*pair)
+	succ := make(map[string][]string)
+	inDegree := make(map[string]int)
+	for _, edge := range flowEdges(data, order) {
+		from, to := edge.SourceID, edge.TargetID
+		if from == to {
+			continue
+		}
+		if back[edge] {
+			from, to = to, from
+		}
+		p := pairIndex[[2]string{from, to}]
+		if p == nil {
+			p = &pair{from: from, to: to}
+			pairIndex[[2]string{from, to}] = p
+			pairs = append(pairs, p)
+			succ[from] = append(succ[from], to)
+			inDegree[to]++
+		}
+		if back[edge] {
+			p.up = true
+		} else {
+			p.down = true
+		}
+		if withLabels && edge.Label != "" && !containsString(p.labels, edge.Label) {
+			p.labels = append(p.labels, edge.Label)
+		}
+	}
+
+	// Longest-path layering, in topological order
+	layerOf := make(map[string]int, len(order))
+	var ready []string
+	for _, id := range order {
+		if inDegree[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+	for len(ready) > 0 {
+		id := ready[0]
+		ready = ready[1:]
+		for _, next := range succ[id] {
+			if layerOf[id]+1 > layerOf[next] {
+				layerOf[next] = layerOf[id] + 1
+			}
+			if inDegree[next]--; inDegree[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+
+	vertices := make(map[string]*flowVertex, len(order))
+	var all []*flowVertex
+	for _, id := range order {
+		text := flowNodeText(data.Nodes[id])
+		if selfLoops[id] {
+			text += " ↺"
+		}
+		v := &flowVertex{id: id, text: text, layer: layerOf[id], key: float64(rank[id]), width: lipgloss.Width(text) + 4}
+		vertices[id] = v
+		all = append(all, v)
+	}
+
+	var segments []*flowSegment
+	connect := func(from, to *flowVertex) *flowSegment {
+		from.down = append(from.down, to)
+		to.up = append(to.up, from)
+		s := &flowSegment{from: from, to: to}
+		segments = append(segments, s)
+		return s
+	}
+	for _, p := range pairs {
+		from, to := vertices[p.from], vertices[p.to]
+		var chain []*flowSegment
+		prev := from
+		for layer := from.layer + 1; layer < to.layer; layer++ {
+			dummy := &flowVertex{layer: layer, key: from.key + 0.5, width: 1}
+			all = append(all, dummy)
+			chain = append(chain, connect(prev, dummy))
+			prev = dummy
+		}
+		chain = append(chain, connect(prev, to))
+		chain[0].label = strings.Join(p.labels, ",")
+		chain[0].arrowUp = p.up
+		chain[len(chain)-1].arrowDown = p.down
+	}
+
+	var layers [][]*flowVertex
+	for _, v := range all {
+		for len(layers) <= v.layer {
+			layers = append(layers, nil)
+		}
+		layers[v.layer] = append(layers[v.layer], v)
+	}
+	for _, layer := range layers {
+		sortLayer(layer)
+	}
+
+	// Barycenter sweeps, down then up
+	reorder := func(layer []*flowVertex, neighbours func(*flowVertex) []*flowVertex) {
+		for _, v := range layer {
+			if ns := neighbours(v); len(ns) > 0 {
+				sum := 0
+				for _, n := range ns {
+					sum += n.pos
+				}
+				v.key = float64(sum) / float64(len(ns))
+			} else {
+				v.key = float64(v.pos)
+			}
+		}
+		sortLayer(layer)
+	}
+	for i := 0; i < 4; i++ {
+		for l := 1; l < len(layers); l++ {
+			reorder(layers[l], func(v *flowVertex) []*flowVertex { return v.up })
+		}
+		for l := len(layers) - 2; l >= 0; l-- {
+			reorder(layers[l], func(v *flowVertex) []*flowVertex { return v.down })
+		}
+	}
+
+	// Place vertices left to right, each under its parents where there is
+	// room, then move parents right to sit over their children
+	for l, layer := range layers {
+		placeLayer(layer, l > 0, func(v *flowVertex) []*flowVertex { return v.up })
+	}
+	for l := len(layers) - 2; l >= 0; l-- {
+		placeLayer(layers[l], true, func(v *flowVertex) []*flowVertex { return v.down })
+	}
+	return layers, segments
+}
+
+// placeLayer sets the columns of a layer, keeping a gap between vertices and
+// never moving a vertex left of where it was. With align, each vertex is
+// centered on its neighbours where there is room.
+func placeLayer(layer []*flowVertex, align bool, neighbours func(*flowVertex) []*flowVertex) {
+	const gap = 3
+	right := -gap
+	for _, v := range layer {
+		x := max(v.x, right+gap)
+		if ns := neighbours(v); align && len(ns) > 0 {
+			sum := 0
+			for _, n := range ns {
+				sum += n.center()
+			}
+			x = max(x, sum/len(ns)-v.width/2)
+		}
+		v.x = x
+		right = x + v.width
+	}
+}
+
+// sortLayer orders a layer by key and records each vertex's position.
+func sortLayer(layer []*flowVertex) {
+	sort.SliceStable(layer, func(i, j int) bool { return layer[i].key < layer[j].key })
+	for i, v := range layer {
+		v.pos = i
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Directions a line leaves a grid cell in.
+const (
+	lineUp = 1 << iota
+	lineDown
+	lineLeft
+	lineRight
+)
+
+var lineRunes = map[int]rune{
+	lineUp: '│', lineDown: '│', lineUp | lineDown: '│',
+	lineLeft: '─', lineRight: '─', lineLeft | lineRight: '─',
+	lineDown | lineRight: '┌', lineDown | lineLeft: '┐',
+	lineUp | lineRight: '└', lineUp | lineLeft: '┘',
+	lineUp | lineDown | lineRight: '├', lineUp | lineDown | lineLeft: '┤',
+	lineDown | lineLeft | lineRight: '┬', lineUp | lineLeft | lineRight: '┴',
+	lineUp | lineDown | lineLeft | lineRight: '┼',
+}
+
+// Styles of grid cells.
+const (
+	cellBlank = iota
+	cellBox
+	cellText
+	cellEdge
+	cellLabel
+)
+
+type flowCell struct {
+	r     rune // Set for box and text cells, and arrowheads
+	lines int
+	class int
+	wide  bool // Second cell of a wide rune, which prints nothing
+}
+
+// flowGrid is a character canvas for the flowchart.
+type flowGrid struct {
+	cells [][]flowCell
+}
+
+func newFlowGrid(width, height int) *flowGrid {
+	g := &flowGrid{cells: make([][]flowCell, height)}
+	for y := range g.cells {
+		g.cells[y] = make([]flowCell, width)
+	}
+	return g
+}
+
+func (g *flowGrid) set(x, y int, r rune, class int) {
+	g.cells[y][x] = flowCell{r: r, class: class}
+}
+
+func (g *flowGrid) line(x, y, dirs int) {
+	g.cells[y][x].lines |= dirs
+	g.cells[y][x].class = cellEdge
+}
+
+func (g *flowGrid) vline(x, y1, y2 int) {
+	for y := y1; y < y2; y++ {
+		g.line(x, y, lineDown)
+		g.line(x, y+1, lineUp)
+	}
+}
+
+func (g *flowGrid) hline(y, x1, x2 int) {
+	for x := x1; x < x2; x++ {
+		g.line(x, y, lineRight)
+		g.line(x+1, y, lineLeft)
+	}
+}
+
+// free reports whether the cells from x1 up to x2 on row y are empty.
+func (g *flowGrid) free(y, x1, x2 int) bool {
+	if x2 > len(g.cells[y]) {
+		return false
+	}
+	for x := x1; x < x2; x++ {
+		if c := g.cells[y][x]; c.r != 0 || c.lines != 0 || c.wide {
+			return false
+		}
+	}
+	return true
+}
+
+// text writes s from column x; wide runes take two cells.
+func (g *flowGrid) text(x, y int, s string, class int) {
+	for _, r := range s {
+		g.set(x, y, r, class)
+		x++
+		for w := lipgloss.Width(string(r)); w > 1; w-- {
+			g.cells[y][x] = flowCell{class: class, wide: true}
+			x++
+		}
+	}
+}
+
+func (g *flowGrid) box(v *flowVertex, y int) {
+	inner := strings.Repeat("─", v.width-2)
+	g.text(v.x, y, "┌"+inner+"┐", cellBox)
+	g.text(v.x, y+1, "│", cellBox)
+	g.text(v.x+2, y+1, v.text, cellText)
+	g.text(v.x+v.width-1, y+1, "│", cellBox)
+	g.text(v.x, y+2, "└"+inner+"┘", cellBox)
+}
+
+// renderFlow draws a layout, returning its rows.
+func renderFlow(layers [][]*flowVertex, segments []*flowSegment) [][]flowCell {
+	// Segments leaving each layer, grouped by their upper vertex
+	type group struct {
+		from     *flowVertex
+		segments []*flowSegment
+		lo, hi   int
+		track    int // -1 for a straight vertical line
+	}
+	groups := make([][]*group, len(layers))
+	byVertex := make(map[*flowVertex]*group)
+	for _, s := range segments {
+		grp := byVertex[s.from]
+		if grp == nil {
+			c := s.from.center()
+			grp = &group{from: s.from, lo: c, hi: c, track: -1}
+			byVertex[s.from] = grp
+			groups[s.from.layer] = append(groups[s.from.layer], grp)
+		}
+		grp.segments = append(grp.segments, s)
+		grp.lo = min(grp.lo, s.to.center())
+		grp.hi = max(grp.hi, s.to.center())
+	}
+
+	// Give each horizontal run a track, sharing tracks between runs that
+	// do not overlap
+	tracks := make([]int, len(layers))
+	for l, gs := range groups {
+		sort.Slice(gs, func(i, j int) bool { return gs[i].lo < gs[j].lo })
+		var ends []int
+		for _, grp := range gs {
+			if grp.lo == grp.hi {
+				continue
+			}
+			for t, end := range ends {
+				if end+1 < grp.lo {
+					grp.track = t
+					ends[t] = grp.hi
+					break
+				}
+			}
+			if grp.track < 0 {
+				grp.track = len(ends)
+				ends = append(ends, grp.hi)
+			}
+		}
+		tracks[l] = len(ends)
+	}
+
+	width, height := 0, 0
+	layerY := make([]int, len(layers))
+	for l, layer := range layers {
+		for _, v := range layer {
+			width = max(width, v.x+v.width)
+		}
+		layerY[l] = height
+		height += 3
+		if l < len(layers)-1 {
+			height += 2 + tracks[l]
+		}
+	}
+	labelWidth := 0
+	for _, s := range segments {
+		labelWidth = max(labelWidth, lipgloss.Width(s.label)+2)
+	}
+	grid := newFlowGrid(width+labelWidth, height)
+
+	for l, layer := range layers {
+		for _, v := range layer {
+			if v.id == "" {
+				grid.vline(v.x, layerY[l], layerY[l]+2)
+				grid.line(v.x, layerY[l], lineUp)
+				grid.line(v.x, layerY[l]+2, lineDown)
+			} else {
+				grid.box(v, layerY[l])
+			}
+		}
+	}
+
+	for l, gs := range groups {
+		top := layerY[l] + 3
+		bottom := top + 1 + tracks[l]
+		for _, grp := range gs {
+			sx := grp.from.center()
+			if grp.from.id != "" {
+				grid.set(sx, top-1, '┬', cellBox)
+			}
+			grid.line(sx, top, lineUp)
+			turn := bottom
+			if grp.track >= 0 {
+				turn = top + 1 + grp.track
+				grid.hline(turn, grp.lo, grp.hi)
+			}
+			grid.vline(sx, top, turn)
+			for _, s := range grp.segments {
+				tx := s.to.center()
+				grid.vline(tx, turn, bottom)
+				grid.line(tx, bottom, lineDown)
+			}
+		}
+		// Arrowheads and labels go on once every line is drawn
+		for _, grp := range gs {
+			for _, s := range grp.segments {
+				if s.arrowDown {
+					grid.set(s.to.center(), bottom, '▼', cellEdge)
+				}
+			}
+		}
+		for _, grp := range gs {
+			sx := grp.from.center()
+			var labels []string
+			for _, s := range grp.segments {
+				if tx := s.to.center(); s.to.id != "" && grid.cells[bottom][tx].r != '▼' {
+					grid.set(tx, bottom+1, '┴', cellBox)
+				}
+				if s.arrowUp {
+					grid.set(sx, top, '▲', cellEdge)
+				}
+				for _, label := range strings.Split(s.label, ",") {
+					if label != "" && !containsString(labels, label) {
+						labels = append(labels, label)
+					}
+				}
+			}
+			if label := strings.Join(labels, ","); label != "" {
+				n := lipgloss.Width(label)
+				if grid.free(top, sx+1, sx+n+2) {
+					grid.text(sx+2, top, label, cellLabel)
+				}
+			}
+		}
+	}
+	return grid.cells
+}
+
+// printFlowLayout prints the graph as a layered flowchart.
+func printFlowLayout(data *glpg.GLPG, flags map[string]bool) error {
+	ct := theme.GetDefaultTheme()
+	styles := map[int]lipgloss.Style{
+		cellBlank: lipgloss.NewStyle(),
+		cellBox:   lipgloss.NewStyle().Foreground(lipgloss.Color(ct.Base0D)),
+		cellText:  lipgloss.NewStyle().Foreground(lipgloss.Color(ct.Base05)),
+		cellEdge:  lipgloss.NewStyle().Foreground(lipgloss.Color(ct.Base0E)),
+		cellLabel: lipgloss.NewStyle().Foreground(lipgloss.Color(ct.Base03)).Italic(true),
+	}
+	title := lipgloss.NewStyle().Foreground(lipgloss.Color(ct.Base0A)).Bold(true).Render("GLPG Flowchart")
+
+	var b strings.Builder
+	b.WriteString(title + "\n\n")
+	if len(data.Nodes) == 0 {
+		b.WriteString("(empty graph)\n")
+		fmt.Print(b.String())
+		return nil
+	}
+	layers, segments := layoutFlow(data, !flags["less"])
+	for _, row := range renderFlow(layers, segments) {
+		// Trim trailing blanks, then style runs of cells of the same class
+		end := len(row)
+		for end > 0 && row[end-1].r == 0 && row[end-1].lines == 0 && !row[end-1].wide {
+			end--
+		}
+		for start := 0; start < end; {
+			class := row[start].class
+			var run strings.Builder
+			for ; start < end && row[start].class == class; start++ {
+				c := row[start]
+				switch {
+				case c.wide:
+				case c.r != 0:
+					run.WriteRune(c.r)
+				case c.lines != 0:
+					run.WriteRune(lineRunes[c.lines])
+				default:
+					run.WriteByte(' ')
+				}
+			}
+			b.WriteString(styles[class].Render(run.String()))
+		}
+		b.WriteString("\n")
+	}
+	fmt.Print(b.String())
+	return nil
+}
@@ -0,0 +1,46 @@
+package output
+
+import "testing"
+
+func TestPrintGLPGAsFlow(t *testing.T) {
+	tests := []struct {
+		golden string
+		format string
+		flags  map[string]bool
+	}{
+		{"flow.txt", FlowASCII, nil},
+		{"flow.less.txt", FlowASCII, map[string]bool{"less": true}},
+		{"flow.mmd", FlowMermaid, nil},
+		{"flow.dot", FlowDOT, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			g := specialGraph(t)
+			checkGolden(t, tt.golden, render(t, func() error { return PrintGLPGAsFlow(g, tt.flags, tt.format) }))
+		})
+	}
+
+	if err := PrintGLPGAsFlow(specialGraph(t), nil, "svg"); err == nil {
+		t.Errorf("PrintGLPGAsFlow accepted an unknown format")
+	}
+}
+
+func TestFlowEscaping(t *testing.T) {
+	tests := []struct {
+		in, mermaid, dot string
+	}{
+		{"plain", "plain", `"plain"`},
+		{`say "hi"`, "say #quot;hi#quot;", `"say \"hi\""`},
+		{"<b>#1</b>", "#lt;b#gt;#35;1#lt;/b#gt;", `"<b>#1</b>"`},
+		{`C:\dir`, `C:\dir`, `"C:\\dir"`},
+		{"two\nlines", "two lines", `"two\nlines"`},
+	}
+	for _, tt := range tests {
+		if got := mermaidText(tt.in); got != tt.mermaid {
+			t.Errorf("mermaidText(%q) = %s, want %s", tt.in, got, tt.mermaid)
+		}
+		if got := dotQuote(tt.in); got != tt.dot {
+			t.Errorf("dotQuote(%q) = %s, want %s", tt.in, got, tt.dot)
+		}
+	}
+}
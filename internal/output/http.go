@@ -0,0 +1,166 @@
+package output
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// HTTPMessage describes the message httpify wraps a mode's output in: a
+// request when Method is set, a response otherwise.
+type HTTPMessage struct {
+	Method      string // Request method, e.g. GET or POST
+	URL         string // Request target; its host becomes the Host header
+	Status      int    // Response status code
+	ContentType string
+}
+
+// bodilessMethods are the request methods that carry no content.
+var bodilessMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+var httpToken = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// PrintHTTP writes body wrapped in an HTTP/1.1 message. Responses start
+// with a status line and carry a strong ETag derived from the SHA-256 of
+// the body; requests start with a method line and a Host header, and
+// GET, HEAD, OPTIONS and TRACE requests are written without the body.
+// Header lines end in CRLF, as HTTP requires; the body is written as is.
+func PrintHTTP(msg HTTPMessage, body []byte) error {
+	var head []string
+	withBody := true
+	if msg.Method != "" {
+		method := strings.ToUpper(msg.Method)
+		if !httpToken.MatchString(method) {
+			return fmt.Errorf("invalid HTTP method %q", msg.Method)
+		}
+		target, host, err := requestTarget(msg.URL)
+		if err != nil {
+			return err
+		}
+		head = append(head, method+" "+target+" HTTP/1.1", "Host: "+host)
+		withBody = !bodilessMethods[method]
+	} else {
+		text := http.StatusText(msg.Status)
+		if text == "" {
+			return fmt.Errorf("invalid HTTP status %d", msg.Status)
+		}
+		head = append(head, fmt.Sprintf("HTTP/1.1 %d %s", msg.Status, text))
+		// 1xx, 204 and 304 responses never have content
+		withBody = msg.Status >= 200 && msg.Status != http.StatusNoContent && msg.Status != http.StatusNotModified
+	}
+
+	if withBody {
+		head = append(head,
+			"Content-Type: "+msg.ContentType,
+			"Content-Length: "+strconv.Itoa(len(body)))
+	}
+	if msg.Method == "" {
+		sum := sha256.Sum256(body)
+		head = append(head, `ETag: "`+hex.EncodeToString(sum[:16])+`"`)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for _, line := range head {
+		w.WriteString(line + "\r\n")
+	}
+	w.WriteString("\r\n")
+	if withBody {
+		w.Write(body)
+	}
+	return w.Flush()
+}
+
+// requestTarget splits a URL into the request target and the Host header,
+// defaulting to localhost for URLs without a host.
+func requestTarget(rawURL string) (string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid request URL %q: %w", rawURL, err)
+	}
+	host := u.Host
+	if host == "" {
+		host = "localhost"
+	}
+	target := u.RequestURI()
+	if !strings.HasPrefix(target, "/") {
+		target = "/" + target
+	}
+	return target, host, nil
+}
+
+// ContentType returns the media type of a mode's output. format is the
+// value of the mode's format flag (--flow-format or --chart-format), for
+// the modes that have one.
+func ContentType(mode, format string) string {
+	switch mode {
+	case "jsonify":
+		return "application/json"
+	case "xmlify":
+		return "application/xml"
+	case "yamlify":
+		return "application/yaml"
+	case "tomlify":
+		return "application/toml"
+	case "commafy":
+		return "text/csv; charset=utf-8"
+	case "mdify":
+		return "text/markdown; charset=utf-8"
+	case "flowify":
+		switch strings.ToLower(format) {
+		case FlowMermaid:
+			return "text/vnd.mermaid; charset=utf-8"
+		case FlowDOT, "graphviz":
+			return "text/vnd.graphviz; charset=utf-8"
+		}
+	case "graphify":
+		if strings.ToLower(format) == ChartSVG {
+			return "image/svg+xml"
+		}
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// ansiEscape matches terminal escape sequences: CSI sequences such as
+// colors, and OSC sequences such as hyperlinks.
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;:?]*[ -/]*[@-~]|\x1b\\][^\x07\x1b]*(?:\x07|\x1b\\\\)")
+
+// CaptureStdout runs print with os.Stdout redirected, returning what it
+// wrote with any terminal styling removed. If print panics, os.Stdout is
+// restored and the pipe closed before the panic goes on.
+func CaptureStdout(print func() error) ([]byte, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	// Buffered, so the reader can finish even if nobody waits for it
+	done := make(chan []byte, 1)
+	go func() {
+		data, _ := io.ReadAll(r)
+		r.Close()
+		done <- data
+	}()
+
+	stdout := os.Stdout
+	os.Stdout = w
+	func() {
+		defer func() {
+			os.Stdout = stdout
+			w.Close()
+		}()
+		err = print()
+	}()
+	return ansiEscape.ReplaceAll(<-done, nil), err
+}
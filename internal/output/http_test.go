@@ -0,0 +1,107 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPrintHTTP(t *testing.T) {
+	body := []byte("{\"name\": \"日本\"}\n")
+	tests := []struct {
+		name string
+		msg  HTTPMessage
+		want string
+	}{
+		{"response", HTTPMessage{Status: 200, ContentType: "application/json"},
+			"HTTP/1.1 200 OK\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Content-Length: 19\r\n" +
+				"ETag: \"86da242ad35f5c95df8e878d98a90ef4\"\r\n" +
+				"\r\n" + string(body)},
+		{"not found", HTTPMessage{Status: 404, ContentType: "text/plain; charset=utf-8"},
+			"HTTP/1.1 404 Not Found\r\n" +
+				"Content-Type: text/plain; charset=utf-8\r\n" +
+				"Content-Length: 19\r\n" +
+				"ETag: \"86da242ad35f5c95df8e878d98a90ef4\"\r\n" +
+				"\r\n" + string(body)},
+		{"no content", HTTPMessage{Status: 204, ContentType: "application/json"},
+			"HTTP/1.1 204 No Content\r\n" +
+				"ETag: \"86da242ad35f5c95df8e878d98a90ef4\"\r\n" +
+				"\r\n"},
+		{"not modified", HTTPMessage{Status: 304, ContentType: "application/json"},
+			"HTTP/1.1 304 Not Modified\r\n" +
+				"ETag: \"86da242ad35f5c95df8e878d98a90ef4\"\r\n" +
+				"\r\n"},
+		{"informational", HTTPMessage{Status: 103, ContentType: "application/json"},
+			"HTTP/1.1 103 Early Hints\r\n" +
+				"ETag: \"86da242ad35f5c95df8e878d98a90ef4\"\r\n" +
+				"\r\n"},
+		{"post", HTTPMessage{Method: "post", URL: "https://example.com/api/graph?v=1", ContentType: "application/json"},
+			"POST /api/graph?v=1 HTTP/1.1\r\n" +
+				"Host: example.com\r\n" +
+				"Content-Type: application/json\r\n" +
+				"Content-Length: 19\r\n" +
+				"\r\n" + string(body)},
+		{"get", HTTPMessage{Method: "GET", URL: "graph", ContentType: "application/json"},
+			"GET /graph HTTP/1.1\r\n" +
+				"Host: localhost\r\n" +
+				"\r\n"},
+		{"head", HTTPMessage{Method: "HEAD", URL: "/", ContentType: "application/json"},
+			"HEAD / HTTP/1.1\r\n" +
+				"Host: localhost\r\n" +
+				"\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := render(t, func() error { return PrintHTTP(tt.msg, body) }); got != tt.want {
+				t.Errorf("PrintHTTP =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintHTTPErrors(t *testing.T) {
+	tests := []struct {
+		msg  HTTPMessage
+		want string
+	}{
+		{HTTPMessage{Status: 999}, "invalid HTTP status 999"},
+		{HTTPMessage{Method: "GET MORE"}, `invalid HTTP method "GET MORE"`},
+		{HTTPMessage{Method: "GET", URL: "http://[::1"}, `invalid request URL "http://[::1"`},
+	}
+	for _, tt := range tests {
+		_, err := CaptureStdout(func() error { return PrintHTTP(tt.msg, nil) })
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("PrintHTTP(%+v) error = %v, want %q", tt.msg, err, tt.want)
+		}
+	}
+}
+
+func TestCaptureStdout(t *testing.T) {
+	stdout := os.Stdout
+	out, err := CaptureStdout(func() error {
+		fmt.Print("\x1b[1;34mbold\x1b[0m \x1b]8;;https://example.com\x07link\x1b]8;;\x07")
+		return errors.New("printed anyway")
+	})
+	if string(out) != "bold link" || err == nil || err.Error() != "printed anyway" {
+		t.Errorf("CaptureStdout = %q, %v; want the unstyled text and print's error", out, err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("CaptureStdout swallowed a panic")
+			}
+		}()
+		CaptureStdout(func() error { panic("print failed") })
+	}()
+	if os.Stdout != stdout {
+		t.Fatalf("os.Stdout was not restored after a panic")
+	}
+	if out, err := CaptureStdout(func() error { fmt.Print("again"); return nil }); string(out) != "again" || err != nil {
+		t.Errorf("CaptureStdout after a panic = %q, %v", out, err)
+	}
+}
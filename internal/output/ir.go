@@ -0,0 +1,18 @@
+package output
+
+import (
+	"bufio"
+	"lazybox/internal/glpg"
+	"os"
+)
+
+// PrintGLPGAsIR serializes the GLPG itself in one of glpg.IRFormats (JSON
+// Graph Format by default). Output is left unstyled so it can be piped into
+// other graph tools; --min drops indentation.
+func PrintGLPGAsIR(graph *glpg.GLPG, format string, flags map[string]bool) error {
+	w := bufio.NewWriter(os.Stdout)
+	if err := glpg.Encode(w, graph, format, !flags["min"]); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -1,6 +1,7 @@
 package output
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"lazybox/internal/glpg"
@@ -8,6 +9,8 @@ import (
 	"lazybox/internal/theme"
 	"os"
 	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -59,6 +62,8 @@ type RustStyleEntry struct {
 	GitRemotes       map[string]string `json:"gitRemotes,omitempty"`
 	Contents         []RustStyleEntry  `json:"contents,omitempty"`
 	Error            *string           `json:"error,omitempty"`
+	Truncated        *bool             `json:"truncated,omitempty"`
+	SkippedEntries   *int              `json:"skippedEntries,omitempty"`
 }
 
 // Convert from *ir.FileInfo to RustStyleEntry recursively
@@ -90,11 +95,24 @@ func FileInfoToRustStyleEntry(fi *ir.FileInfo, compact bool, isRoot bool, rootPa
 	for _, child := range fi.Children {
 		contents = append(contents, FileInfoToRustStyleEntry(child, compact, false, rootPath))
 	}
+	// Truncation is reported even in compact output so omissions stay visible
+	var truncated *bool
+	var skippedEntries *int
+	if fi.Truncated {
+		t := true
+		truncated = &t
+		if fi.SkippedEntries > 0 {
+			n := fi.SkippedEntries
+			skippedEntries = &n
+		}
+	}
 	if compact {
 		return RustStyleEntry{
-			Name:     fi.Name,
-			Path:     path,
-			Contents: contents,
+			Name:           fi.Name,
+			Path:           path,
+			Contents:       contents,
+			Truncated:      truncated,
+			SkippedEntries: skippedEntries,
 		}
 	}
 	// Normal output: all fields
@@ -147,25 +165,35 @@ func FileInfoToRustStyleEntry(fi *ir.FileInfo, compact bool, isRoot bool, rootPa
 		GitRemotes:       gitRemotes,
 		Contents:         contents,
 		Error:            errorStr,
+		Truncated:        truncated,
+		SkippedEntries:   skippedEntries,
 	}
 }
 
 // PrintJSONWithHighlight prints JSON with syntax highlighting using lipgloss
 func PrintJSONWithHighlight(data []byte, minified bool) {
 	var out any
-	if err := json.Unmarshal(data, &out); err != nil {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // keep numbers exactly as marshaled
+	if err := dec.Decode(&out); err != nil {
 		fmt.Println(string(data))
 		return
 	}
 	printJSONValueWithHighlight(out, 0, minified)
 }
 
+// quoteJSON returns s as a quoted, escaped JSON string.
+func quoteJSON(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
 func printJSONValueWithHighlight(v any, indent int, minified bool) {
 	pad := func(n int) string {
 		if minified {
 			return ""
 		}
-		return string(make([]byte, n*2))
+		return strings.Repeat("  ", n)
 	}
 	switch val := v.(type) {
 	case map[string]any:
@@ -174,8 +202,15 @@ func printJSONValueWithHighlight(v any, indent int, minified bool) {
 		} else {
 			fmt.Print(jqKeyStyle.Render("{\n"))
 		}
+		// Sort keys so repeated runs produce identical, diffable output
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
 		first := true
-		for k, v2 := range val {
+		for _, k := range keys {
+			v2 := val[k]
 			if !first {
 				if minified {
 					fmt.Print(jqKeyStyle.Render(","))
@@ -185,7 +220,7 @@ func printJSONValueWithHighlight(v any, indent int, minified bool) {
 			}
 			first = false
 			fmt.Print(pad(indent + 1))
-			fmt.Print(jqKeyStyle.Render("\"" + k + "\""))
+			fmt.Print(jqKeyStyle.Render(quoteJSON(k)))
 			fmt.Print(jqKeyStyle.Render(": "))
 			printJSONValueWithHighlight(v2, indent+1, minified)
 		}
@@ -208,7 +243,9 @@ func printJSONValueWithHighlight(v any, indent int, minified bool) {
 		}
 		fmt.Print(jqKeyStyle.Render("]"))
 	case string:
-		fmt.Print(jqStringStyle.Render("\"" + val + "\""))
+		fmt.Print(jqStringStyle.Render(quoteJSON(val)))
+	case json.Number:
+		fmt.Print(jqNumStyle.Render(val.String()))
 	case float64:
 		fmt.Print(jqNumStyle.Render(fmt.Sprintf("%v", val)))
 	case bool:
@@ -0,0 +1,89 @@
+package output
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"lazybox/internal/glpg"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// specialGraph builds a small package whose labels, names, keys and values
+// hold characters the output formats must escape or measure:
+//
+//	root -CONTAINS-> dir -CONTAINS-> file -DEPENDS ON-> other -SEE-> dir
+func specialGraph(t *testing.T) *glpg.GLPG {
+	t.Helper()
+	g := glpg.NewGLPG()
+	for _, node := range []*glpg.GLPGNode{
+		{ID: "root", Labels: []string{"Package"}, Properties: glpg.GLPGProperty{
+			"Name": `demo & "co"`, "Version": 1.5}},
+		{ID: "dir", Labels: []string{"FileInfo", "directory"}, Properties: glpg.GLPGProperty{
+			"Name": "src <main>", "Path": "src", "IsDir": true, "Size": int64(64)}},
+		{ID: "file", Labels: []string{"FileInfo", "file"}, Properties: glpg.GLPGProperty{
+			"Name": "日本.go", "Path": "src/日本.go", "IsDir": false, "Size": int64(2048), "Extension": ".go",
+			"LineCount": 12, "Doc": "line one\nline two", "bad key": "x", "id": "clash",
+			"Keywords": []interface{}{
+				map[string]interface{}{"Keyword": "func", "Count": 3},
+				map[string]interface{}{"Keyword": "naïve", "Count": 1},
+			}}},
+		{ID: "other", Labels: []string{"My Label-1"}, Properties: glpg.GLPGProperty{
+			"Name": "a-b.c", "Tags": []interface{}{"x", "y"}, "Meta": map[string]interface{}{"k.1": "v"}}},
+	} {
+		g.AddNode(node)
+	}
+	for _, e := range []struct {
+		source, label, target string
+		props                 glpg.GLPGProperty
+	}{
+		{"root", "CONTAINS", "dir", glpg.GLPGProperty{"INDEX": 0}},
+		{"dir", "CONTAINS", "file", nil},
+		{"file", "DEPENDS ON", "other", glpg.GLPGProperty{"weight": 2, "path": []interface{}{"a", "b"}, "bad key": true}},
+		{"other", "SEE", "dir", glpg.GLPGProperty{"idref": "clash", "order": []interface{}{1, 2}}},
+	} {
+		edge := g.Connect(e.source, e.label, e.target)
+		if edge == nil {
+			t.Fatalf("connecting %s to %s", e.source, e.target)
+		}
+		for k, v := range e.props {
+			edge.Properties[k] = v
+		}
+	}
+	return g
+}
+
+// render returns what print writes to stdout.
+func render(t *testing.T, print func() error) string {
+	t.Helper()
+	out, err := CaptureStdout(print)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+// checkGolden compares got with testdata/name, or rewrites the file with
+// -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s:\n%s\nwant:\n%s", path, got, want)
+	}
+}
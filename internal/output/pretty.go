@@ -94,6 +94,38 @@ func PrintGLPGAsPretty(graph *glpg.GLPG, flags map[string]bool) error {
 }
 
 func renderNode(node *glpg.GLPGNode, graph *glpg.GLPG, flags map[string]bool) string {
+	var sb strings.Builder
+	sb.WriteString(renderNodeProperties(node))
+
+	outgoing := graph.GetOutgoingEdges(node.ID)
+	if len(outgoing) > 0 {
+		sb.WriteString(labelStyle.Render("Outgoing Edges:") + "\n")
+		for _, edge := range outgoing {
+			sb.WriteString(fmt.Sprintf("  %s %s %s\n",
+				edgeLabelStyle.Render("--("+edge.Label+")-->"),
+				nodeIDStyle.Render(edge.TargetID),
+				renderPropertiesInline(edge.Properties),
+			))
+		}
+	}
+
+	incoming := graph.GetIncomingEdges(node.ID)
+	if len(incoming) > 0 {
+		sb.WriteString(labelStyle.Render("Incoming Edges:") + "\n")
+		for _, edge := range incoming {
+			sb.WriteString(fmt.Sprintf("  %s %s %s\n",
+				nodeIDStyle.Render(edge.SourceID),
+				edgeLabelStyle.Render("--("+edge.Label+")-->"),
+				renderPropertiesInline(edge.Properties),
+			))
+		}
+	}
+
+	return sb.String()
+}
+
+// renderNodeProperties renders a node's ID, labels and properties.
+func renderNodeProperties(node *glpg.GLPGNode) string {
 	var sb strings.Builder
 	sb.WriteString(nodeIDStyle.Render(fmt.Sprintf("Node: %s", node.ID)))
 	if len(node.Labels) > 0 {
@@ -127,31 +159,6 @@ func renderNode(node *glpg.GLPGNode, graph *glpg.GLPG, flags map[string]bool) st
 			}
 		}
 	}
-
-	outgoing := graph.GetOutgoingEdges(node.ID)
-	if len(outgoing) > 0 {
-		sb.WriteString(labelStyle.Render("Outgoing Edges:") + "\n")
-		for _, edge := range outgoing {
-			sb.WriteString(fmt.Sprintf("  %s %s %s\n",
-				edgeLabelStyle.Render("--("+edge.Label+")-->"),
-				nodeIDStyle.Render(edge.TargetID),
-				renderPropertiesInline(edge.Properties),
-			))
-		}
-	}
-
-	incoming := graph.GetIncomingEdges(node.ID)
-	if len(incoming) > 0 {
-		sb.WriteString(labelStyle.Render("Incoming Edges:") + "\n")
-		for _, edge := range incoming {
-			sb.WriteString(fmt.Sprintf("  %s %s %s\n",
-				nodeIDStyle.Render(edge.SourceID),
-				edgeLabelStyle.Render("--("+edge.Label+")-->"),
-				renderPropertiesInline(edge.Properties),
-			))
-		}
-	}
-
 	return sb.String()
 }
 
@@ -0,0 +1,192 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"lazybox/internal/glpg"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// NodeStreamer renders nodes one at a time as they are produced, for the
+// --incremental flag. Implementations are safe for concurrent use, since
+// scanners report entries from several goroutines.
+type NodeStreamer interface {
+	WriteNode(node *glpg.GLPGNode) error
+	Close() error
+}
+
+// EdgeStreamer is implemented by node streamers that can also render edges.
+// Callers write an edge after both of its endpoints.
+type EdgeStreamer interface {
+	WriteEdge(edge *glpg.GLPGEdge) error
+}
+
+// NewNodeStreamer returns a streamer for the canonical mode name, or false
+// if the mode needs the whole graph before it can print anything.
+func NewNodeStreamer(mode string, flags map[string]bool) (NodeStreamer, bool) {
+	switch mode {
+	case "jsonify":
+		return &jsonLinesStreamer{}, true
+	case "commafy":
+		return &csvStreamer{writer: csv.NewWriter(os.Stdout)}, true
+	case "prettify":
+		return &prettyStreamer{}, true
+	}
+	return nil, false
+}
+
+// StreamGLPG writes every node of an already built graph to s in ID order,
+// then, if s is an EdgeStreamer, every edge in ID order. It lets targets
+// without incremental discovery still honor --incremental.
+func StreamGLPG(graph *glpg.GLPG, s NodeStreamer) error {
+	nodeIDs := make([]string, 0, len(graph.Nodes))
+	for id := range graph.Nodes {
+		nodeIDs = append(nodeIDs, id)
+	}
+	sort.Strings(nodeIDs)
+	for _, id := range nodeIDs {
+		if err := s.WriteNode(graph.Nodes[id]); err != nil {
+			return err
+		}
+	}
+	if es, ok := s.(EdgeStreamer); ok {
+		edgeIDs := make([]string, 0, len(graph.Edges))
+		for id := range graph.Edges {
+			edgeIDs = append(edgeIDs, id)
+		}
+		sort.Strings(edgeIDs)
+		for _, id := range edgeIDs {
+			if err := es.WriteEdge(graph.Edges[id]); err != nil {
+				return err
+			}
+		}
+	}
+	return s.Close()
+}
+
+// jsonLinesStreamer prints one JSON object per node or edge per line (JSON
+// Lines). Edge records have JSON Graph Format's source, target and relation
+// keys in place of labels.
+type jsonLinesStreamer struct {
+	mu sync.Mutex
+}
+
+func (s *jsonLinesStreamer) WriteNode(node *glpg.GLPGNode) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"id":         node.ID,
+		"labels":     node.Labels,
+		"properties": node.Properties,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal node %s: %w", node.ID, err)
+	}
+	return s.writeLine(data)
+}
+
+func (s *jsonLinesStreamer) WriteEdge(edge *glpg.GLPGEdge) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"id":         edge.ID,
+		"source":     edge.SourceID,
+		"target":     edge.TargetID,
+		"relation":   edge.Label,
+		"properties": edge.Properties,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal edge %s: %w", edge.ID, err)
+	}
+	return s.writeLine(data)
+}
+
+func (s *jsonLinesStreamer) writeLine(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := fmt.Fprintln(os.Stdout, string(data))
+	return err
+}
+
+func (s *jsonLinesStreamer) Close() error { return nil }
+
+// csvStreamer prints one CSV row per node. The columns are fixed by the
+// first node written, since the header has to be printed before any other
+// node is known; properties missing from the header are collected as
+// key=value pairs in a trailing "Other" column.
+type csvStreamer struct {
+	mu     sync.Mutex
+	writer *csv.Writer
+	keys   []string // Property columns, nil until the header is written
+}
+
+func (s *csvStreamer) WriteNode(node *glpg.GLPGNode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.keys == nil {
+		s.keys = make([]string, 0, len(node.Properties))
+		for key := range node.Properties {
+			s.keys = append(s.keys, key)
+		}
+		sort.Strings(s.keys)
+		header := append([]string{"ID", "Label"}, s.keys...)
+		header = append(header, "Other")
+		if err := s.writer.Write(header); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	}
+
+	row := make([]string, 0, len(s.keys)+3)
+	label := ""
+	if len(node.Labels) > 0 {
+		label = node.Labels[0]
+	}
+	row = append(row, node.ID, label)
+	known := make(map[string]bool, len(s.keys))
+	for _, key := range s.keys {
+		known[key] = true
+		if val, ok := node.Properties[key]; ok {
+			row = append(row, fmt.Sprintf("%v", val))
+		} else {
+			row = append(row, "")
+		}
+	}
+	var other []string
+	for key, val := range node.Properties {
+		if !known[key] {
+			other = append(other, fmt.Sprintf("%s=%v", key, val))
+		}
+	}
+	sort.Strings(other)
+	row = append(row, strings.Join(other, ";"))
+
+	if err := s.writer.Write(row); err != nil {
+		return fmt.Errorf("failed to write CSV row for node %s: %w", node.ID, err)
+	}
+	s.writer.Flush() // Flush every row so output appears immediately
+	return s.writer.Error()
+}
+
+func (s *csvStreamer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writer.Flush()
+	return s.writer.Error()
+}
+
+// prettyStreamer prints one styled box per node. Edges are not known while
+// streaming, so only the node's labels and properties are shown.
+type prettyStreamer struct {
+	mu sync.Mutex
+}
+
+func (s *prettyStreamer) WriteNode(node *glpg.GLPGNode) error {
+	box := boxStyle.Render(renderNodeProperties(node))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := fmt.Fprintln(os.Stdout, box)
+	return err
+}
+
+func (s *prettyStreamer) Close() error { return nil }
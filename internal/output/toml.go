@@ -0,0 +1,205 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"lazybox/internal/glpg"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrintGLPGAsTOML renders the GLPG as TOML, in the same shape as jsonify:
+// the fs tree for fs scans (--less keeps names and paths) and the graph
+// otherwise. Nested objects become [tables] and lists of objects become
+// [[arrays of tables]]; TOML has no null, so null values are left out.
+// --min writes nested values inline, one top-level key per line.
+func PrintGLPGAsTOML(graph *glpg.GLPG, flags map[string]bool) error {
+	doc, err := documentValue(graph, flags)
+	if err != nil {
+		return fmt.Errorf("converting graph for TOML: %w", err)
+	}
+	table, ok := doc.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("TOML documents must be tables, got %T", doc)
+	}
+	w := bufio.NewWriter(os.Stdout)
+	t := &tomlWriter{w: w, inline: flags["min"]}
+	t.writeTable(nil, table)
+	return w.Flush()
+}
+
+// tomlWriter writes plain values (as produced by documentValue) as TOML.
+type tomlWriter struct {
+	w       *bufio.Writer
+	inline  bool
+	started bool // Whether anything has been written, for blank lines between tables
+}
+
+// writeTable writes the keys of table: plain values first, then nested
+// tables and arrays of tables under headers naming their full path.
+func (t *tomlWriter) writeTable(path []string, table map[string]interface{}) {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var tables, arrays []string
+	for _, key := range keys {
+		value := table[key]
+		switch {
+		case value == nil:
+			continue
+		case t.inline:
+		case isTable(value):
+			tables = append(tables, key)
+			continue
+		case isArrayOfTables(value):
+			arrays = append(arrays, key)
+			continue
+		}
+		t.w.WriteString(tomlKey(key) + " = " + t.value(value) + "\n")
+		t.started = true
+	}
+
+	for _, key := range tables {
+		sub := append(append([]string(nil), path...), key)
+		t.header("[" + tomlPath(sub) + "]")
+		t.writeTable(sub, table[key].(map[string]interface{}))
+	}
+	for _, key := range arrays {
+		sub := append(append([]string(nil), path...), key)
+		for _, item := range table[key].([]interface{}) {
+			t.header("[[" + tomlPath(sub) + "]]")
+			t.writeTable(sub, item.(map[string]interface{}))
+		}
+	}
+}
+
+func (t *tomlWriter) header(h string) {
+	if t.started {
+		t.w.WriteString("\n")
+	}
+	t.w.WriteString(h + "\n")
+	t.started = true
+}
+
+// value formats a value on one line: scalars, [arrays] and {inline tables}.
+// Multi-line strings use TOML's multi-line strings unless writing inline.
+func (t *tomlWriter) value(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		if strings.Contains(val, "\n") && !t.inline {
+			return `"""` + "\n" + tomlEscape(val, true) + `"""`
+		}
+		return `"` + tomlEscape(val, false) + `"`
+	case bool:
+		return strconv.FormatBool(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		switch {
+		case math.IsNaN(val):
+			return "nan"
+		case math.IsInf(val, 1):
+			return "inf"
+		case math.IsInf(val, -1):
+			return "-inf"
+		}
+		s := strconv.FormatFloat(val, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".en") {
+			s += ".0"
+		}
+		return s
+	case []interface{}:
+		items := make([]string, 0, len(val))
+		for _, item := range val {
+			if item != nil {
+				items = append(items, t.value(item))
+			}
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for key, item := range val {
+			if item != nil {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		pairs := make([]string, len(keys))
+		for i, key := range keys {
+			pairs[i] = tomlKey(key) + " = " + t.value(val[key])
+		}
+		if len(pairs) == 0 {
+			return "{}"
+		}
+		return "{ " + strings.Join(pairs, ", ") + " }"
+	}
+	return `"` + tomlEscape(fmt.Sprint(v), false) + `"`
+}
+
+func isTable(v interface{}) bool {
+	_, ok := v.(map[string]interface{})
+	return ok
+}
+
+// isArrayOfTables reports whether v is a non-empty list of objects.
+func isArrayOfTables(v interface{}) bool {
+	items, ok := v.([]interface{})
+	if !ok || len(items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		if !isTable(item) {
+			return false
+		}
+	}
+	return true
+}
+
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(key string) string {
+	if tomlBareKey.MatchString(key) {
+		return key
+	}
+	return `"` + tomlEscape(key, false) + `"`
+}
+
+func tomlPath(path []string) string {
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = tomlKey(key)
+	}
+	return strings.Join(keys, ".")
+}
+
+// tomlEscape escapes s for a basic string; multi-line strings keep their
+// newlines and tabs.
+func tomlEscape(s string, multiline bool) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n' && multiline, r == '\t':
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
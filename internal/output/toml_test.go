@@ -0,0 +1,37 @@
+package output
+
+import "testing"
+
+func TestPrintGLPGAsTOML(t *testing.T) {
+	tests := []struct {
+		golden string
+		flags  map[string]bool
+	}{
+		{"graph.toml", nil},
+		{"graph.min.toml", map[string]bool{"min": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			g := specialGraph(t)
+			checkGolden(t, tt.golden, render(t, func() error { return PrintGLPGAsTOML(g, tt.flags) }))
+		})
+	}
+}
+
+func TestTOMLKey(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Name", "Name"},
+		{"my-key_2", "my-key_2"},
+		{"bad key", `"bad key"`},
+		{"k.1", `"k.1"`},
+		{"", `""`},
+		{"naïve", `"naïve"`},
+		{`say "hi"\n`, `"say \"hi\"\\n"`},
+		{"tab\there", "\"tab\there\""}, // TOML strings may hold tabs
+	}
+	for _, tt := range tests {
+		if got := tomlKey(tt.in); got != tt.want {
+			t.Errorf("tomlKey(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"lazybox/internal/glpg"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// xmlEntry is the XML form of RustStyleEntry: the fs tree, one <entry> per
+// file with scalar fields as attributes.
+type xmlEntry struct {
+	XMLName          xml.Name    `xml:"entry"`
+	Name             string      `xml:"name,attr"`
+	Path             *string     `xml:"path,attr,omitempty"`
+	AbsolutePathFull *string     `xml:"absolutePathFull,attr,omitempty"`
+	EntryTypeFull    *string     `xml:"entryTypeFull,attr,omitempty"`
+	IsSymlink        *bool       `xml:"isSymlink,attr,omitempty"`
+	SymlinkTarget    *string     `xml:"symlinkTarget,attr,omitempty"`
+	IsGitRepo        *bool       `xml:"isGitRepo,attr,omitempty"`
+	Error            *string     `xml:"error,attr,omitempty"`
+	Truncated        *bool       `xml:"truncated,attr,omitempty"`
+	SkippedEntries   *int        `xml:"skippedEntries,attr,omitempty"`
+	GitRemotes       []xmlRemote `xml:"gitRemote"`
+	Contents         []xmlEntry  `xml:"entry"`
+}
+
+type xmlRemote struct {
+	Name string `xml:"name,attr"`
+	URL  string `xml:",chardata"`
+}
+
+func rustStyleEntryToXML(e RustStyleEntry) xmlEntry {
+	x := xmlEntry{
+		Name:             e.Name,
+		Path:             e.Path,
+		AbsolutePathFull: e.AbsolutePathFull,
+		EntryTypeFull:    e.EntryTypeFull,
+		IsSymlink:        e.IsSymlink,
+		SymlinkTarget:    e.SymlinkTarget,
+		IsGitRepo:        e.IsGitRepo,
+		Error:            e.Error,
+		Truncated:        e.Truncated,
+		SkippedEntries:   e.SkippedEntries,
+	}
+	names := make([]string, 0, len(e.GitRemotes))
+	for name := range e.GitRemotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		x.GitRemotes = append(x.GitRemotes, xmlRemote{Name: name, URL: e.GitRemotes[name]})
+	}
+	for _, child := range e.Contents {
+		x.Contents = append(x.Contents, rustStyleEntryToXML(child))
+	}
+	return x
+}
+
+// PrintGLPGAsXML renders the GLPG as XML. fs scans print their tree as
+// nested <entry> elements, mirroring the JSON output (--less keeps only
+// names and paths). Other graphs print one element per node, named after
+// its first label and nested under the node it hangs from (see
+// glpg.ContainmentForest); other edges become <link idref="..."> elements.
+// --min drops indentation.
+func PrintGLPGAsXML(graph *glpg.GLPG, flags map[string]bool) error {
+	indent := !flags["min"]
+	w := bufio.NewWriter(os.Stdout)
+	w.WriteString(xml.Header)
+
+	if graph.OriginalFileInfo != nil {
+		compact := flags["less"] || flags["compact"]
+		rootPath := graph.OriginalFileInfo.AbsolutePath
+		entry := rustStyleEntryToXML(FileInfoToRustStyleEntry(graph.OriginalFileInfo, compact, true, rootPath))
+		enc := xml.NewEncoder(w)
+		if indent {
+			enc.Indent("", "  ")
+		}
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("encoding fs tree as XML: %w", err)
+		}
+		w.WriteString("\n")
+		return w.Flush()
+	}
+
+	x := &xmlGraphWriter{w: w, g: graph, forest: graph.ContainmentForest(), indent: indent}
+	x.open(0, "graph", [][2]string{{"nodes", strconv.Itoa(len(graph.Nodes))}, {"edges", strconv.Itoa(len(graph.Edges))}}, false)
+	for _, root := range x.forest.Roots {
+		x.writeNode(1, graph.Nodes[root], nil)
+	}
+	x.close(0, "graph")
+	if !indent {
+		w.WriteString("\n")
+	}
+	return w.Flush()
+}
+
+// xmlGraphWriter writes a GLPG as nested XML elements.
+type xmlGraphWriter struct {
+	w      *bufio.Writer
+	g      *glpg.GLPG
+	forest *glpg.Forest
+	indent bool
+}
+
+// Attributes every node element may carry; properties with these names, or
+// starting with the prefix of tree edge properties, are written as
+// <property> elements instead.
+var xmlReservedAttrs = map[string]bool{"id": true, "labels": true, "edge": true}
+
+const xmlEdgePrefix = "edge."
+
+func isXMLReservedAttr(key string) bool {
+	return xmlReservedAttrs[key] || strings.HasPrefix(key, xmlEdgePrefix)
+}
+
+// writeNode writes node and, nested in it, its properties, cross-links and
+// the nodes hanging from it. in is the tree edge the node hangs from.
+func (x *xmlGraphWriter) writeNode(depth int, node *glpg.GLPGNode, in *glpg.GLPGEdge) {
+	name := "node"
+	if len(node.Labels) > 0 {
+		name = xmlName(node.Labels[0])
+	}
+	attrs := [][2]string{{"id", node.ID}}
+	if len(node.Labels) > 1 {
+		attrs = append(attrs, [2]string{"labels", strings.Join(node.Labels, ";")})
+	}
+	var edgeProps []string
+	if in != nil {
+		attrs = append(attrs, [2]string{"edge", in.Label})
+		var edgeAttrs [][2]string
+		edgeAttrs, edgeProps = xmlEdgeAttrs(in.Properties, xmlEdgePrefix)
+		attrs = append(attrs, edgeAttrs...)
+	}
+
+	// Scalars become attributes; multi-line strings, composite values and
+	// keys that are not XML names become <property> elements
+	var elementProps []string
+	for _, key := range sortedPropertyKeys(node.Properties) {
+		value := node.Properties[key]
+		if s, ok := xmlScalar(value); ok && !strings.Contains(s, "\n") && isXMLName(key) && !isXMLReservedAttr(key) {
+			attrs = append(attrs, [2]string{key, s})
+		} else {
+			elementProps = append(elementProps, key)
+		}
+	}
+
+	var links []*glpg.GLPGEdge
+	for _, edge := range x.g.OutgoingEdges[node.ID] {
+		if !x.forest.IsTreeEdge(edge) && x.g.GetNode(edge.TargetID) != nil {
+			links = append(links, edge)
+		}
+	}
+	children := x.forest.Children[node.ID]
+	empty := len(edgeProps) == 0 && len(elementProps) == 0 && len(links) == 0 && len(children) == 0
+	x.open(depth, name, attrs, empty)
+	if empty {
+		return
+	}
+	for _, key := range edgeProps {
+		x.writeValue(depth+1, "edgeProperty", [][2]string{{"name", key}}, in.Properties[key])
+	}
+	for _, key := range elementProps {
+		x.writeValue(depth+1, "property", [][2]string{{"name", key}}, node.Properties[key])
+	}
+	for _, edge := range links {
+		linkAttrs, linkProps := xmlEdgeAttrs(edge.Properties, "")
+		attrs := append([][2]string{{"edge", edge.Label}, {"idref", edge.TargetID}}, linkAttrs...)
+		x.open(depth+1, "link", attrs, len(linkProps) == 0)
+		if len(linkProps) == 0 {
+			continue
+		}
+		for _, key := range linkProps {
+			x.writeValue(depth+2, "property", [][2]string{{"name", key}}, edge.Properties[key])
+		}
+		x.close(depth+1, "link")
+	}
+	for _, edge := range children {
+		x.writeNode(depth+1, x.g.Nodes[edge.TargetID], edge)
+	}
+	x.close(depth, name)
+}
+
+// writeValue writes a property value as an element: scalars as text, maps
+// as <item key="..."> children and lists as <item> children.
+func (x *xmlGraphWriter) writeValue(depth int, name string, attrs [][2]string, value interface{}) {
+	if s, ok := xmlScalar(value); ok {
+		x.pad(depth)
+		x.w.WriteString("<" + name + xmlAttrs(attrs) + ">" + xmlText(s) + "</" + name + ">")
+		x.newline()
+		return
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Map:
+		keys := make([]string, 0, v.Len())
+		values := make(map[string]interface{}, v.Len())
+		for _, k := range v.MapKeys() {
+			key := fmt.Sprint(k.Interface())
+			keys = append(keys, key)
+			values[key] = v.MapIndex(k).Interface()
+		}
+		sort.Strings(keys)
+		x.open(depth, name, attrs, len(keys) == 0)
+		if len(keys) == 0 {
+			return
+		}
+		for _, key := range keys {
+			x.writeValue(depth+1, "item", [][2]string{{"key", key}}, values[key])
+		}
+		x.close(depth, name)
+	case reflect.Slice, reflect.Array:
+		x.open(depth, name, attrs, v.Len() == 0)
+		if v.Len() == 0 {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			x.writeValue(depth+1, "item", nil, v.Index(i).Interface())
+		}
+		x.close(depth, name)
+	default:
+		// nil, or a value with no XML form
+		x.open(depth, name, attrs, true)
+	}
+}
+
+func (x *xmlGraphWriter) open(depth int, name string, attrs [][2]string, empty bool) {
+	x.pad(depth)
+	x.w.WriteString("<" + name + xmlAttrs(attrs))
+	if empty {
+		x.w.WriteString("/>")
+	} else {
+		x.w.WriteString(">")
+	}
+	x.newline()
+}
+
+func (x *xmlGraphWriter) close(depth int, name string) {
+	x.pad(depth)
+	x.w.WriteString("</" + name + ">")
+	x.newline()
+}
+
+func (x *xmlGraphWriter) pad(depth int) {
+	if x.indent {
+		x.w.WriteString(strings.Repeat("  ", depth))
+	}
+}
+
+func (x *xmlGraphWriter) newline() {
+	if x.indent {
+		x.w.WriteString("\n")
+	}
+}
+
+// xmlAttrs formats attributes, escaping their values.
+func xmlAttrs(attrs [][2]string) string {
+	var b strings.Builder
+	for _, attr := range attrs {
+		b.WriteString(" " + attr[0] + `="`)
+		xml.EscapeText(&b, []byte(attr[1]))
+		b.WriteString(`"`)
+	}
+	return b.String()
+}
+
+var xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// xmlText escapes s as element text. Unlike xml.EscapeText it keeps tabs and
+// newlines, so multi-line values such as file contents stay readable;
+// characters XML cannot hold become U+FFFD.
+func xmlText(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' || r == 0xFFFE || r == 0xFFFF {
+			return '\uFFFD'
+		}
+		return r
+	}, s)
+	return xmlTextEscaper.Replace(s)
+}
+
+// xmlEdgeAttrs returns an edge's properties as attributes, with their keys
+// prefixed; non-scalar values are encoded as JSON. The keys of properties
+// that cannot be attributes (keys that are not XML names, or clash with the
+// attributes of a <link>) are returned in elements, to be written as child
+// elements instead.
+func xmlEdgeAttrs(props glpg.GLPGProperty, prefix string) (attrs [][2]string, elements []string) {
+	for _, key := range sortedPropertyKeys(props) {
+		if !isXMLName(prefix+key) || (prefix == "" && xmlLinkAttrs[key]) {
+			elements = append(elements, key)
+			continue
+		}
+		s, ok := xmlScalar(props[key])
+		if !ok {
+			data, err := json.Marshal(props[key])
+			if err != nil {
+				data = []byte(fmt.Sprint(props[key]))
+			}
+			s = string(data)
+		}
+		attrs = append(attrs, [2]string{prefix + key, s})
+	}
+	return attrs, elements
+}
+
+// Attributes every <link> element carries.
+var xmlLinkAttrs = map[string]bool{"edge": true, "idref": true}
+
+// xmlScalar formats strings, bools and numbers, reporting false for other
+// values.
+func xmlScalar(value interface{}) (string, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), true
+	}
+	return "", false
+}
+
+func sortedPropertyKeys(props glpg.GLPGProperty) []string {
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isXMLName reports whether s can be used as an element or attribute name.
+// Colons are left out, since they denote namespaces.
+func isXMLName(s string) bool {
+	if s == "" || strings.HasPrefix(strings.ToLower(s), "xml") {
+		return false
+	}
+	for i, r := range s {
+		letter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r > 0x7f
+		if !letter && (i == 0 || !(r == '-' || r == '.' || (r >= '0' && r <= '9'))) {
+			return false
+		}
+	}
+	return true
+}
+
+// xmlName turns a label into an element name by replacing invalid
+// characters with underscores, and prefixing one if the label cannot start
+// a name (it starts with a digit, say).
+func xmlName(label string) string {
+	if isXMLName(label) {
+		return label
+	}
+	var b strings.Builder
+	for _, r := range label {
+		if isXMLName("a" + string(r)) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	name := b.String()
+	if !isXMLName(name) {
+		name = "_" + name
+	}
+	return name
+}
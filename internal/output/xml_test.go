@@ -0,0 +1,35 @@
+package output
+
+import "testing"
+
+func TestPrintGLPGAsXML(t *testing.T) {
+	tests := []struct {
+		golden string
+		flags  map[string]bool
+	}{
+		{"graph.xml", nil},
+		{"graph.min.xml", map[string]bool{"min": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			g := specialGraph(t)
+			checkGolden(t, tt.golden, render(t, func() error { return PrintGLPGAsXML(g, tt.flags) }))
+		})
+	}
+}
+
+func TestXMLName(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"FileInfo", "FileInfo"},
+		{"My Label-1", "My_Label-1"},
+		{"1st", "_1st"},
+		{"xmlThing", "_xmlThing"},
+		{"日本", "日本"},
+		{"a:b", "a_b"},
+	}
+	for _, tt := range tests {
+		if got := xmlName(tt.in); got != tt.want {
+			t.Errorf("xmlName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
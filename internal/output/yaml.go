@@ -0,0 +1,106 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"lazybox/internal/glpg"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// documentValue returns the value jsonify prints for graph, decoded into
+// plain maps, slices and scalars so other encoders produce the same shape:
+// the Rust-style tree for fs scans (compact with --less), otherwise the
+// graph itself.
+func documentValue(graph *glpg.GLPG, flags map[string]bool) (interface{}, error) {
+	if graph.OriginalFileInfo != nil {
+		compact := flags["less"] || flags["compact"]
+		rootPath := graph.OriginalFileInfo.AbsolutePath
+		return plainValue(FileInfoToRustStyleEntry(graph.OriginalFileInfo, compact, true, rootPath))
+	}
+	return plainValue(graph)
+}
+
+func plainValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // keep numbers exactly as marshaled
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return typedNumbers(out), nil
+}
+
+// typedNumbers replaces the json.Numbers in v with int64s, or float64s for
+// numbers that are not integers.
+func typedNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = typedNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = typedNumbers(item)
+		}
+	}
+	return v
+}
+
+// PrintGLPGAsYAML renders the GLPG as YAML, in the same shape as jsonify:
+// the fs tree for fs scans (--less keeps names and paths) and the graph
+// otherwise. With --all, fs scans print the tree and the graph as two
+// documents. Every document starts with "---", so the output of several
+// runs concatenates into one multi-document stream. --min writes each
+// document on one line in flow style.
+func PrintGLPGAsYAML(graph *glpg.GLPG, flags map[string]bool) error {
+	doc, err := documentValue(graph, flags)
+	if err != nil {
+		return fmt.Errorf("converting graph for YAML: %w", err)
+	}
+	docs := []interface{}{doc}
+	if graph.OriginalFileInfo != nil && flags["all"] {
+		g, err := plainValue(graph)
+		if err != nil {
+			return fmt.Errorf("converting graph for YAML: %w", err)
+		}
+		docs = append(docs, g)
+	}
+	return writeYAMLDocuments(docs, flags["min"])
+}
+
+func writeYAMLDocuments(docs []interface{}, flow bool) error {
+	w := bufio.NewWriter(os.Stdout)
+	for _, doc := range docs {
+		var node yaml.Node
+		if err := node.Encode(doc); err != nil {
+			return fmt.Errorf("encoding YAML: %w", err)
+		}
+		if flow {
+			node.Style = yaml.FlowStyle
+		}
+		w.WriteString("---\n")
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(&node); err != nil {
+			return fmt.Errorf("encoding YAML: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return fmt.Errorf("encoding YAML: %w", err)
+		}
+	}
+	return w.Flush()
+}
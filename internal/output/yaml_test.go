@@ -0,0 +1,19 @@
+package output
+
+import "testing"
+
+func TestPrintGLPGAsYAML(t *testing.T) {
+	tests := []struct {
+		golden string
+		flags  map[string]bool
+	}{
+		{"graph.yaml", nil},
+		{"graph.min.yaml", map[string]bool{"min": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			g := specialGraph(t)
+			checkGolden(t, tt.golden, render(t, func() error { return PrintGLPGAsYAML(g, tt.flags) }))
+		})
+	}
+}
@@ -1,11 +1,101 @@
 package pkg
 
 import (
+	"bytes"
+	"lazybox/internal/file"
 	"lazybox/internal/fs"
 	"lazybox/internal/ir"
+	"lazybox/internal/text"
+	"net/http"
+	"sync"
+	"unicode/utf8"
 )
 
-// Crawl is a placeholder for package crawling. For now, just uses fs.Scan.
-func Crawl(path string) (*ir.FileInfo, error) {
-	return fs.Scan(path)
+// sniffLen is how many leading bytes are inspected to decide if a file is binary.
+const sniffLen = 8000
+
+// Crawl scans the directory at path and attaches the content and text
+// analysis of every text file to the resulting tree. opts are passed to the
+// filesystem scan, so ignored files are never read. Files are read as they
+// are discovered, before opts.OnEntry is called for them.
+func Crawl(path string, opts fs.Options) (*ir.PackageInfo, error) {
+	pkgInfo := &ir.PackageInfo{}
+	var mu sync.Mutex // Guards the pkgInfo counters; entries arrive concurrently
+	onEntry := opts.OnEntry
+	opts.OnEntry = func(fi, parent *ir.FileInfo) {
+		crawlFile(fi, pkgInfo, &mu, opts.MaxFileSize)
+		if onEntry != nil {
+			onEntry(fi, parent)
+		}
+	}
+
+	root, err := fs.ScanWithOptions(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	pkgInfo.Name = root.Name
+	pkgInfo.Path = root.AbsolutePath
+	pkgInfo.Root = root
+	return pkgInfo, nil
+}
+
+// crawlFile reads a regular file, attaching its content and text analysis
+// and updating the package counters. At most maxSize bytes are read
+// (0 for unlimited).
+func crawlFile(fi *ir.FileInfo, pkgInfo *ir.PackageInfo, mu *sync.Mutex, maxSize int64) {
+	if fi.Type != ir.FileTypeFile || fi.Error != "" {
+		return
+	}
+	mu.Lock()
+	pkgInfo.FileCount++
+	pkgInfo.TotalSize += fi.Size
+	mu.Unlock()
+
+	contentBytes, truncated, err := file.ReadContent(fi.AbsolutePath, maxSize)
+	if err != nil {
+		fi.Error = err.Error()
+		return
+	}
+	fi.Truncated = truncated
+	mimeType := http.DetectContentType(contentBytes)
+	if IsBinary(contentBytes, truncated) {
+		mu.Lock()
+		pkgInfo.BinaryFileCount++
+		mu.Unlock()
+		fi.TextAnalysis = &ir.TextInfo{IsBinary: true, MimeType: mimeType}
+		return
+	}
+
+	mu.Lock()
+	pkgInfo.TextFileCount++
+	mu.Unlock()
+	content := string(contentBytes)
+	fi.SetContent(content)
+	analysis, err := text.Analyze(content, fi.Path)
+	if err != nil {
+		fi.Error = err.Error()
+		return
+	}
+	analysis.MimeType = mimeType
+	analysis.Encoding = "utf-8"
+	fi.TextAnalysis = analysis
+}
+
+// IsBinary reports whether data looks like binary content: it contains a NUL
+// byte or is not valid UTF-8 within the first sniffLen bytes. truncated
+// reports whether data was cut short by a read limit.
+func IsBinary(data []byte, truncated bool) bool {
+	if len(data) > sniffLen {
+		data = data[:sniffLen]
+		truncated = true
+	}
+	// Don't reject a valid multi-byte rune cut off at the sniff boundary
+	// or by a read limit; anywhere else an incomplete rune is invalid.
+	for i := 0; truncated && i < utf8.UTFMax-1 && len(data) > 0 && !utf8.Valid(data); i++ {
+		data = data[:len(data)-1]
+	}
+	if bytes.IndexByte(data, 0) != -1 {
+		return true
+	}
+	return !utf8.Valid(data)
 }
@@ -0,0 +1,297 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"lazybox/internal/glpg"
+)
+
+// Eval runs the query against g and returns a new graph holding the matched
+// nodes and the edges between them. Nodes are copied, so projections don't
+// modify g.
+func (q *Query) Eval(g *glpg.GLPG) (*glpg.GLPG, error) {
+	current := q.matchSet(g)
+	result := glpg.NewGLPG()
+	for _, id := range sortedIDs(current) {
+		node := g.GetNode(id)
+		result.AddNode(&glpg.GLPGNode{
+			ID:         node.ID,
+			Labels:     append([]string(nil), node.Labels...),
+			Properties: q.project(node.Properties),
+		})
+	}
+	for _, id := range sortedIDs(current) {
+		for _, edge := range g.GetOutgoingEdges(id) {
+			if current[edge.TargetID] {
+				copied := *edge
+				if err := result.AddEdge(&copied); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// Match returns the IDs of the nodes matched by the last pattern, sorted.
+func (q *Query) Match(g *glpg.GLPG) []string {
+	return sortedIDs(q.matchSet(g))
+}
+
+// matchSet returns the set of nodes matched by the last pattern.
+func (q *Query) matchSet(g *glpg.GLPG) map[string]bool {
+	current := make(map[string]bool)
+	for id, node := range g.Nodes {
+		if q.Steps[0].Match(node) {
+			current[id] = true
+		}
+	}
+	for i, hop := range q.Hops {
+		reached := hop.follow(g, current)
+		current = make(map[string]bool, len(reached))
+		for id := range reached {
+			if node := g.GetNode(id); node != nil && q.Steps[i+1].Match(node) {
+				current[id] = true
+			}
+		}
+	}
+	return current
+}
+
+// project copies props, keeping only the projected properties if any.
+func (q *Query) project(props glpg.GLPGProperty) glpg.GLPGProperty {
+	out := make(glpg.GLPGProperty, len(props))
+	if q.Projection == nil {
+		for k, v := range props {
+			out[k] = v
+		}
+		return out
+	}
+	for _, name := range q.Projection {
+		if key, v, ok := lookup(props, name); ok {
+			out[key] = v
+		}
+	}
+	return out
+}
+
+// follow returns the nodes reached from the given nodes over the hop.
+func (h Hop) follow(g *glpg.GLPG, from map[string]bool) map[string]bool {
+	reached := make(map[string]bool)
+	frontier := sortedIDs(from)
+	for len(frontier) > 0 {
+		var next []string
+		for _, id := range frontier {
+			for _, target := range h.neighbors(g, id) {
+				if reached[target] {
+					continue
+				}
+				reached[target] = true
+				if h.Transitive {
+					next = append(next, target)
+				}
+			}
+		}
+		frontier = next
+	}
+	return reached
+}
+
+// neighbors returns the nodes one matching edge away from id.
+func (h Hop) neighbors(g *glpg.GLPG, id string) []string {
+	var ids []string
+	if h.Direction == Outgoing || h.Direction == Both {
+		for _, edge := range g.GetOutgoingEdges(id) {
+			if h.matchLabel(edge) {
+				ids = append(ids, edge.TargetID)
+			}
+		}
+	}
+	if h.Direction == Incoming || h.Direction == Both {
+		for _, edge := range g.GetIncomingEdges(id) {
+			if h.matchLabel(edge) {
+				ids = append(ids, edge.SourceID)
+			}
+		}
+	}
+	return ids
+}
+
+func (h Hop) matchLabel(edge *glpg.GLPGEdge) bool {
+	return h.Label == "" || strings.EqualFold(h.Label, edge.Label)
+}
+
+// Match reports whether node satisfies the pattern.
+func (s Step) Match(node *glpg.GLPGNode) bool {
+	if s.Label != "" && !hasLabel(node, s.Label) {
+		return false
+	}
+	for _, pred := range s.Predicates {
+		if !pred.Match(node) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasLabel(node *glpg.GLPGNode, label string) bool {
+	for _, l := range node.Labels {
+		if strings.EqualFold(l, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether node satisfies the predicate. A predicate on @label
+// holds if any label satisfies it.
+func (p Predicate) Match(node *glpg.GLPGNode) bool {
+	switch strings.ToLower(p.Key) {
+	case "@id":
+		return p.test(node.ID)
+	case "@label", "@labels":
+		for _, l := range node.Labels {
+			if p.test(l) {
+				return true
+			}
+		}
+		return false
+	}
+	_, v, ok := lookup(node.Properties, p.Key)
+	if !ok {
+		return false
+	}
+	return p.test(v)
+}
+
+// test applies the predicate to a property value.
+func (p Predicate) test(v interface{}) bool {
+	if p.Op == "" {
+		return !isEmpty(v)
+	}
+	s := fmt.Sprint(v)
+	if p.Value.IsNum {
+		if n, ok := toNumber(v); ok {
+			return compareNumbers(p.Op, n, p.Value.Num) || p.stringOp(s)
+		}
+	}
+	switch p.Op {
+	case "=":
+		return s == p.Value.Raw
+	case "!=":
+		return s != p.Value.Raw
+	case "<":
+		return s < p.Value.Raw
+	case "<=":
+		return s <= p.Value.Raw
+	case ">":
+		return s > p.Value.Raw
+	case ">=":
+		return s >= p.Value.Raw
+	}
+	return p.stringOp(s)
+}
+
+// stringOp applies the string-only operators.
+func (p Predicate) stringOp(s string) bool {
+	switch p.Op {
+	case "~":
+		return p.Value.Pattern.MatchString(s)
+	case "^=":
+		return strings.HasPrefix(s, p.Value.Raw)
+	case "$=":
+		return strings.HasSuffix(s, p.Value.Raw)
+	case "*=":
+		return strings.Contains(s, p.Value.Raw)
+	}
+	return false
+}
+
+func compareNumbers(op string, a, b float64) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+// toNumber converts numeric property values, and numeric strings, to float64.
+func toNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// lookup finds a property by name, falling back to a case-insensitive match.
+func lookup(props glpg.GLPGProperty, name string) (string, interface{}, bool) {
+	if v, ok := props[name]; ok {
+		return name, v, true
+	}
+	for k, v := range props {
+		if strings.EqualFold(k, name) {
+			return k, v, true
+		}
+	}
+	return "", nil, false
+}
+
+func isEmpty(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case bool:
+		return !val
+	}
+	return false
+}
+
+func sortedIDs(set map[string]bool) []string {
+	ids := make([]string, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
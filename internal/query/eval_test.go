@@ -0,0 +1,130 @@
+package query
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"lazybox/internal/glpg"
+)
+
+// testGraph builds a small package tree:
+//
+//	pkg -CONTAINS-> root -CONTAINS-> main.go, main_test.go, docs -CONTAINS-> README.md
+//	main.go -IMPORTS-> fmt
+func testGraph(t *testing.T) *glpg.GLPG {
+	t.Helper()
+	g := glpg.NewGLPG()
+	nodes := []*glpg.GLPGNode{
+		{ID: "pkg", Labels: []string{"PackageInfo"}, Properties: glpg.GLPGProperty{"Name": "demo"}},
+		{ID: "root", Labels: []string{"FileInfo", "directory"}, Properties: glpg.GLPGProperty{"Name": "demo", "Size": int64(4096), "IsDir": true}},
+		{ID: "main", Labels: []string{"FileInfo", "file"}, Properties: glpg.GLPGProperty{"Name": "main.go", "Extension": ".go", "Size": int64(3000), "Mode": "-rw-r--r--"}},
+		{ID: "test", Labels: []string{"FileInfo", "file"}, Properties: glpg.GLPGProperty{"Name": "main_test.go", "Extension": ".go", "Size": int64(1200)}},
+		{ID: "docs", Labels: []string{"FileInfo", "directory"}, Properties: glpg.GLPGProperty{"Name": "docs", "Size": int64(4096), "IsDir": true}},
+		{ID: "readme", Labels: []string{"FileInfo", "file"}, Properties: glpg.GLPGProperty{"Name": "README.md", "Extension": ".md", "Size": "512", "Owner": ""}},
+		{ID: "fmt", Labels: []string{"Import"}, Properties: glpg.GLPGProperty{"Path": "fmt"}},
+	}
+	for _, node := range nodes {
+		g.AddNode(node)
+	}
+	for _, e := range [][3]string{
+		{"pkg", "CONTAINS", "root"},
+		{"root", "CONTAINS", "main"},
+		{"root", "CONTAINS", "test"},
+		{"root", "CONTAINS", "docs"},
+		{"docs", "CONTAINS", "readme"},
+		{"main", "IMPORTS", "fmt"},
+	} {
+		if g.Connect(e[0], e[1], e[2]) == nil {
+			t.Fatalf("connecting %v", e)
+		}
+	}
+	return g
+}
+
+func TestMatch(t *testing.T) {
+	g := testGraph(t)
+	tests := []struct {
+		query string
+		want  string // matched IDs, sorted and space-separated
+	}{
+		{"Import", "fmt"},
+		{"fileinfo[@label=file]", "main readme test"},
+		{"*[@id^=ma]", "main"},
+		{`FileInfo[Extension=".go"]`, "main test"},
+		{"FileInfo[Size>2KB]", "docs main root"},
+		{"FileInfo[Size<1KB]", "readme"},
+		{"FileInfo[Size!=4096]", "main readme test"},
+		{`FileInfo[Name~"_test\\.go$"]`, "test"},
+		{`FileInfo[name$=".md"]`, "readme"},
+		{`FileInfo[Name*="ain"]`, "main test"},
+		{"FileInfo[IsDir]", "docs root"},
+		{"FileInfo[Owner]", ""},
+		{"FileInfo[Mode]", "main"},
+		{"PackageInfo-CONTAINS->*", "root"},
+		{"PackageInfo-CONTAINS*->FileInfo[@label=file]", "main readme test"},
+		{"FileInfo[Name=docs]-CONTAINS*->*", "readme"},
+		{"Import<-IMPORTS-*", "main"},
+		{"*[Name=README.md]<-CONTAINS*-*", "docs pkg root"},
+		{"Import-IMPORTS-*", "main"},
+		{"Import-CONTAINS-*", ""},
+		{"FileInfo[Name=main.go]->*", "fmt"},
+		{"FileInfo[Name=docs]-CONTAINS-*", "readme root"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.query, err)
+			}
+			if got := strings.Join(q.Match(g), " "); got != tt.want {
+				t.Errorf("Match(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEval(t *testing.T) {
+	g := testGraph(t)
+	q, err := Parse("PackageInfo-CONTAINS*->FileInfo[Size>=1000] {name, Extension}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := q.Eval(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for id := range result.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if got, want := strings.Join(ids, " "), "docs main root test"; got != want {
+		t.Fatalf("Eval kept nodes %q, want %q", got, want)
+	}
+
+	// Only edges between kept nodes survive
+	var edges []string
+	for _, edge := range result.Edges {
+		edges = append(edges, edge.SourceID+"->"+edge.TargetID)
+	}
+	sort.Strings(edges)
+	if got, want := strings.Join(edges, " "), "root->docs root->main root->test"; got != want {
+		t.Errorf("Eval kept edges %q, want %q", got, want)
+	}
+
+	// The projection keeps the listed properties under their own names
+	main := result.GetNode("main").Properties
+	if len(main) != 2 || main["Name"] != "main.go" || main["Extension"] != ".go" {
+		t.Errorf("projected main.go properties = %v, want Name and Extension", main)
+	}
+	if docs := result.GetNode("docs").Properties; len(docs) != 1 || docs["Name"] != "docs" {
+		t.Errorf("projected docs properties = %v, want only Name", docs)
+	}
+
+	// The source graph is left alone
+	if len(g.GetNode("main").Properties) != 4 {
+		t.Errorf("Eval modified the source graph: %v", g.GetNode("main").Properties)
+	}
+}
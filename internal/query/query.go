@@ -0,0 +1,390 @@
+// Package query implements a small path/pattern language for selecting parts
+// of a GLPG.
+//
+// A query is a path of node patterns joined by edge patterns, optionally
+// followed by a projection:
+//
+//	FileInfo[Extension=".go", Size>2KB]
+//	PackageInfo-CONTAINS*->FileInfo[Name~"_test\.go$"]
+//	TypeInfo<-METHOD_OF-FuncInfo {Name, Signature}
+//
+// A node pattern is a label (or * for any node) with optional predicates in
+// brackets, all of which must hold. A predicate is a property name, an
+// operator and a value; a bare property name only checks that the property
+// is present and non-empty. Property names match case-insensitively, and
+// @id and @label refer to the node ID and labels. Operators are = != < <= >
+// >= ~ (regular expression), ^= (prefix), $= (suffix) and *= (contains).
+// Values are quoted strings, numbers (with an optional KB, MB or GB size
+// suffix), true/false or bare words.
+//
+// An edge pattern is -LABEL-> (outgoing), <-LABEL- (incoming) or -LABEL-
+// (either direction); the label may be omitted to follow any edge, and a
+// trailing * (as in -CONTAINS*->) follows one or more edges of that kind.
+//
+// The result holds the nodes matched by the last pattern and the edges between
+// them. A projection in braces keeps only the listed properties.
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed query.
+type Query struct {
+	Steps      []Step   // node patterns; len(Steps) == len(Hops)+1
+	Hops       []Hop    // edge patterns between consecutive steps
+	Projection []string // properties to keep, or nil for all
+}
+
+// Step is a node pattern.
+type Step struct {
+	Label      string // "" matches any label
+	Predicates []Predicate
+}
+
+// Direction is the direction an edge pattern follows.
+type Direction int
+
+const (
+	Outgoing Direction = iota
+	Incoming
+	Both
+)
+
+// Hop is an edge pattern.
+type Hop struct {
+	Label      string // "" matches any edge
+	Direction  Direction
+	Transitive bool // follow one or more edges
+}
+
+// Predicate is a property test in a node pattern.
+type Predicate struct {
+	Key   string
+	Op    string // "" tests for presence
+	Value Value
+}
+
+// Value is a predicate operand.
+type Value struct {
+	Raw     string
+	Num     float64
+	IsNum   bool
+	Pattern *regexp.Regexp // for ~
+}
+
+// token kinds produced by the lexer
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokPunct
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lex splits a query into tokens. Inside brackets "-" and "<" are operators
+// or signs, outside they start edge patterns.
+func lex(src string) ([]token, error) {
+	var toks []token
+	depth := 0
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			text := src[i+1 : j]
+			if c == '"' {
+				unquoted, err := strconv.Unquote(src[i : j+1])
+				if err == nil {
+					text = unquoted
+				}
+			}
+			toks = append(toks, token{tokString, text, i})
+			i = j + 1
+		case depth > 0 && strings.ContainsRune("=!<>~^$*", rune(c)):
+			op := string(c)
+			if i+1 < len(src) && src[i+1] == '=' && c != '=' && c != '~' {
+				op += "="
+			}
+			if op == "!" || op == "^" || op == "$" || op == "*" {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			toks = append(toks, token{tokOp, op, i})
+			i += len(op)
+		case depth == 0 && strings.HasPrefix(src[i:], "->"):
+			toks = append(toks, token{tokPunct, "->", i})
+			i += 2
+		case depth == 0 && strings.HasPrefix(src[i:], "<-"):
+			toks = append(toks, token{tokPunct, "<-", i})
+			i += 2
+		case strings.ContainsRune("[]{},*-", rune(c)):
+			if c == '[' {
+				depth++
+			} else if c == ']' {
+				depth--
+			}
+			toks = append(toks, token{tokPunct, string(c), i})
+			i++
+		case isIdentByte(c):
+			j := i
+			for j < len(src) && isIdentByte(src[j]) {
+				j++
+			}
+			toks = append(toks, token{tokIdent, src[i:j], i})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected %q at %d", c, i)
+		}
+	}
+	return append(toks, token{tokEOF, "", len(src)}), nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '.' || c == '/' || c == '@' || c >= 0x80 ||
+		unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+// parser is a recursive-descent parser over the token stream.
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(text string) bool {
+	if t := p.peek(); (t.kind == tokPunct || t.kind == tokOp) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		t := p.peek()
+		return fmt.Errorf("expected %q at %d, found %q", text, t.pos, t.text)
+	}
+	return nil
+}
+
+// Parse parses a query.
+func Parse(src string) (*Query, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	p := &parser{toks: toks}
+	q, err := p.parseQuery()
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	return q, nil
+}
+
+func (p *parser) parseQuery() (*Query, error) {
+	q := &Query{}
+	step, err := p.parseStep()
+	if err != nil {
+		return nil, err
+	}
+	q.Steps = append(q.Steps, step)
+	for {
+		hop, ok, err := p.parseHop()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		step, err := p.parseStep()
+		if err != nil {
+			return nil, err
+		}
+		q.Hops = append(q.Hops, hop)
+		q.Steps = append(q.Steps, step)
+	}
+	if p.accept("{") {
+		for {
+			t := p.next()
+			if t.kind != tokIdent {
+				return nil, fmt.Errorf("expected property name at %d", t.pos)
+			}
+			q.Projection = append(q.Projection, t.text)
+			if p.accept("}") {
+				break
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+	return q, nil
+}
+
+func (p *parser) parseStep() (Step, error) {
+	var step Step
+	t := p.next()
+	switch {
+	case t.kind == tokIdent:
+		step.Label = t.text
+	case t.kind == tokPunct && t.text == "*":
+	default:
+		return step, fmt.Errorf("expected a label or * at %d", t.pos)
+	}
+	if !p.accept("[") {
+		return step, nil
+	}
+	for {
+		pred, err := p.parsePredicate()
+		if err != nil {
+			return step, err
+		}
+		step.Predicates = append(step.Predicates, pred)
+		if p.accept("]") {
+			return step, nil
+		}
+		if err := p.expect(","); err != nil {
+			return step, err
+		}
+	}
+}
+
+func (p *parser) parsePredicate() (Predicate, error) {
+	var pred Predicate
+	t := p.next()
+	if t.kind != tokIdent && t.kind != tokString {
+		return pred, fmt.Errorf("expected property name at %d", t.pos)
+	}
+	pred.Key = t.text
+	if p.peek().kind != tokOp {
+		return pred, nil
+	}
+	pred.Op = p.next().text
+	value, err := p.parseValue()
+	if err != nil {
+		return pred, err
+	}
+	if pred.Op == "~" {
+		value.Pattern, err = regexp.Compile(value.Raw)
+		if err != nil {
+			return pred, err
+		}
+	}
+	pred.Value = value
+	return pred, nil
+}
+
+func (p *parser) parseValue() (Value, error) {
+	negative := p.accept("-")
+	t := p.next()
+	if t.kind == tokString && !negative {
+		return Value{Raw: t.text}, nil
+	}
+	if t.kind != tokIdent {
+		return Value{}, fmt.Errorf("expected a value at %d", t.pos)
+	}
+	v := Value{Raw: t.text}
+	if n, ok := parseNumber(t.text); ok {
+		v.Num, v.IsNum = n, true
+		if negative {
+			v.Num = -n
+			v.Raw = "-" + v.Raw
+		}
+	} else if negative {
+		return Value{}, fmt.Errorf("expected a number at %d", t.pos)
+	}
+	return v, nil
+}
+
+// parseNumber parses a number with an optional B, KB, MB or GB suffix.
+func parseNumber(s string) (float64, bool) {
+	multipliers := []struct {
+		suffix string
+		factor float64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"B", 1}}
+	factor := 1.0
+	upper := strings.ToUpper(s)
+	for _, m := range multipliers {
+		if strings.HasSuffix(upper, m.suffix) {
+			upper = strings.TrimSuffix(upper, m.suffix)
+			factor = m.factor
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(upper, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n * factor, true
+}
+
+func (p *parser) parseHop() (Hop, bool, error) {
+	var hop Hop
+	switch {
+	case p.accept("-"):
+		p.parseHopLabel(&hop)
+		switch {
+		case p.accept("->"):
+			hop.Direction = Outgoing
+		case p.accept("-"):
+			hop.Direction = Both
+		default:
+			t := p.peek()
+			return hop, false, fmt.Errorf("expected -> or - at %d", t.pos)
+		}
+	case p.accept("<-"):
+		p.parseHopLabel(&hop)
+		if err := p.expect("-"); err != nil {
+			return hop, false, err
+		}
+		hop.Direction = Incoming
+	case p.accept("->"):
+		// "->" right after a step follows any outgoing edge
+		hop.Direction = Outgoing
+	default:
+		return hop, false, nil
+	}
+	return hop, true, nil
+}
+
+func (p *parser) parseHopLabel(hop *Hop) {
+	if t := p.peek(); t.kind == tokIdent {
+		hop.Label = p.next().text
+	}
+	hop.Transitive = p.accept("*")
+}
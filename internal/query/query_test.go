@@ -0,0 +1,125 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// describe formats a parsed query so tests can compare it as a string:
+// steps as Label[key op raw(num)], hops as (dir LABEL*) and the projection
+// in braces.
+func describe(q *Query) string {
+	var b strings.Builder
+	for i, step := range q.Steps {
+		if i > 0 {
+			hop := q.Hops[i-1]
+			dir := map[Direction]string{Outgoing: "out", Incoming: "in", Both: "both"}[hop.Direction]
+			fmt.Fprintf(&b, " (%s %s", dir, hop.Label)
+			if hop.Transitive {
+				b.WriteString("*")
+			}
+			b.WriteString(") ")
+		}
+		label := step.Label
+		if label == "" {
+			label = "*"
+		}
+		b.WriteString(label)
+		if len(step.Predicates) == 0 {
+			continue
+		}
+		var preds []string
+		for _, pred := range step.Predicates {
+			s := pred.Key
+			if pred.Op != "" {
+				s += " " + pred.Op + " " + pred.Value.Raw
+				if pred.Value.IsNum {
+					s += fmt.Sprintf("(%g)", pred.Value.Num)
+				}
+			}
+			preds = append(preds, s)
+		}
+		b.WriteString("[" + strings.Join(preds, ", ") + "]")
+	}
+	if q.Projection != nil {
+		b.WriteString(" {" + strings.Join(q.Projection, ", ") + "}")
+	}
+	return b.String()
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"label", "FileInfo", "FileInfo"},
+		{"any node", "*", "*"},
+		{"presence", "FileInfo[Content]", "FileInfo[Content]"},
+		{"string value", `FileInfo[Extension=".go"]`, "FileInfo[Extension = .go]"},
+		{"single quotes", `FileInfo[Name='a b']`, "FileInfo[Name = a b]"},
+		{"bare word", "FileInfo[Type=file]", "FileInfo[Type = file]"},
+		{"size suffix", "FileInfo[Size>2KB]", "FileInfo[Size > 2KB(2048)]"},
+		{"negative number", "Node[Delta>=-3]", "Node[Delta >= -3(-3)]"},
+		{"several predicates", `FileInfo[Extension=".go", Size<=1MB]`, "FileInfo[Extension = .go, Size <= 1MB(1.048576e+06)]"},
+		{"string operators", `*[Name^="a", Name$=".go", Name*="b", Name!="c"]`, "*[Name ^= a, Name $= .go, Name *= b, Name != c]"},
+		{"regexp", `FileInfo[Name~"_test\\.go$"]`, `FileInfo[Name ~ _test\.go$]`},
+		{"special keys", `*[@id^="FileInfo_", @label=file]`, "*[@id ^= FileInfo_, @label = file]"},
+		{"outgoing", "PackageInfo-CONTAINS->FileInfo", "PackageInfo (out CONTAINS) FileInfo"},
+		{"incoming", "TypeInfo<-METHOD_OF-FuncInfo", "TypeInfo (in METHOD_OF) FuncInfo"},
+		{"either direction", "A-LINK-B", "A (both LINK) B"},
+		{"transitive", "PackageInfo-CONTAINS*->FileInfo", "PackageInfo (out CONTAINS*) FileInfo"},
+		{"any edge", "A-->B", "A (out ) B"},
+		{"bare arrow", "A->B", "A (out ) B"},
+		{"three steps", "A->B<-C-D", "A (out ) B (in C) D"},
+		{"projection", "FuncInfo {Name, Signature}", "FuncInfo {Name, Signature}"},
+		{"predicates and projection", "FileInfo[Size>0] {Name}", "FileInfo[Size > 0(0)] {Name}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.query, err)
+			}
+			if got := describe(q); got != tt.want {
+				t.Errorf("Parse(%q) = %s, want %s", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string // substring of the error
+	}{
+		{"empty", "", "expected a label or *"},
+		{"unterminated string", `FileInfo[Name="a]`, "unterminated string"},
+		{"unclosed predicates", "FileInfo[Size>1", `expected ","`},
+		{"missing value", "FileInfo[Size>]", "expected a value"},
+		{"missing property", "FileInfo[=1]", "expected property name"},
+		{"bad operator", "FileInfo[Size!1]", `unexpected '!'`},
+		{"bad regexp", `FileInfo[Name~"("]`, "missing closing )"},
+		{"negative word", "FileInfo[Size>-abc]", "expected a number"},
+		{"dangling hop", "A-CONTAINS", "expected -> or -"},
+		{"hop without target", "A->", "expected a label or *"},
+		{"incoming without dash", "A<-B", `expected "-"`},
+		{"unclosed projection", "A {Name", `expected ","`},
+		{"empty projection", "A {}", "expected property name"},
+		{"trailing input", "A B", `unexpected "B"`},
+		{"unexpected character", "A;", "unexpected ';'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			if err == nil {
+				t.Fatalf("Parse(%q) = %s, want an error", tt.query, describe(q))
+			}
+			if !strings.HasPrefix(err.Error(), "query: ") || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse(%q) error = %q, want it to contain %q", tt.query, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package rewrite
+
+import (
+	"fmt"
+	"strings"
+
+	"lazybox/internal/glpg"
+	"lazybox/internal/query"
+)
+
+// Delete modes for Spec.Delete.
+const (
+	DeleteNode    = "node"    // remove the matched node and its edges
+	DeleteSubtree = "subtree" // also remove everything reachable from it
+)
+
+// Spec describes a rule: a query selecting nodes and one action to apply to
+// each of them. In a rules file a spec may instead name a built-in rule.
+type Spec struct {
+	Name     string          `yaml:"name"`
+	Builtin  string          `yaml:"builtin,omitempty"`
+	Match    string          `yaml:"match"`
+	Relabel  *RelabelAction  `yaml:"relabel,omitempty"`
+	Delete   string          `yaml:"delete,omitempty"`
+	Collapse *CollapseAction `yaml:"collapse,omitempty"`
+	Hoist    *HoistAction    `yaml:"hoist,omitempty"`
+}
+
+// RelabelAction renames the label From to To, or replaces every label with
+// To if From is empty.
+type RelabelAction struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+// CollapseAction removes matched nodes that sit in the middle of a chain,
+// that is nodes with exactly one incoming and one outgoing edge (of label
+// Edge, if set), and links their neighbours directly. The IDs of the removed
+// nodes are kept in the new edge's Collapsed property.
+type CollapseAction struct {
+	Edge string `yaml:"edge"`
+}
+
+// HoistAction copies properties from the nodes reached over outgoing Edge
+// edges onto the matched node, prefixed with Prefix. All properties are
+// copied if Properties is empty; with Remove the source nodes are deleted.
+type HoistAction struct {
+	Edge       string   `yaml:"edge"`
+	Properties []string `yaml:"properties"`
+	Prefix     string   `yaml:"prefix"`
+	Remove     bool     `yaml:"remove"`
+}
+
+// specRule is a compiled Spec.
+type specRule struct {
+	spec  Spec
+	match *query.Query
+	apply func(g *glpg.GLPG, id string) bool
+}
+
+func (r *specRule) Name() string { return r.spec.Name }
+
+// Apply runs the action on every node matched before any rewriting, so a
+// rule never matches its own output.
+func (r *specRule) Apply(g *glpg.GLPG) int {
+	fired := 0
+	for _, id := range r.match.Match(g) {
+		if g.GetNode(id) != nil && r.apply(g, id) {
+			fired++
+		}
+	}
+	return fired
+}
+
+// Compile checks the spec and returns it as a Rule.
+func (s Spec) Compile() (Rule, error) {
+	if s.Match == "" {
+		return nil, fmt.Errorf("rule %q has no match", s.Name)
+	}
+	q, err := query.Parse(s.Match)
+	if err != nil {
+		return nil, err
+	}
+	r := &specRule{spec: s, match: q}
+	actions := 0
+	if s.Relabel != nil {
+		if s.Relabel.To == "" {
+			return nil, fmt.Errorf("rule %q: relabel needs a 'to' label", s.Name)
+		}
+		r.apply = s.Relabel.apply
+		actions++
+	}
+	if s.Delete != "" {
+		switch s.Delete {
+		case DeleteNode:
+			r.apply = deleteNode
+		case DeleteSubtree:
+			r.apply = deleteSubtree
+		default:
+			return nil, fmt.Errorf("rule %q: delete must be %q or %q", s.Name, DeleteNode, DeleteSubtree)
+		}
+		actions++
+	}
+	if s.Collapse != nil {
+		r.apply = s.Collapse.apply
+		actions++
+	}
+	if s.Hoist != nil {
+		r.apply = s.Hoist.apply
+		actions++
+	}
+	if actions != 1 {
+		return nil, fmt.Errorf("rule %q must have exactly one of relabel, delete, collapse or hoist", s.Name)
+	}
+	return r, nil
+}
+
+func (a *RelabelAction) apply(g *glpg.GLPG, id string) bool {
+	node := g.GetNode(id)
+	if a.From == "" {
+		if len(node.Labels) == 1 && node.Labels[0] == a.To {
+			return false
+		}
+		g.SetLabels(id, []string{a.To})
+		return true
+	}
+	labels := append([]string(nil), node.Labels...)
+	changed := false
+	for i, label := range labels {
+		if strings.EqualFold(label, a.From) && label != a.To {
+			labels[i] = a.To
+			changed = true
+		}
+	}
+	if changed {
+		g.SetLabels(id, labels)
+	}
+	return changed
+}
+
+func deleteNode(g *glpg.GLPG, id string) bool {
+	g.RemoveNode(id)
+	return true
+}
+
+func deleteSubtree(g *glpg.GLPG, id string) bool {
+	seen := map[string]bool{id: true}
+	stack := []string{id}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, edge := range g.GetOutgoingEdges(current) {
+			if !seen[edge.TargetID] {
+				seen[edge.TargetID] = true
+				stack = append(stack, edge.TargetID)
+			}
+		}
+	}
+	for nodeID := range seen {
+		g.RemoveNode(nodeID)
+	}
+	return true
+}
+
+func (a *CollapseAction) apply(g *glpg.GLPG, id string) bool {
+	in, out := g.GetIncomingEdges(id), g.GetOutgoingEdges(id)
+	if len(in) != 1 || len(out) != 1 {
+		return false
+	}
+	if a.Edge != "" && (!strings.EqualFold(in[0].Label, a.Edge) || !strings.EqualFold(out[0].Label, a.Edge)) {
+		return false
+	}
+	if in[0].SourceID == id || out[0].TargetID == id {
+		return false // self-loop
+	}
+
+	// Keep the incoming edge's properties; Collapsed lists the removed nodes
+	// in path order, including those either edge already stood in for
+	props := make(glpg.GLPGProperty, len(in[0].Properties)+1)
+	for k, v := range in[0].Properties {
+		props[k] = v
+	}
+	collapsed := append(collapsedIDs(in[0].Properties["Collapsed"]), id)
+	props["Collapsed"] = append(collapsed, collapsedIDs(out[0].Properties["Collapsed"])...)
+	edge := &glpg.GLPGEdge{
+		ID:         g.NewEdgeID(in[0].SourceID, in[0].Label, out[0].TargetID),
+		SourceID:   in[0].SourceID,
+		TargetID:   out[0].TargetID,
+		Label:      in[0].Label,
+		Properties: props,
+	}
+	// Add the new edge first so a failure leaves the chain untouched
+	if err := g.AddEdge(edge); err != nil {
+		return false
+	}
+	g.RemoveNode(id)
+	return true
+}
+
+// collapsedIDs returns a copy of the node IDs in a Collapsed property: a
+// []string as CollapseAction writes it, or a []interface{} once the graph
+// has been dumped and loaded back.
+func collapsedIDs(v interface{}) []string {
+	switch ids := v.(type) {
+	case []string:
+		return append([]string(nil), ids...)
+	case []interface{}:
+		out := make([]string, 0, len(ids))
+		for _, id := range ids {
+			out = append(out, fmt.Sprint(id))
+		}
+		return out
+	}
+	return nil
+}
+
+func (a *HoistAction) apply(g *glpg.GLPG, id string) bool {
+	hoisted := make(glpg.GLPGProperty)
+	var sources []string
+	for _, edge := range g.GetOutgoingEdges(id) {
+		if a.Edge != "" && !strings.EqualFold(edge.Label, a.Edge) {
+			continue
+		}
+		source := g.GetNode(edge.TargetID)
+		if source == nil {
+			continue
+		}
+		sources = append(sources, source.ID)
+		if len(a.Properties) == 0 {
+			for k, v := range source.Properties {
+				hoisted[a.Prefix+k] = v
+			}
+			continue
+		}
+		for _, k := range a.Properties {
+			if v, ok := source.Properties[k]; ok {
+				hoisted[a.Prefix+k] = v
+			}
+		}
+	}
+	g.UpdateProperties(id, hoisted)
+	if a.Remove {
+		for _, sourceID := range sources {
+			g.RemoveNode(sourceID)
+		}
+	}
+	return len(hoisted) > 0 || (a.Remove && len(sources) > 0)
+}
@@ -0,0 +1,185 @@
+// Package rewrite implements the transformation stage between GLPG ingestion
+// and mode serialization: a pipeline of rules that match parts of the graph
+// and rewrite them.
+package rewrite
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"lazybox/internal/glpg"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule rewrites a graph in place and returns how many times it fired.
+type Rule interface {
+	Name() string
+	Apply(g *glpg.GLPG) int
+}
+
+// Pipeline is an ordered list of rules. Each rule runs once, in order.
+type Pipeline []Rule
+
+// Entry records how many times one rule fired.
+type Entry struct {
+	Rule  string
+	Fired int
+}
+
+// Report lists the rules a pipeline ran and how many times each fired.
+type Report struct {
+	Entries []Entry
+}
+
+// Total returns the total number of rewrites.
+func (r *Report) Total() int {
+	total := 0
+	for _, e := range r.Entries {
+		total += e.Fired
+	}
+	return total
+}
+
+// String renders the report, one line per rule.
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d rules, %d rewrites\n", len(r.Entries), r.Total())
+	width := 0
+	for _, e := range r.Entries {
+		if len(e.Rule) > width {
+			width = len(e.Rule)
+		}
+	}
+	for _, e := range r.Entries {
+		fmt.Fprintf(&b, "  %-*s  %d\n", width, e.Rule, e.Fired)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Apply runs every rule against g in order.
+func (p Pipeline) Apply(g *glpg.GLPG) *Report {
+	report := &Report{}
+	for _, rule := range p {
+		report.Entries = append(report.Entries, Entry{Rule: rule.Name(), Fired: rule.Apply(g)})
+	}
+	return report
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Rule)
+)
+
+// Register makes a rule available by name to --rule and to "builtin:"
+// entries in rules files, replacing any rule already registered under that
+// name.
+func Register(rule Rule) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[rule.Name()] = rule
+}
+
+// Lookup returns the registered rule with the given name.
+func Lookup(name string) (Rule, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	rule, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown rule %q (built in: %s)", name, strings.Join(builtinNames(), ", "))
+	}
+	return rule, nil
+}
+
+// builtinNames lists the registered rules; the caller holds registryMu.
+func builtinNames() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Built-in rules, written as the same specs a rules file would contain.
+var builtins = []Spec{
+	{
+		// A directory with a directory parent and a directory child: the
+		// path runs from the parent through the directory to its child and
+		// back up to the directory, which is what it matches
+		Name:     "collapse-dirs",
+		Match:    `FileInfo[IsDir]-CONTAINS->FileInfo[IsDir]-CONTAINS->FileInfo[IsDir]<-CONTAINS-FileInfo[IsDir]`,
+		Collapse: &CollapseAction{Edge: "CONTAINS"},
+	},
+	{
+		Name:  "hoist-content",
+		Match: `FileInfo`,
+		Hoist: &HoistAction{Edge: "HAS_CONTENT", Properties: []string{"LineCount", "MimeType"}},
+	},
+	{
+		Name:   "drop-content",
+		Match:  `FileContent`,
+		Delete: DeleteNode,
+	},
+	{
+		Name:   "drop-git",
+		Match:  `FileInfo[Name=".git"]`,
+		Delete: DeleteSubtree,
+	},
+}
+
+func init() {
+	for _, spec := range builtins {
+		rule, err := spec.Compile()
+		if err != nil {
+			panic(fmt.Sprintf("rewrite: built-in rule %s: %v", spec.Name, err))
+		}
+		Register(rule)
+	}
+}
+
+// File is the layout of a YAML rules file:
+//
+//	rules:
+//	  - builtin: drop-git
+//	  - name: go-files
+//	    match: 'FileInfo[Extension=".go"]'
+//	    relabel: {from: FileInfo, to: GoFile}
+//	  - name: no-tests
+//	    match: 'FileInfo[Name~"_test\.go$"]'
+//	    delete: node
+type File struct {
+	Rules []Spec `yaml:"rules"`
+}
+
+// LoadFile reads a YAML rules file and compiles its rules in order.
+func LoadFile(path string) (Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing rules file %s: %w", path, err)
+	}
+	var pipeline Pipeline
+	for i, spec := range file.Rules {
+		var rule Rule
+		if spec.Builtin != "" {
+			rule, err = Lookup(spec.Builtin)
+		} else {
+			if spec.Name == "" {
+				spec.Name = fmt.Sprintf("rule-%d", i+1)
+			}
+			rule, err = spec.Compile()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %w", path, i+1, err)
+		}
+		pipeline = append(pipeline, rule)
+	}
+	return pipeline, nil
+}
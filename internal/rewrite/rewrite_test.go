@@ -0,0 +1,274 @@
+package rewrite
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"lazybox/internal/glpg"
+)
+
+// testTree builds a scanned repository:
+//
+//	repo -> src -> cmd -> app -> main.go -HAS_CONTENT-> content
+//	repo -> .git -> HEAD
+//	repo -> README.md
+func testTree(t *testing.T) *glpg.GLPG {
+	t.Helper()
+	g := glpg.NewGLPG()
+	dir := func(id string) *glpg.GLPGNode {
+		return &glpg.GLPGNode{ID: id, Labels: []string{"FileInfo", "directory"}, Properties: glpg.GLPGProperty{"Name": id, "IsDir": true}}
+	}
+	file := func(id string) *glpg.GLPGNode {
+		return &glpg.GLPGNode{ID: id, Labels: []string{"FileInfo", "file"}, Properties: glpg.GLPGProperty{"Name": id, "IsDir": false}}
+	}
+	for _, node := range []*glpg.GLPGNode{
+		dir("repo"), dir("src"), dir("cmd"), dir("app"), dir(".git"),
+		file("main.go"), file("HEAD"), file("README.md"),
+		{ID: "content", Labels: []string{"FileContent"}, Properties: glpg.GLPGProperty{"Content": "package main", "LineCount": 1, "MimeType": "text/x-go"}},
+	} {
+		g.AddNode(node)
+	}
+	for _, e := range [][3]string{
+		{"repo", "CONTAINS", "src"},
+		{"src", "CONTAINS", "cmd"},
+		{"cmd", "CONTAINS", "app"},
+		{"app", "CONTAINS", "main.go"},
+		{"main.go", "HAS_CONTENT", "content"},
+		{"repo", "CONTAINS", ".git"},
+		{".git", "CONTAINS", "HEAD"},
+		{"repo", "CONTAINS", "README.md"},
+	} {
+		if g.Connect(e[0], e[1], e[2]) == nil {
+			t.Fatalf("connecting %v", e)
+		}
+	}
+	return g
+}
+
+// edgeList lists the graph's edges as sorted source-LABEL->target strings.
+func edgeList(g *glpg.GLPG) string {
+	var edges []string
+	for _, edge := range g.Edges {
+		edges = append(edges, edge.SourceID+"-"+edge.Label+"->"+edge.TargetID)
+	}
+	sort.Strings(edges)
+	return strings.Join(edges, " ")
+}
+
+func TestBuiltinRules(t *testing.T) {
+	const all = ".git-CONTAINS->HEAD app-CONTAINS->main.go cmd-CONTAINS->app main.go-HAS_CONTENT->content " +
+		"repo-CONTAINS->.git repo-CONTAINS->README.md repo-CONTAINS->src src-CONTAINS->cmd"
+	tests := []struct {
+		rule  string
+		fired int
+		edges string
+		check func(t *testing.T, g *glpg.GLPG)
+	}{
+		{"collapse-dirs", 2, ".git-CONTAINS->HEAD app-CONTAINS->main.go main.go-HAS_CONTENT->content " +
+			"repo-CONTAINS->.git repo-CONTAINS->README.md repo-CONTAINS->app", func(t *testing.T, g *glpg.GLPG) {
+			for _, edge := range g.GetOutgoingEdges("repo") {
+				if edge.TargetID != "app" {
+					continue
+				}
+				if got := strings.Join(edge.Properties["Collapsed"].([]string), " "); got != "src cmd" {
+					t.Errorf("repo->app Collapsed = %q, want \"src cmd\"", got)
+				}
+			}
+		}},
+		{"hoist-content", 1, all, func(t *testing.T, g *glpg.GLPG) {
+			props := g.GetNode("main.go").Properties
+			if props["LineCount"] != 1 || props["MimeType"] != "text/x-go" || props["Content"] != nil {
+				t.Errorf("main.go properties = %v, want LineCount and MimeType only", props)
+			}
+		}},
+		{"drop-content", 1, ".git-CONTAINS->HEAD app-CONTAINS->main.go cmd-CONTAINS->app " +
+			"repo-CONTAINS->.git repo-CONTAINS->README.md repo-CONTAINS->src src-CONTAINS->cmd", nil},
+		{"drop-git", 1, "app-CONTAINS->main.go cmd-CONTAINS->app main.go-HAS_CONTENT->content " +
+			"repo-CONTAINS->README.md repo-CONTAINS->src src-CONTAINS->cmd", func(t *testing.T, g *glpg.GLPG) {
+			if g.GetNode("HEAD") != nil {
+				t.Errorf("drop-git left .git/HEAD behind")
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := Lookup(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			g := testTree(t)
+			if fired := rule.Apply(g); fired != tt.fired {
+				t.Errorf("%s fired %d times, want %d", tt.rule, fired, tt.fired)
+			}
+			if got := edgeList(g); got != tt.edges {
+				t.Errorf("%s left edges\n%s\nwant\n%s", tt.rule, got, tt.edges)
+			}
+			if tt.check != nil {
+				tt.check(t, g)
+			}
+			if errs := g.CheckIntegrity(); len(errs) != 0 {
+				t.Errorf("%s broke the graph: %v", tt.rule, errs)
+			}
+
+			// Rules that found nothing left to do do not fire again
+			if tt.rule != "hoist-content" {
+				if fired := rule.Apply(g); fired != 0 {
+					t.Errorf("%s fired %d times on its own output", tt.rule, fired)
+				}
+			}
+		})
+	}
+
+	if _, err := Lookup("drop-tests"); err == nil || !strings.Contains(err.Error(), "built in: collapse-dirs, drop-content, drop-git, hoist-content") {
+		t.Errorf("Lookup of an unknown rule: %v", err)
+	}
+}
+
+// TestCollapseAfterReload checks that collapsing keeps the IDs an earlier
+// collapse recorded after the graph went through a dump, where Collapsed
+// comes back as a []interface{}.
+func TestCollapseAfterReload(t *testing.T) {
+	first, err := Spec{Name: "src", Match: "FileInfo[Name=src]", Collapse: &CollapseAction{}}.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := testTree(t)
+	if fired := first.Apply(g); fired != 1 {
+		t.Fatalf("collapsing src fired %d times", fired)
+	}
+	var buf bytes.Buffer
+	if err := glpg.Encode(&buf, g, "jgf", false); err != nil {
+		t.Fatal(err)
+	}
+	if g, err = glpg.Decode(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	rule, err := Lookup("collapse-dirs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fired := rule.Apply(g); fired != 1 {
+		t.Errorf("collapse-dirs fired %d times on the reloaded graph, want 1", fired)
+	}
+	for _, edge := range g.GetOutgoingEdges("repo") {
+		if edge.TargetID == "app" {
+			if got := strings.Join(edge.Properties["Collapsed"].([]string), " "); got != "src cmd" {
+				t.Errorf("repo->app Collapsed = %q, want \"src cmd\"", got)
+			}
+			return
+		}
+	}
+	t.Errorf("no repo->app edge after collapsing: %s", edgeList(g))
+}
+
+// writeRules writes a rules file and loads it.
+func writeRules(t *testing.T, src string) (Pipeline, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadFile(path)
+}
+
+func TestLoadFile(t *testing.T) {
+	pipeline, err := writeRules(t, `
+rules:
+  - builtin: drop-git
+  - name: go-files
+    match: 'FileInfo[Name$=".go"]'
+    relabel: {from: fileinfo, to: GoFile}
+  - match: 'FileInfo[Name=README.md]'
+    delete: node
+  - name: inline-content
+    match: 'GoFile'
+    hoist: {edge: HAS_CONTENT, prefix: content_, remove: true}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, rule := range pipeline {
+		names = append(names, rule.Name())
+	}
+	if got, want := strings.Join(names, " "), "drop-git go-files rule-3 inline-content"; got != want {
+		t.Fatalf("loaded rules %q, want %q", got, want)
+	}
+
+	g := testTree(t)
+	report := pipeline.Apply(g)
+	if got, want := report.Total(), 4; got != want {
+		t.Errorf("pipeline made %d rewrites, want %d", got, want)
+	}
+	main := g.GetNode("main.go")
+	if got := strings.Join(main.Labels, " "); got != "GoFile file" {
+		t.Errorf("main.go labels = %q, want the relabeled FileInfo", got)
+	}
+	if main.Properties["content_Content"] != "package main" || g.GetNode("content") != nil {
+		t.Errorf("content was not hoisted and removed: %v", main.Properties)
+	}
+	if got, want := edgeList(g), "app-CONTAINS->main.go cmd-CONTAINS->app repo-CONTAINS->src src-CONTAINS->cmd"; got != want {
+		t.Errorf("edges after the pipeline = %q, want %q", got, want)
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		want  string
+	}{
+		{"no match", "rules:\n  - name: x\n    delete: node\n", `rule 1: rule "x" has no match`},
+		{"no action", "rules:\n  - match: FileInfo\n", `rule 1: rule "rule-1" must have exactly one of`},
+		{"two actions", "rules:\n  - match: FileInfo\n    delete: node\n    collapse: {}\n", "must have exactly one of"},
+		{"bad delete", "rules:\n  - match: FileInfo\n    delete: all\n", `delete must be "node" or "subtree"`},
+		{"relabel without to", "rules:\n  - match: FileInfo\n    relabel: {from: A}\n", "relabel needs a 'to' label"},
+		{"bad query", "rules:\n  - match: 'FileInfo['\n    delete: node\n", "rule 1: query:"},
+		{"unknown builtin", "rules:\n  - builtin: drop-tests\n", `unknown rule "drop-tests"`},
+		{"not YAML", "rules: [", "parsing rules file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := writeRules(t, tt.rules)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadFile error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestDryRun checks the report --dry-run prints: the pipeline runs against a
+// clone, so the graph that would be rendered is untouched.
+func TestDryRun(t *testing.T) {
+	var pipeline Pipeline
+	for _, name := range []string{"drop-git", "collapse-dirs", "drop-git"} {
+		rule, err := Lookup(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pipeline = append(pipeline, rule)
+	}
+	g := testTree(t)
+	before := edgeList(g)
+
+	report := pipeline.Apply(g.Clone())
+	want := "3 rules, 3 rewrites\n" +
+		"  drop-git       1\n" +
+		"  collapse-dirs  2\n" +
+		"  drop-git       0"
+	if got := report.String(); got != want {
+		t.Errorf("dry-run report =\n%s\nwant\n%s", got, want)
+	}
+	if got := edgeList(g); got != before || len(g.Nodes) != 9 {
+		t.Errorf("dry run modified the graph: %s", got)
+	}
+
+	if got := (Pipeline{}).Apply(g).String(); got != "0 rules, 0 rewrites" {
+		t.Errorf("empty pipeline report = %q", got)
+	}
+}
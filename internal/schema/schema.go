@@ -0,0 +1,388 @@
+// Package schema defines GLPG schemas (meta-GLPGs) declaring which node
+// labels, properties and edges a graph may contain, and validates graphs
+// against them.
+package schema
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"lazybox/internal/glpg"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema declares the allowed shape of a graph. Schemas are written in YAML
+// (or JSON):
+//
+//	strict: true
+//	nodes:
+//	  FileInfo:
+//	    properties:
+//	      Name: {type: string, required: true}
+//	      Size: {type: int}
+//	      Type: {type: string, enum: [file, directory, symlink]}
+//	  FileContent:
+//	    additional: false
+//	    properties:
+//	      Content: {type: string, required: true}
+//	edges:
+//	  - label: CONTAINS
+//	    from: [PackageInfo, FileInfo]
+//	    to: [FileInfo]
+//
+// In strict mode nodes must carry at least one declared label and edges must
+// match a declared edge. Node properties not declared for a label are allowed
+// unless the label sets additional: false.
+type Schema struct {
+	Strict bool                 `yaml:"strict"`
+	Nodes  map[string]*NodeType `yaml:"nodes"`
+	Edges  []*EdgeType          `yaml:"edges"`
+}
+
+// NodeType declares the properties of nodes with one label.
+type NodeType struct {
+	Properties map[string]*Property `yaml:"properties"`
+	Additional *bool                `yaml:"additional"` // nil means true
+}
+
+// EdgeType declares an allowed edge label and its endpoints. Empty From or To
+// lists allow any label at that end.
+type EdgeType struct {
+	Label      string               `yaml:"label"`
+	From       []string             `yaml:"from"`
+	To         []string             `yaml:"to"`
+	Properties map[string]*Property `yaml:"properties"`
+}
+
+// Property declares a property's type and constraints.
+type Property struct {
+	Type     string   `yaml:"type"` // see Types; empty means any
+	Required bool     `yaml:"required"`
+	Enum     []string `yaml:"enum"`
+	Pattern  string   `yaml:"pattern"`
+
+	pattern *regexp.Regexp
+}
+
+// Types lists the property types a schema may use.
+var Types = []string{"any", "string", "int", "float", "bool", "time", "list", "map"}
+
+// Load reads and checks a schema file.
+func Load(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Schema
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parsing schema %s: %w", path, err)
+	}
+	if err := s.compile(); err != nil {
+		return nil, fmt.Errorf("schema %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// compile checks property types and compiles patterns.
+func (s *Schema) compile() error {
+	check := func(where string, props map[string]*Property) error {
+		for name, p := range props {
+			if p == nil {
+				props[name] = &Property{}
+				continue
+			}
+			if p.Type != "" && !validType(p.Type) {
+				return fmt.Errorf("%s property %s: unknown type %q (expected one of %s)", where, name, p.Type, strings.Join(Types, ", "))
+			}
+			if p.Pattern != "" {
+				re, err := regexp.Compile(p.Pattern)
+				if err != nil {
+					return fmt.Errorf("%s property %s: %w", where, name, err)
+				}
+				p.pattern = re
+			}
+		}
+		return nil
+	}
+	for label, nt := range s.Nodes {
+		if nt == nil {
+			s.Nodes[label] = &NodeType{}
+			continue
+		}
+		if err := check("node "+label, nt.Properties); err != nil {
+			return err
+		}
+	}
+	for i, et := range s.Edges {
+		if et == nil || et.Label == "" {
+			return fmt.Errorf("edge %d has no label", i+1)
+		}
+		if err := check("edge "+et.Label, et.Properties); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validType(t string) bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Violation is a single way in which a graph breaks its schema.
+type Violation struct {
+	Element  string // "node" or "edge"
+	ID       string
+	Label    string
+	Property string
+	Message  string
+}
+
+func (v Violation) String() string {
+	where := fmt.Sprintf("%s %s (%s)", v.Element, v.ID, v.Label)
+	if v.Property != "" {
+		where += " property " + v.Property
+	}
+	return where + ": " + v.Message
+}
+
+// Validate checks g against the schema and returns every violation, ordered
+// by element and ID.
+func (s *Schema) Validate(g *glpg.GLPG) []Violation {
+	var violations []Violation
+	nodeIDs := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		nodeIDs = append(nodeIDs, id)
+	}
+	sort.Strings(nodeIDs)
+	for _, id := range nodeIDs {
+		violations = append(violations, s.validateNode(g.Nodes[id])...)
+	}
+
+	edges := make([]*glpg.GLPGEdge, 0, len(g.Edges))
+	for _, edge := range g.Edges {
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].SourceID != edges[j].SourceID {
+			return edges[i].SourceID < edges[j].SourceID
+		}
+		if edges[i].TargetID != edges[j].TargetID {
+			return edges[i].TargetID < edges[j].TargetID
+		}
+		return edges[i].ID < edges[j].ID
+	})
+	for _, edge := range edges {
+		violations = append(violations, s.validateEdge(g, edge)...)
+	}
+	return violations
+}
+
+func (s *Schema) validateNode(node *glpg.GLPGNode) []Violation {
+	var violations []Violation
+	declared := false
+	for _, label := range node.Labels {
+		nt, ok := s.Nodes[label]
+		if !ok {
+			continue
+		}
+		declared = true
+		fail := func(prop, msg string) {
+			violations = append(violations, Violation{"node", node.ID, label, prop, msg})
+		}
+		checkProperties(nt.Properties, node.Properties, nt.Additional == nil || *nt.Additional, fail)
+	}
+	if !declared && s.Strict {
+		violations = append(violations, Violation{"node", node.ID, strings.Join(node.Labels, ","), "",
+			"no declared label (declared: " + strings.Join(s.nodeLabels(), ", ") + ")"})
+	}
+	return violations
+}
+
+func (s *Schema) validateEdge(g *glpg.GLPG, edge *glpg.GLPGEdge) []Violation {
+	fail := func(prop, msg string) Violation {
+		return Violation{"edge", fmt.Sprintf("%s->%s", edge.SourceID, edge.TargetID), edge.Label, prop, msg}
+	}
+	var violations []Violation
+	if g.GetNode(edge.SourceID) == nil {
+		violations = append(violations, fail("", "source node does not exist"))
+	}
+	if g.GetNode(edge.TargetID) == nil {
+		violations = append(violations, fail("", "target node does not exist"))
+	}
+
+	var sameLabel []*EdgeType
+	for _, et := range s.Edges {
+		if et.Label == edge.Label {
+			sameLabel = append(sameLabel, et)
+		}
+	}
+	if len(sameLabel) == 0 {
+		if s.Strict {
+			violations = append(violations, fail("", "undeclared edge label"))
+		}
+		return violations
+	}
+	source, target := g.GetNode(edge.SourceID), g.GetNode(edge.TargetID)
+	for _, et := range sameLabel {
+		if endpointAllowed(et.From, source) && endpointAllowed(et.To, target) {
+			checkProperties(et.Properties, edge.Properties, true, func(prop, msg string) {
+				violations = append(violations, fail(prop, msg))
+			})
+			return violations
+		}
+	}
+	return append(violations, fail("", fmt.Sprintf("not allowed from %s to %s", labelsOf(source), labelsOf(target))))
+}
+
+// checkProperties validates props against the declared properties.
+func checkProperties(declared map[string]*Property, props glpg.GLPGProperty, additional bool, fail func(prop, msg string)) {
+	names := make([]string, 0, len(declared))
+	for name := range declared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := declared[name]
+		value, ok := props[name]
+		if !ok || value == nil {
+			if p.Required {
+				fail(name, "required property is missing")
+			}
+			continue
+		}
+		if msg := p.check(value); msg != "" {
+			fail(name, msg)
+		}
+	}
+	if additional {
+		return
+	}
+	var extra []string
+	for name := range props {
+		if _, ok := declared[name]; !ok {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		fail(name, "property is not declared")
+	}
+}
+
+// check returns a description of how value breaks the property, or "".
+func (p *Property) check(value interface{}) string {
+	if p.Type != "" && p.Type != "any" && !hasType(value, p.Type) {
+		return fmt.Sprintf("expected %s, got %s", p.Type, typeName(value))
+	}
+	s := fmt.Sprint(value)
+	if len(p.Enum) > 0 {
+		found := false
+		for _, allowed := range p.Enum {
+			if s == allowed {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Sprintf("value %q is not one of %s", s, strings.Join(p.Enum, ", "))
+		}
+	}
+	if p.pattern != nil && !p.pattern.MatchString(s) {
+		return fmt.Sprintf("value %q does not match %s", s, p.Pattern)
+	}
+	return ""
+}
+
+// hasType reports whether value has the given schema type.
+func hasType(value interface{}, t string) bool {
+	v := reflect.ValueOf(value)
+	switch t {
+	case "string":
+		return v.Kind() == reflect.String
+	case "int":
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return true
+		case reflect.Float32, reflect.Float64:
+			f := v.Float()
+			return f == float64(int64(f)) // integral numbers read back from JSON
+		}
+		return false
+	case "float":
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return true
+		}
+		return false
+	case "bool":
+		return v.Kind() == reflect.Bool
+	case "time":
+		if _, ok := value.(time.Time); ok {
+			return true
+		}
+		if s, ok := value.(string); ok {
+			_, err := time.Parse(time.RFC3339, s)
+			return err == nil
+		}
+		return false
+	case "list":
+		return v.Kind() == reflect.Slice || v.Kind() == reflect.Array
+	case "map":
+		return v.Kind() == reflect.Map || v.Kind() == reflect.Struct
+	}
+	return true
+}
+
+// typeName describes a value's type in schema terms.
+func typeName(value interface{}) string {
+	for _, t := range []string{"bool", "int", "float", "list", "map", "string"} {
+		if hasType(value, t) {
+			return t
+		}
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func endpointAllowed(labels []string, node *glpg.GLPGNode) bool {
+	if len(labels) == 0 || node == nil {
+		return true
+	}
+	for _, allowed := range labels {
+		for _, label := range node.Labels {
+			if label == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func labelsOf(node *glpg.GLPGNode) string {
+	if node == nil {
+		return "(missing)"
+	}
+	return strings.Join(node.Labels, ",")
+}
+
+func (s *Schema) nodeLabels() []string {
+	labels := make([]string, 0, len(s.Nodes))
+	for label := range s.Nodes {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
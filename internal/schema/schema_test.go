@@ -0,0 +1,164 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lazybox/internal/glpg"
+)
+
+const testSchema = `
+strict: true
+nodes:
+  PackageInfo:
+  FileInfo:
+    properties:
+      Name: {type: string, required: true}
+      Size: {type: int}
+      Type: {type: string, enum: [file, directory]}
+      ModTime: {type: time}
+  FileContent:
+    additional: false
+    properties:
+      Content: {type: string, required: true}
+      MimeType: {pattern: '^text/'}
+edges:
+  - label: CONTAINS
+    from: [PackageInfo, FileInfo]
+    to: [FileInfo]
+  - label: HAS_CONTENT
+    from: [FileInfo]
+    to: [FileContent]
+    properties:
+      Lines: {type: int}
+`
+
+// loadSchema writes src to a schema file and loads it.
+func loadSchema(t *testing.T, src string) (*Schema, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "schema.yaml")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+// validGraph returns a graph that satisfies testSchema.
+func validGraph() *glpg.GLPG {
+	g := glpg.NewGLPG()
+	g.AddNode(&glpg.GLPGNode{ID: "pkg", Labels: []string{"PackageInfo"}, Properties: glpg.GLPGProperty{}})
+	g.AddNode(&glpg.GLPGNode{ID: "dir", Labels: []string{"FileInfo", "directory"}, Properties: glpg.GLPGProperty{"Name": "src", "Size": int64(4096), "Type": "directory"}})
+	g.AddNode(&glpg.GLPGNode{ID: "file", Labels: []string{"FileInfo", "file"}, Properties: glpg.GLPGProperty{"Name": "a.go", "Size": 12.0, "Type": "file", "ModTime": "2024-05-01T10:00:00Z"}})
+	g.AddNode(&glpg.GLPGNode{ID: "content", Labels: []string{"FileContent"}, Properties: glpg.GLPGProperty{"Content": "package a", "MimeType": "text/plain"}})
+	g.Connect("pkg", "CONTAINS", "dir")
+	g.Connect("dir", "CONTAINS", "file")
+	g.Connect("file", "HAS_CONTENT", "content").Properties["Lines"] = 1
+	return g
+}
+
+func TestValidateAcceptsValidGraph(t *testing.T) {
+	s, err := loadSchema(t, testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if violations := s.Validate(validGraph()); len(violations) != 0 {
+		t.Errorf("Validate reported %v, want no violations", violations)
+	}
+}
+
+func TestValidateViolations(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(g *glpg.GLPG)
+		want   string
+	}{
+		{"missing required property", func(g *glpg.GLPG) {
+			delete(g.Nodes["file"].Properties, "Name")
+		}, "node file (FileInfo) property Name: required property is missing"},
+		{"wrong type", func(g *glpg.GLPG) {
+			g.Nodes["file"].Properties["Size"] = "12"
+		}, "node file (FileInfo) property Size: expected int, got string"},
+		{"fractional int", func(g *glpg.GLPG) {
+			g.Nodes["file"].Properties["Size"] = 1.5
+		}, "node file (FileInfo) property Size: expected int, got float"},
+		{"bad time", func(g *glpg.GLPG) {
+			g.Nodes["file"].Properties["ModTime"] = "yesterday"
+		}, "node file (FileInfo) property ModTime: expected time, got string"},
+		{"not in enum", func(g *glpg.GLPG) {
+			g.Nodes["file"].Properties["Type"] = "socket"
+		}, `node file (FileInfo) property Type: value "socket" is not one of file, directory`},
+		{"pattern mismatch", func(g *glpg.GLPG) {
+			g.Nodes["content"].Properties["MimeType"] = "image/png"
+		}, `node content (FileContent) property MimeType: value "image/png" does not match ^text/`},
+		{"undeclared property", func(g *glpg.GLPG) {
+			g.Nodes["content"].Properties["Extra"] = true
+		}, "node content (FileContent) property Extra: property is not declared"},
+		{"undeclared label in strict mode", func(g *glpg.GLPG) {
+			g.AddNode(&glpg.GLPGNode{ID: "odd", Labels: []string{"Odd"}, Properties: glpg.GLPGProperty{}})
+		}, "node odd (Odd): no declared label (declared: FileContent, FileInfo, PackageInfo)"},
+		{"undeclared edge label", func(g *glpg.GLPG) {
+			g.Connect("file", "IMPORTS", "dir")
+		}, "edge file->dir (IMPORTS): undeclared edge label"},
+		{"edge endpoints not allowed", func(g *glpg.GLPG) {
+			g.Connect("content", "CONTAINS", "file")
+		}, "edge content->file (CONTAINS): not allowed from FileContent to FileInfo,file"},
+		{"edge property type", func(g *glpg.GLPG) {
+			for _, edge := range g.GetOutgoingEdges("file") {
+				edge.Properties["Lines"] = "one"
+			}
+		}, "edge file->content (HAS_CONTENT) property Lines: expected int, got string"},
+	}
+	s, err := loadSchema(t, testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := validGraph()
+			tt.modify(g)
+			violations := s.Validate(g)
+			if len(violations) != 1 {
+				t.Fatalf("Validate reported %v, want exactly %q", violations, tt.want)
+			}
+			if got := violations[0].String(); got != tt.want {
+				t.Errorf("Validate reported %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateLenientMode(t *testing.T) {
+	s, err := loadSchema(t, strings.Replace(testSchema, "strict: true", "strict: false", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := validGraph()
+	g.AddNode(&glpg.GLPGNode{ID: "odd", Labels: []string{"Odd"}, Properties: glpg.GLPGProperty{}})
+	g.Connect("file", "IMPORTS", "odd")
+	if violations := s.Validate(g); len(violations) != 0 {
+		t.Errorf("Validate reported %v, want undeclared labels allowed outside strict mode", violations)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{"unknown type", "nodes:\n  A:\n    properties:\n      x: {type: number}\n", `node A property x: unknown type "number"`},
+		{"bad pattern", "nodes:\n  A:\n    properties:\n      x: {pattern: '('}\n", "node A property x: error parsing regexp"},
+		{"edge without label", "edges:\n  - from: [A]\n", "edge 1 has no label"},
+		{"not YAML", "nodes: [", "parsing schema"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadSchema(t, tt.schema)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
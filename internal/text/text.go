@@ -41,7 +41,7 @@ func ExtractPlaceholder(path string) (*ir.FileInfo, error) {
 	if err == nil { // If content was read successfully
 		fileInfo.SetContent(content) // Use helper to set *string
 		textAnalysis := &ir.TextInfo{
-			LineCount: len(strings.Split(content, "\\n")),
+			LineCount: CountLines(content),
 			WordCount: len(strings.Fields(content)),
 			CharCount: len(content),
 			// Other analyses can be added here
@@ -61,10 +61,20 @@ func ExtractPlaceholder(path string) (*ir.FileInfo, error) {
 	return fileInfo, nil
 }
 
+// CountLines returns the number of lines in content: one per newline, plus
+// a last line that has no trailing newline. An empty string has no lines.
+func CountLines(content string) int {
+	n := strings.Count(content, "\n")
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		n++
+	}
+	return n
+}
+
 // Analyze analyzes the given text content and returns detailed TextInfo.
 func Analyze(content string, filePath string /* optional, for context */) (*ir.TextInfo, error) {
 	textInfo := &ir.TextInfo{
-		LineCount:   len(strings.Split(content, "\\n")),
+		LineCount:   CountLines(content),
 		WordCount:   len(strings.Fields(content)),
 		CharCount:   len(content),
 		Readability: &ir.ReadabilityScores{},
@@ -96,7 +106,10 @@ func Analyze(content string, filePath string /* optional, for context */) (*ir.T
 		kwList = append(kwList, ir.KeywordFrequency{Keyword: k, Count: v}) // Corrected field to Count
 	}
 	sort.Slice(kwList, func(i, j int) bool {
-		return kwList[i].Count > kwList[j].Count // Corrected field to Count
+		if kwList[i].Count != kwList[j].Count {
+			return kwList[i].Count > kwList[j].Count // Corrected field to Count
+		}
+		return kwList[i].Keyword < kwList[j].Keyword // Ties in keyword order, so output is stable
 	})
 
 	// Store top N keywords (e.g., top 10)
@@ -0,0 +1,24 @@
+package text
+
+import "testing"
+
+func TestCountLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"\n", 1},
+		{"one", 1},
+		{"one\n", 1},
+		{"one\ntwo", 2},
+		{"one\ntwo\n", 2},
+		{"one\n\n", 2},
+		{"one\r\ntwo\r\n", 2},
+	}
+	for _, tt := range tests {
+		if got := CountLines(tt.in); got != tt.want {
+			t.Errorf("CountLines(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}